@@ -48,6 +48,14 @@ func KafkaSetPublisher(pub interfaces.Publisher) KafkaOptionFunc {
 	}
 }
 
+// KafkaSetSerde set schema registry serializer/deserializer for publisher and consumer,
+// see NewAvroSerde and NewProtobufSerde
+func KafkaSetSerde(serde KafkaSerde) KafkaOptionFunc {
+	return func(kb *KafkaBroker) {
+		kb.Serde = serde
+	}
+}
+
 // GetDefaultKafkaConfig construct default kafka config
 func GetDefaultKafkaConfig(additionalConfigFunc ...func(*sarama.Config)) *sarama.Config {
 	version := env.BaseEnv().Kafka.ClientVersion
@@ -83,6 +91,7 @@ type KafkaBroker struct {
 	BrokerHost []string
 	Config     *sarama.Config
 	Client     sarama.Client
+	Serde      KafkaSerde
 	publisher  interfaces.Publisher
 }
 
@@ -111,6 +120,9 @@ func NewKafkaBroker(opts ...KafkaOptionFunc) *KafkaBroker {
 	if kb.publisher == nil {
 		kb.publisher = NewKafkaPublisher(saramaClient, false) // default publisher is sync
 	}
+	if pub, ok := kb.publisher.(*kafkaPublisher); ok && kb.Serde != nil {
+		pub.serde = kb.Serde
+	}
 
 	return kb
 }
@@ -150,6 +162,7 @@ type kafkaPublisher struct {
 	producerSync  sarama.SyncProducer
 	producerAsync sarama.AsyncProducer
 	broker        string
+	serde         KafkaSerde
 }
 
 // NewKafkaPublisher setup only kafka publisher with client connection
@@ -191,6 +204,13 @@ func (p *kafkaPublisher) PublishMessage(ctx context.Context, args *candishared.P
 	var payload []byte
 	if len(args.Message) > 0 {
 		payload = args.Message
+	} else if p.serde != nil && args.Data != nil {
+		if payload, err = p.serde.Serialize(ctx, args.Topic, args.Data); err != nil {
+			return err
+		}
+		if schemaID, err := GetSchemaIDFromPayload(payload); err == nil {
+			trace.SetTag("schema_id", schemaID)
+		}
 	} else {
 		payload = candihelper.ToBytes(args.Data)
 	}
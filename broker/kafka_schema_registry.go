@@ -0,0 +1,375 @@
+package broker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golangid/candi/config/env"
+)
+
+// SchemaType schema format in schema registry
+type SchemaType string
+
+const (
+	// SchemaTypeAvro avro schema
+	SchemaTypeAvro SchemaType = "AVRO"
+	// SchemaTypeProtobuf protobuf schema
+	SchemaTypeProtobuf SchemaType = "PROTOBUF"
+
+	schemaRegistryContentType = "application/vnd.schemaregistry.v1+json"
+	wireFormatMagicByte       = byte(0)
+)
+
+var (
+	// ErrSchemaNotFound error when schema id or subject not registered in schema registry
+	ErrSchemaNotFound = errors.New("schema not found")
+	// ErrSchemaIncompatible error when schema or payload not compatible with registered schema
+	ErrSchemaIncompatible = errors.New("schema incompatible")
+	// ErrInvalidWireFormat error when payload not in schema registry wire format (magic byte + schema id)
+	ErrInvalidWireFormat = errors.New("invalid schema registry wire format")
+)
+
+// RegisteredSchema schema model from schema registry
+type RegisteredSchema struct {
+	ID         int        `json:"id"`
+	Subject    string     `json:"subject,omitempty"`
+	Version    int        `json:"version,omitempty"`
+	SchemaType SchemaType `json:"schemaType,omitempty"`
+	Schema     string     `json:"schema"`
+}
+
+// SchemaRegistry abstraction of Confluent-compatible schema registry
+type SchemaRegistry interface {
+	GetSchemaByID(ctx context.Context, id int) (*RegisteredSchema, error)
+	GetLatestSchema(ctx context.Context, subject string) (*RegisteredSchema, error)
+	Register(ctx context.Context, subject string, schemaType SchemaType, schema string) (id int, err error)
+}
+
+// SchemaRegistryOptionFunc func type
+type SchemaRegistryOptionFunc func(*schemaRegistryClient)
+
+// SchemaRegistrySetBasicAuth set basic auth credential for schema registry
+func SchemaRegistrySetBasicAuth(username, password string) SchemaRegistryOptionFunc {
+	return func(c *schemaRegistryClient) {
+		c.username, c.password = username, password
+	}
+}
+
+// SchemaRegistrySetHTTPClient set custom http client for schema registry
+func SchemaRegistrySetHTTPClient(httpClient *http.Client) SchemaRegistryOptionFunc {
+	return func(c *schemaRegistryClient) {
+		c.httpClient = httpClient
+	}
+}
+
+// SchemaRegistrySetLatestCacheTTL set cache duration for latest schema of subject
+func SchemaRegistrySetLatestCacheTTL(ttl time.Duration) SchemaRegistryOptionFunc {
+	return func(c *schemaRegistryClient) {
+		c.latestCacheTTL = ttl
+	}
+}
+
+type (
+	schemaRegistryClient struct {
+		baseURL            string
+		username, password string
+		httpClient         *http.Client
+		latestCacheTTL     time.Duration
+
+		mu            sync.RWMutex
+		schemaByID    map[int]*RegisteredSchema
+		idBySchema    map[string]int
+		latestSubject map[string]latestSchemaCache
+	}
+	latestSchemaCache struct {
+		schema    *RegisteredSchema
+		expiredAt time.Time
+	}
+	schemaRegistryError struct {
+		ErrorCode int    `json:"error_code"`
+		Message   string `json:"message"`
+	}
+)
+
+// NewSchemaRegistryClient create Confluent-compatible schema registry http client with schema caching,
+// empty baseURL will use KAFKA_SCHEMA_REGISTRY_URL environment
+func NewSchemaRegistryClient(baseURL string, opts ...SchemaRegistryOptionFunc) SchemaRegistry {
+	if baseURL == "" {
+		baseURL = env.BaseEnv().Kafka.SchemaRegistryURL
+	}
+	c := &schemaRegistryClient{
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+		latestCacheTTL: time.Minute,
+		schemaByID:     make(map[int]*RegisteredSchema),
+		idBySchema:     make(map[string]int),
+		latestSubject:  make(map[string]latestSchemaCache),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *schemaRegistryClient) GetSchemaByID(ctx context.Context, id int) (*RegisteredSchema, error) {
+	c.mu.RLock()
+	schema, ok := c.schemaByID[id]
+	c.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	schema = new(RegisteredSchema)
+	if err := c.do(ctx, http.MethodGet, "/schemas/ids/"+strconv.Itoa(id), nil, schema); err != nil {
+		return nil, err
+	}
+	schema.ID = id
+	if schema.SchemaType == "" {
+		schema.SchemaType = SchemaTypeAvro
+	}
+
+	c.mu.Lock()
+	c.schemaByID[id] = schema
+	c.mu.Unlock()
+	return schema, nil
+}
+
+func (c *schemaRegistryClient) GetLatestSchema(ctx context.Context, subject string) (*RegisteredSchema, error) {
+	c.mu.RLock()
+	cached, ok := c.latestSubject[subject]
+	c.mu.RUnlock()
+	if ok && time.Now().Before(cached.expiredAt) {
+		return cached.schema, nil
+	}
+
+	schema := new(RegisteredSchema)
+	if err := c.do(ctx, http.MethodGet, "/subjects/"+url.PathEscape(subject)+"/versions/latest", nil, schema); err != nil {
+		return nil, err
+	}
+	if schema.SchemaType == "" {
+		schema.SchemaType = SchemaTypeAvro
+	}
+
+	c.mu.Lock()
+	c.schemaByID[schema.ID] = schema
+	c.latestSubject[subject] = latestSchemaCache{schema: schema, expiredAt: time.Now().Add(c.latestCacheTTL)}
+	c.mu.Unlock()
+	return schema, nil
+}
+
+func (c *schemaRegistryClient) Register(ctx context.Context, subject string, schemaType SchemaType, schema string) (int, error) {
+	cacheKey := subject + ":" + schema
+	c.mu.RLock()
+	id, ok := c.idBySchema[cacheKey]
+	c.mu.RUnlock()
+	if ok {
+		return id, nil
+	}
+
+	req := RegisteredSchema{Schema: schema}
+	if schemaType != SchemaTypeAvro {
+		req.SchemaType = schemaType
+	}
+	var resp RegisteredSchema
+	if err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", req, &resp); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	c.idBySchema[cacheKey] = resp.ID
+	c.schemaByID[resp.ID] = &RegisteredSchema{ID: resp.ID, Subject: subject, SchemaType: schemaType, Schema: schema}
+	c.mu.Unlock()
+	return resp.ID, nil
+}
+
+func (c *schemaRegistryClient) do(ctx context.Context, method, path string, reqBody, respBody any) error {
+	var body io.Reader
+	if reqBody != nil {
+		b, err := json.Marshal(reqBody)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", schemaRegistryContentType)
+	if reqBody != nil {
+		req.Header.Set("Content-Type", schemaRegistryContentType)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var errResp schemaRegistryError
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		switch resp.StatusCode {
+		case http.StatusNotFound:
+			return fmt.Errorf("%w: %s", ErrSchemaNotFound, errResp.Message)
+		case http.StatusConflict, http.StatusUnprocessableEntity:
+			return fmt.Errorf("%w: %s", ErrSchemaIncompatible, errResp.Message)
+		}
+		return fmt.Errorf("schema registry: status %d, error code %d: %s", resp.StatusCode, errResp.ErrorCode, errResp.Message)
+	}
+	return json.NewDecoder(resp.Body).Decode(respBody)
+}
+
+// InMemorySchemaRegistryOptionFunc func type
+type InMemorySchemaRegistryOptionFunc func(*InMemorySchemaRegistry)
+
+// InMemorySchemaRegistrySetCompatibilityChecker set compatibility checker when register new schema version in subject,
+// default checker is backward compatibility for avro schema
+func InMemorySchemaRegistrySetCompatibilityChecker(checker func(schemaType SchemaType, latest, newSchema string) error) InMemorySchemaRegistryOptionFunc {
+	return func(r *InMemorySchemaRegistry) {
+		r.compatibilityChecker = checker
+	}
+}
+
+// InMemorySchemaRegistry local schema registry, for testing purpose
+type InMemorySchemaRegistry struct {
+	mu                   sync.RWMutex
+	schemas              []*RegisteredSchema
+	subjects             map[string][]*RegisteredSchema
+	compatibilityChecker func(schemaType SchemaType, latest, newSchema string) error
+}
+
+// NewInMemorySchemaRegistry create local in-memory schema registry
+func NewInMemorySchemaRegistry(opts ...InMemorySchemaRegistryOptionFunc) *InMemorySchemaRegistry {
+	r := &InMemorySchemaRegistry{
+		subjects:             make(map[string][]*RegisteredSchema),
+		compatibilityChecker: avroBackwardCompatibilityChecker,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// GetSchemaByID method
+func (r *InMemorySchemaRegistry) GetSchemaByID(ctx context.Context, id int) (*RegisteredSchema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if id <= 0 || id > len(r.schemas) {
+		return nil, fmt.Errorf("%w: id %d", ErrSchemaNotFound, id)
+	}
+	return r.schemas[id-1], nil
+}
+
+// GetLatestSchema method
+func (r *InMemorySchemaRegistry) GetLatestSchema(ctx context.Context, subject string) (*RegisteredSchema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := r.subjects[subject]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: subject %s", ErrSchemaNotFound, subject)
+	}
+	return versions[len(versions)-1], nil
+}
+
+// Register method
+func (r *InMemorySchemaRegistry) Register(ctx context.Context, subject string, schemaType SchemaType, schema string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versions := r.subjects[subject]
+	for _, version := range versions {
+		if version.Schema == schema {
+			return version.ID, nil
+		}
+	}
+	if len(versions) > 0 && r.compatibilityChecker != nil {
+		latest := versions[len(versions)-1]
+		if latest.SchemaType != schemaType {
+			return 0, fmt.Errorf("%w: schema type %s differ with registered %s", ErrSchemaIncompatible, schemaType, latest.SchemaType)
+		}
+		if err := r.compatibilityChecker(schemaType, latest.Schema, schema); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrSchemaIncompatible, err)
+		}
+	}
+
+	registered := &RegisteredSchema{
+		ID: len(r.schemas) + 1, Subject: subject, Version: len(versions) + 1, SchemaType: schemaType, Schema: schema,
+	}
+	r.schemas = append(r.schemas, registered)
+	r.subjects[subject] = append(versions, registered)
+	return registered.ID, nil
+}
+
+// encodeWireFormat frame payload with magic byte, schema id and optional protobuf message indexes
+func encodeWireFormat(schemaID int, messageIndexes []int, payload []byte) []byte {
+	buff := make([]byte, 5, 5+len(payload)+binary.MaxVarintLen64*(len(messageIndexes)+1))
+	buff[0] = wireFormatMagicByte
+	binary.BigEndian.PutUint32(buff[1:5], uint32(schemaID))
+	if messageIndexes != nil {
+		if len(messageIndexes) == 1 && messageIndexes[0] == 0 {
+			// optimization for first message in schema
+			buff = append(buff, 0)
+		} else {
+			buff = binary.AppendVarint(buff, int64(len(messageIndexes)))
+			for _, idx := range messageIndexes {
+				buff = binary.AppendVarint(buff, int64(idx))
+			}
+		}
+	}
+	return append(buff, payload...)
+}
+
+// decodeWireFormat parse schema id from framed payload
+func decodeWireFormat(data []byte) (schemaID int, payload []byte, err error) {
+	if len(data) < 5 || data[0] != wireFormatMagicByte {
+		return 0, nil, ErrInvalidWireFormat
+	}
+	return int(binary.BigEndian.Uint32(data[1:5])), data[5:], nil
+}
+
+// decodeMessageIndexes parse protobuf message indexes after schema id
+func decodeMessageIndexes(data []byte) (messageIndexes []int, payload []byte, err error) {
+	count, n := binary.Varint(data)
+	if n <= 0 || count < 0 {
+		return nil, nil, ErrInvalidWireFormat
+	}
+	data = data[n:]
+	if count == 0 {
+		return []int{0}, data, nil
+	}
+
+	messageIndexes = make([]int, count)
+	for i := range messageIndexes {
+		idx, n := binary.Varint(data)
+		if n <= 0 {
+			return nil, nil, ErrInvalidWireFormat
+		}
+		messageIndexes[i] = int(idx)
+		data = data[n:]
+	}
+	return messageIndexes, data, nil
+}
+
+// GetSchemaIDFromPayload get schema id from kafka message payload in schema registry wire format
+func GetSchemaIDFromPayload(data []byte) (int, error) {
+	schemaID, _, err := decodeWireFormat(data)
+	return schemaID, err
+}
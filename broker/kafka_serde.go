@@ -0,0 +1,277 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/golangid/candi/candishared"
+	"github.com/hamba/avro/v2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// KafkaSerde serializer and deserializer kafka message payload with schema registry
+type KafkaSerde interface {
+	Serialize(ctx context.Context, topic string, value any) ([]byte, error)
+	Deserialize(ctx context.Context, topic string, data []byte, target any) error
+}
+
+// SerdeOptionFunc func type
+type SerdeOptionFunc func(*serdeOption)
+
+type serdeOption struct {
+	autoRegister    bool
+	subjectNameFunc func(topic string) string
+	schemas         map[string]string
+}
+
+// SerdeSetAutoRegister register schema (from SerdeSetSubjectSchema) to schema registry when serialize, default is true
+func SerdeSetAutoRegister(autoRegister bool) SerdeOptionFunc {
+	return func(o *serdeOption) {
+		o.autoRegister = autoRegister
+	}
+}
+
+// SerdeSetSubjectNameStrategy set subject name from topic, default is topic name strategy ("<topic>-value")
+func SerdeSetSubjectNameStrategy(subjectNameFunc func(topic string) string) SerdeOptionFunc {
+	return func(o *serdeOption) {
+		o.subjectNameFunc = subjectNameFunc
+	}
+}
+
+// SerdeSetSubjectSchema set schema definition for subject, used as writer schema when serialize
+// and as reader schema (avro) when deserialize. Without this option, serializer use latest schema in subject
+func SerdeSetSubjectSchema(subject, schema string) SerdeOptionFunc {
+	return func(o *serdeOption) {
+		o.schemas[subject] = schema
+	}
+}
+
+func newSerdeOption(opts ...SerdeOptionFunc) serdeOption {
+	opt := serdeOption{
+		autoRegister: true,
+		subjectNameFunc: func(topic string) string {
+			return topic + "-value"
+		},
+		schemas: make(map[string]string),
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+	return opt
+}
+
+// resolveWriterSchema get schema id for serialize message in topic
+func (o *serdeOption) resolveWriterSchema(ctx context.Context, registry SchemaRegistry, schemaType SchemaType, topic string) (*RegisteredSchema, error) {
+	subject := o.subjectNameFunc(topic)
+	if schema, ok := o.schemas[subject]; ok && o.autoRegister {
+		id, err := registry.Register(ctx, subject, schemaType, schema)
+		if err != nil {
+			return nil, err
+		}
+		return &RegisteredSchema{ID: id, Subject: subject, SchemaType: schemaType, Schema: schema}, nil
+	}
+
+	latest, err := registry.GetLatestSchema(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	if latest.SchemaType != schemaType {
+		return nil, fmt.Errorf("%w: subject %s registered with schema type %s", ErrSchemaIncompatible, subject, latest.SchemaType)
+	}
+	return latest, nil
+}
+
+type avroSerde struct {
+	registry SchemaRegistry
+	opt      serdeOption
+
+	mu     sync.RWMutex
+	parsed map[string]avro.Schema
+}
+
+// NewAvroSerde create kafka avro serializer and deserializer with schema registry
+func NewAvroSerde(registry SchemaRegistry, opts ...SerdeOptionFunc) KafkaSerde {
+	return &avroSerde{
+		registry: registry,
+		opt:      newSerdeOption(opts...),
+		parsed:   make(map[string]avro.Schema),
+	}
+}
+
+func (s *avroSerde) Serialize(ctx context.Context, topic string, value any) ([]byte, error) {
+	writer, err := s.opt.resolveWriterSchema(ctx, s.registry, SchemaTypeAvro, topic)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := s.parse(writer.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := avro.Marshal(schema, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSchemaIncompatible, err)
+	}
+	return encodeWireFormat(writer.ID, nil, payload), nil
+}
+
+func (s *avroSerde) Deserialize(ctx context.Context, topic string, data []byte, target any) error {
+	schemaID, payload, err := decodeWireFormat(data)
+	if err != nil {
+		return err
+	}
+	registered, err := s.registry.GetSchemaByID(ctx, schemaID)
+	if err != nil {
+		return err
+	}
+	if registered.SchemaType != SchemaTypeAvro {
+		return fmt.Errorf("%w: schema id %d is %s schema", ErrSchemaIncompatible, schemaID, registered.SchemaType)
+	}
+	schema, err := s.parse(registered.Schema)
+	if err != nil {
+		return err
+	}
+
+	if readerSchema, ok := s.opt.schemas[s.opt.subjectNameFunc(topic)]; ok {
+		reader, err := s.parse(readerSchema)
+		if err != nil {
+			return err
+		}
+		if schema, err = avro.NewSchemaCompatibility().Resolve(reader, schema); err != nil {
+			return fmt.Errorf("%w: %v", ErrSchemaIncompatible, err)
+		}
+	}
+
+	if err := avro.Unmarshal(schema, payload, target); err != nil {
+		return fmt.Errorf("%w: %v", ErrSchemaIncompatible, err)
+	}
+	return nil
+}
+
+func (s *avroSerde) parse(schemaStr string) (avro.Schema, error) {
+	s.mu.RLock()
+	schema, ok := s.parsed[schemaStr]
+	s.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	schema, err := avro.Parse(schemaStr)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.parsed[schemaStr] = schema
+	s.mu.Unlock()
+	return schema, nil
+}
+
+type protobufSerde struct {
+	registry SchemaRegistry
+	opt      serdeOption
+}
+
+// NewProtobufSerde create kafka protobuf serializer and deserializer with schema registry,
+// value and target must implement proto.Message
+func NewProtobufSerde(registry SchemaRegistry, opts ...SerdeOptionFunc) KafkaSerde {
+	return &protobufSerde{
+		registry: registry,
+		opt:      newSerdeOption(opts...),
+	}
+}
+
+func (s *protobufSerde) Serialize(ctx context.Context, topic string, value any) ([]byte, error) {
+	message, ok := value.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: value %T is not proto message", ErrSchemaIncompatible, value)
+	}
+	writer, err := s.opt.resolveWriterSchema(ctx, s.registry, SchemaTypeProtobuf, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := proto.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	return encodeWireFormat(writer.ID, protoMessageIndexes(message.ProtoReflect().Descriptor()), payload), nil
+}
+
+func (s *protobufSerde) Deserialize(ctx context.Context, topic string, data []byte, target any) error {
+	message, ok := target.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%w: target %T is not proto message", ErrSchemaIncompatible, target)
+	}
+	schemaID, data, err := decodeWireFormat(data)
+	if err != nil {
+		return err
+	}
+	messageIndexes, payload, err := decodeMessageIndexes(data)
+	if err != nil {
+		return err
+	}
+
+	registered, err := s.registry.GetSchemaByID(ctx, schemaID)
+	if err != nil {
+		return err
+	}
+	if registered.SchemaType != SchemaTypeProtobuf {
+		return fmt.Errorf("%w: schema id %d is %s schema", ErrSchemaIncompatible, schemaID, registered.SchemaType)
+	}
+	if targetIndexes := protoMessageIndexes(message.ProtoReflect().Descriptor()); !slices.Equal(targetIndexes, messageIndexes) {
+		return fmt.Errorf("%w: message indexes %v differ with target %s indexes %v",
+			ErrSchemaIncompatible, messageIndexes, message.ProtoReflect().Descriptor().FullName(), targetIndexes)
+	}
+
+	if err := proto.Unmarshal(payload, message); err != nil {
+		return fmt.Errorf("%w: %v", ErrSchemaIncompatible, err)
+	}
+	return nil
+}
+
+// protoMessageIndexes get message position path in proto file descriptor
+func protoMessageIndexes(desc protoreflect.MessageDescriptor) (indexes []int) {
+	for {
+		indexes = append([]int{desc.Index()}, indexes...)
+		parent, ok := desc.Parent().(protoreflect.MessageDescriptor)
+		if !ok {
+			return indexes
+		}
+		desc = parent
+	}
+}
+
+func avroBackwardCompatibilityChecker(schemaType SchemaType, latest, newSchema string) error {
+	if schemaType != SchemaTypeAvro {
+		return nil
+	}
+	writer, err := avro.Parse(latest)
+	if err != nil {
+		return err
+	}
+	reader, err := avro.Parse(newSchema)
+	if err != nil {
+		return err
+	}
+	return avro.NewSchemaCompatibility().Compatible(reader, writer)
+}
+
+var kafkaSerdeContextKey candishared.ContextKey = "kafka_serde"
+
+// SetKafkaSerdeToContext set kafka serde to context, used by kafka worker for deserialize message in handler
+func SetKafkaSerdeToContext(ctx context.Context, serde KafkaSerde) context.Context {
+	return candishared.SetToContext(ctx, kafkaSerdeContextKey, serde)
+}
+
+// DeserializeKafkaMessage deserialize consumed message in kafka worker handler with serde from kafka broker,
+// returning ErrSchemaIncompatible, ErrSchemaNotFound or ErrInvalidWireFormat if message not match with registered schema
+func DeserializeKafkaMessage(eventContext *candishared.EventContext, target any) error {
+	serde, ok := candishared.GetValueFromContext(eventContext.Context(), kafkaSerdeContextKey).(KafkaSerde)
+	if !ok {
+		return fmt.Errorf("kafka serde is not set in broker, use KafkaSetSerde option")
+	}
+	return serde.Deserialize(eventContext.Context(), eventContext.HandlerRoute(), eventContext.Message(), target)
+}
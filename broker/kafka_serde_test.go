@@ -0,0 +1,83 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	testAvroSchemaV1 = `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"},{"name":"amount","type":"long"}]}`
+	testAvroSchemaV2 = `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"},{"name":"amount","type":"long"},{"name":"note","type":"string","default":""}]}`
+	testAvroSchemaV3 = `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"},{"name":"status","type":"int"}]}`
+)
+
+type testOrder struct {
+	ID     string `avro:"id"`
+	Amount int64  `avro:"amount"`
+	Note   string `avro:"note"`
+}
+
+func TestAvroSerde(t *testing.T) {
+	ctx := context.Background()
+	registry := NewInMemorySchemaRegistry()
+
+	producer := NewAvroSerde(registry, SerdeSetSubjectSchema("order-value", testAvroSchemaV1))
+	data, err := producer.Serialize(ctx, "order", testOrder{ID: "001", Amount: 10})
+	assert.NoError(t, err)
+	schemaID, err := GetSchemaIDFromPayload(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, schemaID)
+
+	// consumer with newer reader schema
+	consumer := NewAvroSerde(registry, SerdeSetSubjectSchema("order-value", testAvroSchemaV2), SerdeSetAutoRegister(false))
+	var order testOrder
+	assert.NoError(t, consumer.Deserialize(ctx, "order", data, &order))
+	assert.Equal(t, testOrder{ID: "001", Amount: 10}, order)
+
+	// incompatible schema version
+	_, err = registry.Register(ctx, "order-value", SchemaTypeAvro, testAvroSchemaV3)
+	assert.True(t, errors.Is(err, ErrSchemaIncompatible))
+
+	err = consumer.Deserialize(ctx, "order", []byte("plain message"), &order)
+	assert.True(t, errors.Is(err, ErrInvalidWireFormat))
+	err = consumer.Deserialize(ctx, "order", encodeWireFormat(99, nil, nil), &order)
+	assert.True(t, errors.Is(err, ErrSchemaNotFound))
+}
+
+func TestProtobufSerde(t *testing.T) {
+	ctx := context.Background()
+	registry := NewInMemorySchemaRegistry()
+
+	serde := NewProtobufSerde(registry, SerdeSetSubjectSchema("greeting-value", `syntax = "proto3"; message StringValue { string value = 1; }`))
+	data, err := serde.Serialize(ctx, "greeting", wrapperspb.String("hello"))
+	assert.NoError(t, err)
+
+	var result wrapperspb.StringValue
+	assert.NoError(t, serde.Deserialize(ctx, "greeting", data, &result))
+	assert.Equal(t, "hello", result.GetValue())
+
+	_, err = serde.Serialize(ctx, "greeting", "not proto")
+	assert.True(t, errors.Is(err, ErrSchemaIncompatible))
+
+	avroSerde := NewAvroSerde(registry)
+	err = avroSerde.Deserialize(ctx, "greeting", data, &result)
+	assert.True(t, errors.Is(err, ErrSchemaIncompatible))
+}
+
+func TestWireFormatMessageIndexes(t *testing.T) {
+	for _, indexes := range [][]int{{0}, {1}, {2, 0, 3}} {
+		data := encodeWireFormat(7, indexes, []byte("payload"))
+		schemaID, rest, err := decodeWireFormat(data)
+		assert.NoError(t, err)
+		assert.Equal(t, 7, schemaID)
+
+		decoded, payload, err := decodeMessageIndexes(rest)
+		assert.NoError(t, err)
+		assert.Equal(t, indexes, decoded)
+		assert.Equal(t, []byte("payload"), payload)
+	}
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// lruCache bounded in-process cache, least recently used entry is evicted when full
+	lruCache struct {
+		mu         sync.Mutex
+		maxEntries int
+		ll         *list.List
+		items      map[string]*list.Element
+		version    uint64 // increased on every invalidation
+	}
+
+	lruEntry struct {
+		key       string
+		value     []byte
+		negative  bool
+		expiredAt time.Time
+	}
+)
+
+func newLRUCache(maxEntries int) *lruCache {
+	return &lruCache{maxEntries: maxEntries, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get return entry if exist and not expired
+func (c *lruCache) get(key string) (entry lruEntry, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return entry, false
+	}
+	entry = *elem.Value.(*lruEntry)
+	if time.Now().After(entry.expiredAt) {
+		c.removeElement(elem)
+		return entry, false
+	}
+	c.ll.MoveToFront(elem)
+	return entry, true
+}
+
+// currentVersion return version before load value from remote, used in set
+func (c *lruCache) currentVersion() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version
+}
+
+// set store entry, skipped (return false) if invalidation happened after version (value from remote may be stale)
+func (c *lruCache) set(version uint64, key string, value []byte, negative bool, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if version != c.version {
+		return false
+	}
+
+	entry := &lruEntry{key: key, value: value, negative: negative, expiredAt: time.Now().Add(ttl)}
+	if elem, ok := c.items[key]; ok {
+		elem.Value = entry
+		c.ll.MoveToFront(elem)
+		return true
+	}
+	c.items[key] = c.ll.PushFront(entry)
+	for c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		c.removeElement(c.ll.Back())
+	}
+	return true
+}
+
+// delete remove key, if key has suffix "*" remove all key with same prefix
+func (c *lruCache) delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	prefix, isPattern := strings.CutSuffix(key, "*")
+	if !isPattern {
+		if elem, ok := c.items[key]; ok {
+			c.removeElement(elem)
+		}
+		return
+	}
+	for k, elem := range c.items {
+		if strings.HasPrefix(k, prefix) {
+			c.removeElement(elem)
+		}
+	}
+}
+
+func (c *lruCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version++
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+func (c *lruCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *lruCache) removeElement(elem *list.Element) {
+	c.ll.Remove(elem)
+	delete(c.items, elem.Value.(*lruEntry).key)
+}
@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache(t *testing.T) {
+	c := newLRUCache(2)
+
+	c.set(c.currentVersion(), "a", []byte("1"), false, time.Minute)
+	c.set(c.currentVersion(), "b", nil, true, time.Minute)
+	_, ok := c.get("a")
+	assert.True(t, ok)
+
+	// "b" is least recently used
+	c.set(c.currentVersion(), "c", []byte("3"), false, time.Minute)
+	_, ok = c.get("b")
+	assert.False(t, ok)
+	assert.Equal(t, 2, c.len())
+
+	c.set(c.currentVersion(), "d", []byte("4"), false, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	_, ok = c.get("d")
+	assert.False(t, ok)
+
+	// value loaded before invalidation is not stored
+	version := c.currentVersion()
+	c.delete("prefix:*")
+	c.set(version, "e", []byte("5"), false, time.Minute)
+	_, ok = c.get("e")
+	assert.False(t, ok)
+
+	c.set(c.currentVersion(), "prefix:1", []byte("1"), false, time.Minute)
+	c.set(c.currentVersion(), "prefix:2", []byte("2"), false, time.Minute)
+	c.delete("prefix:*")
+	assert.Equal(t, 0, c.len())
+}
@@ -0,0 +1,279 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/golangid/candi/logger"
+	"github.com/golangid/candi/tracer"
+	"github.com/gomodule/redigo/redis"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultLocalMaxEntries     = 10000
+	defaultLocalTTL            = time.Minute
+	defaultNegativeTTL         = 10 * time.Second
+	defaultInvalidationChannel = "candi:cache:invalidation"
+)
+
+type (
+	// MultiTierCache implement interfaces.Cache, bounded in-process LRU cache (L1) in front of redis (L2).
+	// Set and Delete is written to redis and invalidated in all instances with redis pub/sub,
+	// value in L1 is stale at most local TTL if invalidation message is missed.
+	MultiTierCache struct {
+		remote     *RedisCache
+		local      *lruCache
+		instanceID string
+
+		maxEntries          int
+		localTTL            time.Duration
+		negativeTTL         time.Duration
+		invalidationChannel string
+
+		hits, negativeHits, misses atomic.Int64
+
+		ctx    context.Context
+		cancel context.CancelFunc
+		done   chan struct{}
+	}
+
+	// MultiTierCacheOption option func
+	MultiTierCacheOption func(*MultiTierCache)
+
+	// MultiTierCacheStats hit/miss metrics of in-process cache
+	MultiTierCacheStats struct {
+		Hits         int64 `json:"hits"`
+		NegativeHits int64 `json:"negative_hits"`
+		Misses       int64 `json:"misses"`
+		Entries      int   `json:"entries"`
+	}
+)
+
+// SetLocalMaxEntries option, max entries in in-process cache (default 10000)
+func SetLocalMaxEntries(max int) MultiTierCacheOption {
+	return func(m *MultiTierCache) {
+		m.maxEntries = max
+	}
+}
+
+// SetLocalTTL option, max age of value in in-process cache (default 1 minute)
+func SetLocalTTL(ttl time.Duration) MultiTierCacheOption {
+	return func(m *MultiTierCache) {
+		m.localTTL = ttl
+	}
+}
+
+// SetNegativeTTL option, age of not found key in in-process cache (default 10 seconds), set 0 for disable negative caching
+func SetNegativeTTL(ttl time.Duration) MultiTierCacheOption {
+	return func(m *MultiTierCache) {
+		m.negativeTTL = ttl
+	}
+}
+
+// SetInvalidationChannel option, redis pub/sub channel for invalidation (default "candi:cache:invalidation")
+func SetInvalidationChannel(channel string) MultiTierCacheOption {
+	return func(m *MultiTierCache) {
+		m.invalidationChannel = channel
+	}
+}
+
+// NewMultiTierCache constructor, subscribe invalidation channel until Close is called
+func NewMultiTierCache(remote *RedisCache, opts ...MultiTierCacheOption) *MultiTierCache {
+	m := &MultiTierCache{
+		remote:              remote,
+		instanceID:          uuid.NewString(),
+		maxEntries:          defaultLocalMaxEntries,
+		localTTL:            defaultLocalTTL,
+		negativeTTL:         defaultNegativeTTL,
+		invalidationChannel: defaultInvalidationChannel,
+		done:                make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.local = newLRUCache(m.maxEntries)
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+
+	go m.subscribe()
+	return m
+}
+
+// Get method, return redis.ErrNil if key not found
+func (m *MultiTierCache) Get(ctx context.Context, key string) (data []byte, err error) {
+	trace, ctx := tracer.StartTraceWithContext(ctx, "cache:get")
+	defer func() { trace.Finish(tracer.FinishWithError(err)) }()
+
+	trace.SetTag("db.key", key)
+	defer func() {
+		stats := m.Stats()
+		trace.SetTag("cache.local_hits", stats.Hits+stats.NegativeHits)
+		trace.SetTag("cache.local_misses", stats.Misses)
+		trace.SetTag("cache.local_entries", stats.Entries)
+	}()
+
+	if entry, ok := m.local.get(key); ok {
+		trace.SetTag("cache.tier", "local")
+		trace.SetTag("cache.hit", true)
+		if entry.negative {
+			m.negativeHits.Add(1)
+			trace.SetTag("cache.negative", true)
+			return nil, redis.ErrNil
+		}
+		m.hits.Add(1)
+		return entry.value, nil
+	}
+	m.misses.Add(1)
+	trace.SetTag("cache.tier", "remote")
+	trace.SetTag("cache.hit", false)
+
+	version := m.local.currentVersion()
+	data, err = m.remote.Get(ctx, key)
+	switch {
+	case err == nil:
+		m.local.set(version, key, data, false, m.localTTL)
+	case errors.Is(err, redis.ErrNil) && m.negativeTTL > 0:
+		m.local.set(version, key, nil, true, m.negativeTTL)
+	}
+	return data, err
+}
+
+// GetKeys method
+func (m *MultiTierCache) GetKeys(ctx context.Context, pattern string) ([]string, error) {
+	return m.remote.GetKeys(ctx, pattern)
+}
+
+// GetTTL method
+func (m *MultiTierCache) GetTTL(ctx context.Context, key string) (time.Duration, error) {
+	return m.remote.GetTTL(ctx, key)
+}
+
+// Set method, []byte and string value is stored in in-process cache, other type is loaded from redis in next Get
+func (m *MultiTierCache) Set(ctx context.Context, key string, value any, expire time.Duration) (err error) {
+	// version is read before write to redis, value is not stored in local if key is invalidated by another write in the meantime
+	version := m.local.currentVersion()
+	if err = m.remote.Set(ctx, key, value, expire); err != nil {
+		m.local.delete(key)
+		return err
+	}
+	m.publishInvalidation(ctx, key)
+
+	ttl := m.localTTL
+	if expire >= 0 {
+		ttl = min(ttl, expire)
+	}
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	}
+	if ttl <= 0 || data == nil || !m.local.set(version, key, data, false, ttl) {
+		m.local.delete(key)
+	}
+	return nil
+}
+
+// Exists method
+func (m *MultiTierCache) Exists(ctx context.Context, key string) (bool, error) {
+	if entry, ok := m.local.get(key); ok {
+		return !entry.negative, nil
+	}
+	return m.remote.Exists(ctx, key)
+}
+
+// Delete method with pattern
+func (m *MultiTierCache) Delete(ctx context.Context, key string) error {
+	err := m.remote.Delete(ctx, key)
+	m.invalidate(ctx, key)
+	return err
+}
+
+// DoCommand method to execute any Redis command, first argument of write command is invalidated as key
+func (m *MultiTierCache) DoCommand(ctx context.Context, isWrite bool, command string, args ...any) (reply any, err error) {
+	reply, err = m.remote.DoCommand(ctx, isWrite, command, args...)
+	if isWrite && len(args) > 0 {
+		switch key := args[0].(type) {
+		case string:
+			m.invalidate(ctx, key)
+		case []byte:
+			m.invalidate(ctx, string(key))
+		}
+	}
+	return reply, err
+}
+
+// Stats return hit/miss metrics of in-process cache
+func (m *MultiTierCache) Stats() MultiTierCacheStats {
+	return MultiTierCacheStats{
+		Hits:         m.hits.Load(),
+		NegativeHits: m.negativeHits.Load(),
+		Misses:       m.misses.Load(),
+		Entries:      m.local.len(),
+	}
+}
+
+// Close stop subscribe invalidation channel
+func (m *MultiTierCache) Close() error {
+	m.cancel()
+	<-m.done
+	return nil
+}
+
+// invalidate delete key in current instance and publish to other instances
+func (m *MultiTierCache) invalidate(ctx context.Context, key string) {
+	m.local.delete(key)
+	m.publishInvalidation(ctx, key)
+}
+
+// publishInvalidation publish key to other instances
+func (m *MultiTierCache) publishInvalidation(ctx context.Context, key string) {
+	if _, err := m.remote.DoCommand(ctx, true, "PUBLISH", m.invalidationChannel, m.instanceID+" "+key); err != nil {
+		logger.LogYellow("multi tier cache > publish invalidation: " + err.Error())
+	}
+}
+
+func (m *MultiTierCache) subscribe() {
+	defer close(m.done)
+
+	for m.ctx.Err() == nil {
+		err := m.receiveInvalidation()
+		if m.ctx.Err() != nil {
+			return
+		}
+		logger.LogYellow("multi tier cache > subscribe invalidation: " + err.Error())
+		select {
+		case <-m.ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (m *MultiTierCache) receiveInvalidation() error {
+	psc := redis.PubSubConn{Conn: m.remote.write.Get()}
+	defer psc.Close()
+
+	if err := psc.Subscribe(m.invalidationChannel); err != nil {
+		return err
+	}
+	for {
+		switch v := psc.ReceiveContext(m.ctx).(type) {
+		case redis.Subscription:
+			if v.Kind == "subscribe" {
+				// invalidation message may be missed while disconnected
+				m.local.clear()
+			}
+		case redis.Message:
+			instanceID, key, _ := strings.Cut(string(v.Data), " ")
+			if instanceID != m.instanceID {
+				m.local.delete(key)
+			}
+		case error:
+			return v
+		}
+	}
+}
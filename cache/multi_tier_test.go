@@ -0,0 +1,289 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	// fakeRedis in memory redis server for GET, SET, EXPIRE, EXISTS, DEL, KEYS, PUBLISH and SUBSCRIBE command
+	fakeRedis struct {
+		mu          sync.Mutex
+		data        map[string][]byte
+		subscribers map[string][]chan any
+		gets        int
+		beforeSet   func(key string)
+	}
+	fakeRedisConn struct {
+		server   *fakeRedis
+		channel  string
+		messages chan any
+	}
+)
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{data: map[string][]byte{}, subscribers: map[string][]chan any{}}
+}
+
+func (f *fakeRedis) pool() *redis.Pool {
+	return &redis.Pool{Dial: func() (redis.Conn, error) { return &fakeRedisConn{server: f}, nil }}
+}
+
+func (f *fakeRedis) getCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.gets
+}
+
+func (c *fakeRedisConn) Do(command string, args ...any) (any, error) {
+	f := c.server
+	if command == "SET" && f.beforeSet != nil {
+		f.beforeSet(args[0].(string))
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch command {
+	case "GET":
+		f.gets++
+		if value, ok := f.data[args[0].(string)]; ok {
+			return value, nil
+		}
+		return nil, nil
+	case "SET":
+		f.data[args[0].(string)] = []byte(fmt.Sprint(args[1]))
+		if v, ok := args[1].([]byte); ok {
+			f.data[args[0].(string)] = v
+		}
+		return "OK", nil
+	case "EXPIRE":
+		return int64(1), nil
+	case "EXISTS":
+		_, ok := f.data[args[0].(string)]
+		return map[bool]int64{true: 1}[ok], nil
+	case "DEL":
+		delete(f.data, args[0].(string))
+		return int64(1), nil
+	case "KEYS":
+		var keys []any
+		for key := range f.data {
+			if strings.HasPrefix(key, strings.TrimSuffix(args[0].(string), "*")) {
+				keys = append(keys, []byte(key))
+			}
+		}
+		return keys, nil
+	case "":
+		return nil, nil
+	case "PUBLISH":
+		channel, data := args[0].(string), []byte(args[1].(string))
+		for _, subscriber := range f.subscribers[channel] {
+			subscriber <- []any{[]byte("message"), []byte(channel), data}
+		}
+		return int64(len(f.subscribers[channel])), nil
+	}
+	return nil, fmt.Errorf("unsupported command %s", command)
+}
+
+// Send handle subscribe command and command from pool for close subscribed connection
+func (c *fakeRedisConn) Send(command string, args ...any) error {
+	switch command {
+	case "SUBSCRIBE":
+		c.channel, c.messages = args[0].(string), make(chan any, 100)
+		c.messages <- []any{[]byte("subscribe"), []byte(c.channel), int64(1)}
+		c.server.mu.Lock()
+		defer c.server.mu.Unlock()
+		c.server.subscribers[c.channel] = append(c.server.subscribers[c.channel], c.messages)
+	case "UNSUBSCRIBE", "PUNSUBSCRIBE":
+		c.unsubscribe()
+	case "ECHO":
+		c.messages <- args[0]
+	default:
+		return fmt.Errorf("unsupported command %s", command)
+	}
+	return nil
+}
+
+func (c *fakeRedisConn) unsubscribe() {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	subscribers := c.server.subscribers[c.channel]
+	for i, subscriber := range subscribers {
+		if subscriber == c.messages {
+			c.server.subscribers[c.channel] = append(subscribers[:i], subscribers[i+1:]...)
+			break
+		}
+	}
+}
+
+func (c *fakeRedisConn) ReceiveContext(ctx context.Context) (any, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case message := <-c.messages:
+		return message, nil
+	}
+}
+
+func (c *fakeRedisConn) Close() error {
+	c.unsubscribe()
+	return nil
+}
+
+func (c *fakeRedisConn) Err() error   { return nil }
+func (c *fakeRedisConn) Flush() error { return nil }
+func (c *fakeRedisConn) Receive() (any, error) {
+	return c.ReceiveContext(context.Background())
+}
+func (c *fakeRedisConn) DoContext(ctx context.Context, command string, args ...any) (any, error) {
+	return c.Do(command, args...)
+}
+
+// newTestMultiTierCache wait until invalidation channel is subscribed
+func newTestMultiTierCache(t *testing.T, server *fakeRedis, opts ...MultiTierCacheOption) *MultiTierCache {
+	m := NewMultiTierCache(NewRedisCache(server.pool(), server.pool()), opts...)
+	t.Cleanup(func() { m.Close() })
+	require.Eventually(t, func() bool { return m.local.currentVersion() > 0 }, time.Second, time.Millisecond)
+	return m
+}
+
+func TestMultiTierCacheGet(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedis()
+	server.data["key"] = []byte("value")
+	m := newTestMultiTierCache(t, server)
+
+	for range 2 {
+		data, err := m.Get(ctx, "key")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("value"), data)
+	}
+	assert.Equal(t, 1, server.getCount())
+
+	t.Run("negative caching", func(t *testing.T) {
+		for range 2 {
+			_, err := m.Get(ctx, "not-found")
+			assert.ErrorIs(t, err, redis.ErrNil)
+		}
+		assert.Equal(t, 2, server.getCount())
+		exists, err := m.Exists(ctx, "not-found")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+		assert.Equal(t, MultiTierCacheStats{Hits: 1, NegativeHits: 1, Misses: 2, Entries: 2}, m.Stats())
+	})
+
+	t.Run("negative caching is disabled", func(t *testing.T) {
+		m := newTestMultiTierCache(t, server, SetNegativeTTL(0))
+		before := server.getCount()
+		for range 2 {
+			_, err := m.Get(ctx, "not-found")
+			assert.ErrorIs(t, err, redis.ErrNil)
+		}
+		assert.Equal(t, before+2, server.getCount())
+	})
+}
+
+func TestMultiTierCacheSet(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedis()
+	m := newTestMultiTierCache(t, server)
+
+	require.NoError(t, m.Set(ctx, "string", "value", time.Minute))
+	data, err := m.Get(ctx, "string")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), data)
+	assert.Equal(t, 0, server.getCount())
+
+	// other type is loaded from redis
+	require.NoError(t, m.Set(ctx, "int", 10, time.Minute))
+	data, err = m.Get(ctx, "int")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("10"), data)
+	assert.Equal(t, 1, server.getCount())
+
+	t.Run("negative cache is replaced", func(t *testing.T) {
+		_, err := m.Get(ctx, "new")
+		assert.ErrorIs(t, err, redis.ErrNil)
+		require.NoError(t, m.Set(ctx, "new", []byte("value"), time.Minute))
+		data, err := m.Get(ctx, "new")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("value"), data)
+	})
+
+	t.Run("invalidated while writing to redis", func(t *testing.T) {
+		// another instance write the same key while this instance is writing to redis
+		server.beforeSet = func(key string) {
+			version := m.local.currentVersion()
+			m.remote.DoCommand(ctx, true, "PUBLISH", m.invalidationChannel, "another-instance "+key)
+			for m.local.currentVersion() == version {
+				time.Sleep(time.Millisecond)
+			}
+		}
+		defer func() { server.beforeSet = nil }()
+
+		require.NoError(t, m.Set(ctx, "string", "new value", time.Minute))
+		_, ok := m.local.get("string")
+		assert.False(t, ok)
+	})
+}
+
+func TestMultiTierCacheInvalidation(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedis()
+	a, b := newTestMultiTierCache(t, server), newTestMultiTierCache(t, server)
+	isInvalidated := func(m *MultiTierCache, key string) func() bool {
+		return func() bool { _, ok := m.local.get(key); return !ok }
+	}
+
+	t.Run("set in another instance", func(t *testing.T) {
+		require.NoError(t, a.Set(ctx, "key", "1", time.Minute))
+		data, _ := b.Get(ctx, "key")
+		assert.Equal(t, []byte("1"), data)
+
+		require.NoError(t, a.Set(ctx, "key", "2", time.Minute))
+		require.Eventually(t, isInvalidated(b, "key"), time.Second, time.Millisecond)
+		data, _ = b.Get(ctx, "key")
+		assert.Equal(t, []byte("2"), data)
+		// invalidation from itself is skipped
+		data, _ = a.Get(ctx, "key")
+		assert.Equal(t, []byte("2"), data)
+	})
+
+	t.Run("negative cache in another instance", func(t *testing.T) {
+		_, err := b.Get(ctx, "new")
+		assert.ErrorIs(t, err, redis.ErrNil)
+
+		require.NoError(t, a.Set(ctx, "new", "1", time.Minute))
+		require.Eventually(t, isInvalidated(b, "new"), time.Second, time.Millisecond)
+		data, err := b.Get(ctx, "new")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("1"), data)
+	})
+
+	t.Run("delete with pattern", func(t *testing.T) {
+		for _, key := range []string{"prefix:1", "prefix:2"} {
+			require.NoError(t, a.Set(ctx, key, key, time.Minute))
+			data, _ := b.Get(ctx, key)
+			assert.Equal(t, []byte(key), data)
+		}
+
+		require.NoError(t, a.Delete(ctx, "prefix:*"))
+		for _, key := range []string{"prefix:1", "prefix:2"} {
+			require.Eventually(t, isInvalidated(b, key), time.Second, time.Millisecond)
+			_, err := b.Get(ctx, key)
+			assert.True(t, errors.Is(err, redis.ErrNil))
+			_, err = a.Get(ctx, key)
+			assert.True(t, errors.Is(err, redis.ErrNil))
+		}
+		data, _ := b.Get(ctx, "key")
+		assert.Equal(t, []byte("2"), data)
+	})
+}
@@ -42,8 +42,14 @@ const (
 	HeaderXForwardedFor = "X-Forwarded-For"
 	// HeaderXRealIP const
 	HeaderXRealIP = "X-Real-IP"
+	// HeaderXRequestID const
+	HeaderXRequestID = "X-Request-ID"
+	// HeaderRetryAfter const
+	HeaderRetryAfter = "Retry-After"
 	// HeaderContentType const
 	HeaderContentType = "Content-Type"
+	// HeaderAccept const
+	HeaderAccept = "Accept"
 	// HeaderAuthorization const
 	HeaderAuthorization = "Authorization"
 	// HeaderCacheControl header const
@@ -30,6 +30,11 @@ const (
 
 // ParseDurationExpression with input format HH:mm:ss
 func ParseDurationExpression(t string) (duration, nextDuration time.Duration, err error) {
+	return ParseDurationExpressionInLocation(t, time.Local)
+}
+
+// ParseDurationExpressionInLocation with input format HH:mm:ss, time is evaluated in given location
+func ParseDurationExpressionInLocation(t string, loc *time.Location) (duration, nextDuration time.Duration, err error) {
 	interval, err := time.ParseDuration(t)
 	if err == nil {
 		return interval, 0, nil
@@ -86,7 +91,7 @@ func ParseDurationExpression(t string) (duration, nextDuration time.Duration, er
 		}
 	}
 
-	now := time.Now()
+	now := time.Now().In(loc)
 	atTime := time.Date(now.Year(), now.Month(), now.Day(), hour, min, sec, 0, now.Location())
 	if now.Before(atTime) {
 		duration = atTime.Sub(now)
@@ -1,6 +1,9 @@
 package candishared
 
-import "context"
+import (
+	"context"
+	"sync/atomic"
+)
 
 // ContextKey represent Key of all context
 type ContextKey string
@@ -20,13 +23,41 @@ const (
 
 	// ContextKeySQLTransaction context key
 	ContextKeySQLTransaction ContextKey = "sqltx"
+
+	// ContextKeyWebSocketInitPayload context key, payload from graphql websocket connection_init message
+	ContextKeyWebSocketInitPayload ContextKey = "webSocketInitPayload"
+
+	// ContextKeyRequestID context key
+	ContextKeyRequestID ContextKey = "requestID"
+
+	// ContextKeyTokenClaimHolder context key, *TokenClaimHolder filled by SetTokenClaimToContext in downstream context
+	ContextKeyTokenClaimHolder ContextKey = "tokenClaimHolder"
 )
 
+// TokenClaimHolder hold token claim set in downstream context (example: by auth middleware), so can be read
+// from upstream handler after request is served (example: for access log)
+type TokenClaimHolder struct {
+	tokenClaim atomic.Pointer[TokenClaim]
+}
+
+// Get token claim from holder, return nil if token claim is not set
+func (h *TokenClaimHolder) Get() *TokenClaim {
+	return h.tokenClaim.Load()
+}
+
 // SetToContext will set context with specific key
 func SetToContext(ctx context.Context, key ContextKey, value any) context.Context {
 	return context.WithValue(ctx, key, value)
 }
 
+// SetTokenClaimToContext set token claim to context and to TokenClaimHolder in context (if any)
+func SetTokenClaimToContext(ctx context.Context, tokenClaim *TokenClaim) context.Context {
+	if holder, ok := GetValueFromContext(ctx, ContextKeyTokenClaimHolder).(*TokenClaimHolder); ok {
+		holder.tokenClaim.Store(tokenClaim)
+	}
+	return SetToContext(ctx, ContextKeyTokenClaim, tokenClaim)
+}
+
 // GetValueFromContext will get context with specific key
 func GetValueFromContext(ctx context.Context, key ContextKey) any {
 	return ctx.Value(key)
@@ -41,3 +72,9 @@ func ParseTokenClaimFromContext(ctx context.Context) *TokenClaim {
 func ParseWorkerKeyFromContext(ctx context.Context) []byte {
 	return GetValueFromContext(ctx, ContextKeyWorkerKey).([]byte)
 }
+
+// ParseRequestIDFromContext get request ID from given context, return empty string if not found
+func ParseRequestIDFromContext(ctx context.Context) string {
+	requestID, _ := GetValueFromContext(ctx, ContextKeyRequestID).(string)
+	return requestID
+}
@@ -0,0 +1,80 @@
+package candishared
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrLockNotAcquired error returned if lock is held by another owner until wait timeout
+	ErrLockNotAcquired = errors.New("lock is held by another owner")
+	// ErrLockNotOwned error returned when refresh or release lock that already expired or acquired by another owner
+	ErrLockNotOwned = errors.New("lock is not owned")
+)
+
+type (
+	// LockOptions options for acquire lock
+	LockOptions struct {
+		// TTL lease duration of lock, lock is released automatically after TTL if not refreshed
+		TTL time.Duration
+		// WaitTimeout max duration waiting lock released by another owner, zero for try once
+		WaitTimeout time.Duration
+		// RetryInterval interval between acquire attempt while waiting
+		RetryInterval time.Duration
+		// AutoRenew refresh lease periodically (every TTL/3) until lock is released
+		AutoRenew bool
+		// Token owner token of lock, default is random UUID
+		Token string
+	}
+
+	// LockOption function type for setting lock options
+	LockOption func(*LockOptions)
+)
+
+// LockSetTTL set lease duration of lock
+func LockSetTTL(ttl time.Duration) LockOption {
+	return func(o *LockOptions) {
+		o.TTL = ttl
+	}
+}
+
+// LockSetWaitTimeout set max duration waiting lock released by another owner
+func LockSetWaitTimeout(timeout time.Duration) LockOption {
+	return func(o *LockOptions) {
+		o.WaitTimeout = timeout
+	}
+}
+
+// LockSetRetryInterval set interval between acquire attempt while waiting, default is 50ms
+func LockSetRetryInterval(interval time.Duration) LockOption {
+	return func(o *LockOptions) {
+		o.RetryInterval = interval
+	}
+}
+
+// LockSetAutoRenew refresh lease periodically until lock is released, for long running job
+func LockSetAutoRenew(autoRenew bool) LockOption {
+	return func(o *LockOptions) {
+		o.AutoRenew = autoRenew
+	}
+}
+
+// LockSetToken set owner token of lock (ex: with instance id for identify the owner), token must be unique for each acquisition
+// and not numeric
+func LockSetToken(token string) LockOption {
+	return func(o *LockOptions) {
+		o.Token = token
+	}
+}
+
+// ParseLockOptions parse lock options with default TTL (30 seconds if zero)
+func ParseLockOptions(defaultTTL time.Duration, opts ...LockOption) LockOptions {
+	if defaultTTL <= 0 {
+		defaultTTL = 30 * time.Second
+	}
+	opt := LockOptions{TTL: defaultTTL, RetryInterval: 50 * time.Millisecond}
+	for _, o := range opts {
+		o(&opt)
+	}
+	return opt
+}
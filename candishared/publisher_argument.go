@@ -17,7 +17,8 @@ type PublisherArgument struct {
 	IsDeleteMessage bool
 	Timestamp       time.Time
 
-	// Deprecated : use Message
+	// Data will be serialized with broker serde if configured (ex: kafka schema registry serde),
+	// otherwise deprecated : use Message
 	Data any
 }
 
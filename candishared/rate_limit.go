@@ -0,0 +1,14 @@
+package candishared
+
+import "time"
+
+// RateLimitResult result from rate limiter
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter duration until quota fully restored
+	ResetAfter time.Duration
+	// RetryAfter duration until next request allowed, zero if allowed
+	RetryAfter time.Duration
+}
@@ -0,0 +1,372 @@
+package candiutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golangid/candi/logger"
+	"github.com/golangid/candi/tracer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Circuit breaker with state closed -> open (reject all call until cool down) -> half-open (allow some probe call) -> closed,
+// shared by name in one runtime for http client (see HTTPRequestSetBreakerName), grpc client and arbitrary function
+
+const (
+	// CircuitBreakerClosed state, all call is allowed
+	CircuitBreakerClosed CircuitBreakerState = iota
+	// CircuitBreakerOpen state, all call is rejected with ErrCircuitBreakerOpen
+	CircuitBreakerOpen
+	// CircuitBreakerHalfOpen state, limited probe call is allowed
+	CircuitBreakerHalfOpen
+)
+
+var (
+	// ErrCircuitBreakerOpen error returned when circuit breaker is open
+	ErrCircuitBreakerOpen = errors.New("circuit breaker is open")
+	// ErrCircuitBreakerTooManyRequests error returned when probe call in half-open state exceed max requests
+	ErrCircuitBreakerTooManyRequests = errors.New("circuit breaker is half-open, too many requests")
+
+	circuitBreakers   = map[string]*CircuitBreaker{}
+	circuitBreakersMu sync.Mutex
+)
+
+type (
+	// CircuitBreakerState type
+	CircuitBreakerState int
+
+	// CircuitBreakerOptions for circuit breaker
+	CircuitBreakerOptions struct {
+		// ConsecutiveFailures trip to open if consecutive failures reach this value, 0 for disable
+		ConsecutiveFailures int
+		// FailureRatio trip to open if failure ratio reach this value after MinRequests in interval, 0 for disable
+		FailureRatio float64
+		MinRequests  int
+		// Interval reset counts in closed state, 0 for never reset
+		Interval time.Duration
+		// CoolDown duration of open state before half-open
+		CoolDown time.Duration
+		// HalfOpenMaxRequests max probe call in half-open state, close circuit if all probe call is success
+		HalfOpenMaxRequests int
+		// IsFailure classify error as failure, default is all error except context canceled
+		IsFailure func(err error) bool
+		// Fallback called by Execute when call is rejected or failed, return value of fallback is returned by Execute
+		Fallback func(ctx context.Context, err error) error
+		// OnStateChange called when state changed, must not call method of the circuit breaker (called while holding lock)
+		OnStateChange func(name string, from, to CircuitBreakerState)
+	}
+
+	// CircuitBreakerOption function type for setting options
+	CircuitBreakerOption func(*CircuitBreakerOptions)
+
+	// CircuitBreaker implementation
+	CircuitBreaker struct {
+		mu         sync.Mutex
+		name       string
+		opt        CircuitBreakerOptions
+		state      CircuitBreakerState
+		generation uint64
+		expiry     time.Time
+
+		requests, failures, consecutiveFailures int
+		halfOpenRequests, halfOpenSuccesses     int
+	}
+)
+
+// String state name
+func (s CircuitBreakerState) String() string {
+	switch s {
+	case CircuitBreakerClosed:
+		return "closed"
+	case CircuitBreakerOpen:
+		return "open"
+	case CircuitBreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
+// WithConsecutiveFailuresCircuitBreaker sets consecutive failures threshold, default is 5
+func WithConsecutiveFailuresCircuitBreaker(threshold int) CircuitBreakerOption {
+	return func(o *CircuitBreakerOptions) {
+		o.ConsecutiveFailures = threshold
+	}
+}
+
+// WithFailureRatioCircuitBreaker sets failure ratio threshold after min requests, default is 0.5 after 10 requests
+func WithFailureRatioCircuitBreaker(ratio float64, minRequests int) CircuitBreakerOption {
+	return func(o *CircuitBreakerOptions) {
+		o.FailureRatio = ratio
+		o.MinRequests = minRequests
+	}
+}
+
+// WithIntervalCircuitBreaker sets interval for reset counts in closed state, default is 1 minute
+func WithIntervalCircuitBreaker(interval time.Duration) CircuitBreakerOption {
+	return func(o *CircuitBreakerOptions) {
+		o.Interval = interval
+	}
+}
+
+// WithCoolDownCircuitBreaker sets duration of open state before half-open, default is 30 seconds
+func WithCoolDownCircuitBreaker(coolDown time.Duration) CircuitBreakerOption {
+	return func(o *CircuitBreakerOptions) {
+		o.CoolDown = coolDown
+	}
+}
+
+// WithHalfOpenMaxRequestsCircuitBreaker sets max probe call in half-open state, default is 1
+func WithHalfOpenMaxRequestsCircuitBreaker(maxRequests int) CircuitBreakerOption {
+	return func(o *CircuitBreakerOptions) {
+		o.HalfOpenMaxRequests = maxRequests
+	}
+}
+
+// WithIsFailureCircuitBreaker sets error classifier, error not classified as failure is counted as success
+func WithIsFailureCircuitBreaker(isFailure func(err error) bool) CircuitBreakerOption {
+	return func(o *CircuitBreakerOptions) {
+		o.IsFailure = isFailure
+	}
+}
+
+// WithFallbackCircuitBreaker sets fallback for Execute when call is rejected or failed
+func WithFallbackCircuitBreaker(fallback func(ctx context.Context, err error) error) CircuitBreakerOption {
+	return func(o *CircuitBreakerOptions) {
+		o.Fallback = fallback
+	}
+}
+
+// WithOnStateChangeCircuitBreaker sets hook when state changed
+func WithOnStateChangeCircuitBreaker(onStateChange func(name string, from, to CircuitBreakerState)) CircuitBreakerOption {
+	return func(o *CircuitBreakerOptions) {
+		o.OnStateChange = onStateChange
+	}
+}
+
+// NewCircuitBreaker constructor, not registered in registry (see RegisterCircuitBreaker)
+func NewCircuitBreaker(name string, opts ...CircuitBreakerOption) *CircuitBreaker {
+	opt := CircuitBreakerOptions{
+		ConsecutiveFailures: 5,
+		FailureRatio:        0.5,
+		MinRequests:         10,
+		Interval:            time.Minute,
+		CoolDown:            30 * time.Second,
+		HalfOpenMaxRequests: 1,
+		IsFailure: func(err error) bool {
+			return !errors.Is(err, context.Canceled)
+		},
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+	if opt.HalfOpenMaxRequests <= 0 {
+		opt.HalfOpenMaxRequests = 1
+	}
+
+	cb := &CircuitBreaker{name: name, opt: opt}
+	cb.newGeneration(time.Now())
+	return cb
+}
+
+// RegisterCircuitBreaker create circuit breaker with options and register (replace if exist) by name
+func RegisterCircuitBreaker(name string, opts ...CircuitBreakerOption) *CircuitBreaker {
+	cb := NewCircuitBreaker(name, opts...)
+	circuitBreakersMu.Lock()
+	circuitBreakers[name] = cb
+	circuitBreakersMu.Unlock()
+	return cb
+}
+
+// GetCircuitBreaker get registered circuit breaker by name, register new circuit breaker with default options if not exist
+func GetCircuitBreaker(name string) *CircuitBreaker {
+	circuitBreakersMu.Lock()
+	defer circuitBreakersMu.Unlock()
+
+	cb, ok := circuitBreakers[name]
+	if !ok {
+		cb = NewCircuitBreaker(name)
+		circuitBreakers[name] = cb
+	}
+	return cb
+}
+
+// Name of circuit breaker
+func (cb *CircuitBreaker) Name() string {
+	return cb.name
+}
+
+// State get current state
+func (cb *CircuitBreaker) State() CircuitBreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.currentState(context.Background(), time.Now())
+}
+
+// Allow check call is allowed, done must be called with result of the call (failure or not) if allowed
+func (cb *CircuitBreaker) Allow(ctx context.Context) (done func(failure bool), err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.currentState(ctx, time.Now()) {
+	case CircuitBreakerOpen:
+		return nil, ErrCircuitBreakerOpen
+	case CircuitBreakerHalfOpen:
+		if cb.halfOpenRequests >= cb.opt.HalfOpenMaxRequests {
+			return nil, ErrCircuitBreakerTooManyRequests
+		}
+		cb.halfOpenRequests++
+	}
+	cb.requests++
+
+	generation := cb.generation
+	return func(failure bool) { cb.done(ctx, generation, failure) }, nil
+}
+
+// Execute fn if allowed, error from fn classified as failure (see WithIsFailureCircuitBreaker) is counted to trip the circuit.
+// Fallback is called if call is rejected or failed
+func (cb *CircuitBreaker) Execute(ctx context.Context, fn func(context.Context) error) (err error) {
+	done, err := cb.Allow(ctx)
+	if err != nil {
+		tracer.Log(ctx, "circuit_breaker.rejected", cb.name)
+		return cb.fallback(ctx, err)
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			done(true)
+			panic(rec)
+		}
+	}()
+
+	err = fn(ctx)
+	failure := err != nil && cb.opt.IsFailure(err)
+	done(failure)
+	if failure {
+		return cb.fallback(ctx, err)
+	}
+	return err
+}
+
+// UnaryClientInterceptor grpc client interceptor, error with code Unavailable, DeadlineExceeded, ResourceExhausted,
+// Internal or Unknown is counted as failure. If call is rejected and fallback return nil, reply is left as is
+func (cb *CircuitBreaker) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		done, err := cb.Allow(ctx)
+		if err != nil {
+			tracer.Log(ctx, "circuit_breaker.rejected", cb.name)
+			if fallbackErr := cb.fallback(ctx, err); fallbackErr != nil {
+				return status.Error(codes.Unavailable, fallbackErr.Error())
+			}
+			return nil
+		}
+
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		switch status.Code(err) {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+			done(true)
+		default:
+			done(false)
+		}
+		return err
+	}
+}
+
+func (cb *CircuitBreaker) fallback(ctx context.Context, err error) error {
+	if cb.opt.Fallback == nil {
+		return err
+	}
+	return cb.opt.Fallback(ctx, err)
+}
+
+func (cb *CircuitBreaker) done(ctx context.Context, generation uint64, failure bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	now := time.Now()
+	state := cb.currentState(ctx, now)
+	if generation != cb.generation {
+		return
+	}
+
+	if !failure {
+		cb.consecutiveFailures = 0
+		if state == CircuitBreakerHalfOpen {
+			cb.halfOpenSuccesses++
+			if cb.halfOpenSuccesses >= cb.opt.HalfOpenMaxRequests {
+				cb.setState(ctx, CircuitBreakerClosed, now)
+			}
+		}
+		return
+	}
+
+	cb.failures++
+	cb.consecutiveFailures++
+	switch state {
+	case CircuitBreakerHalfOpen:
+		cb.setState(ctx, CircuitBreakerOpen, now)
+	case CircuitBreakerClosed:
+		if cb.readyToTrip() {
+			cb.setState(ctx, CircuitBreakerOpen, now)
+		}
+	}
+}
+
+func (cb *CircuitBreaker) readyToTrip() bool {
+	if cb.opt.ConsecutiveFailures > 0 && cb.consecutiveFailures >= cb.opt.ConsecutiveFailures {
+		return true
+	}
+	return cb.opt.FailureRatio > 0 && cb.requests >= cb.opt.MinRequests &&
+		float64(cb.failures)/float64(cb.requests) >= cb.opt.FailureRatio
+}
+
+// currentState update state by time (open -> half-open after cool down, reset counts in closed state after interval)
+func (cb *CircuitBreaker) currentState(ctx context.Context, now time.Time) CircuitBreakerState {
+	switch cb.state {
+	case CircuitBreakerClosed:
+		if !cb.expiry.IsZero() && now.After(cb.expiry) {
+			cb.newGeneration(now)
+		}
+	case CircuitBreakerOpen:
+		if now.After(cb.expiry) {
+			cb.setState(ctx, CircuitBreakerHalfOpen, now)
+		}
+	}
+	return cb.state
+}
+
+func (cb *CircuitBreaker) setState(ctx context.Context, state CircuitBreakerState, now time.Time) {
+	if cb.state == state {
+		return
+	}
+	prev := cb.state
+	cb.state = state
+	cb.newGeneration(now)
+
+	tracer.Log(ctx, "circuit_breaker."+cb.name, prev.String()+" -> "+state.String())
+	logger.LogYellow(fmt.Sprintf("[CIRCUIT-BREAKER] %s: state changed from %s to %s", cb.name, prev, state))
+	if cb.opt.OnStateChange != nil {
+		cb.opt.OnStateChange(cb.name, prev, state)
+	}
+}
+
+func (cb *CircuitBreaker) newGeneration(now time.Time) {
+	cb.generation++
+	cb.requests, cb.failures, cb.consecutiveFailures = 0, 0, 0
+	cb.halfOpenRequests, cb.halfOpenSuccesses = 0, 0
+
+	cb.expiry = time.Time{}
+	switch cb.state {
+	case CircuitBreakerClosed:
+		if cb.opt.Interval > 0 {
+			cb.expiry = now.Add(cb.opt.Interval)
+		}
+	case CircuitBreakerOpen:
+		cb.expiry = now.Add(cb.opt.CoolDown)
+	}
+}
@@ -0,0 +1,46 @@
+package candiutils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	var states []CircuitBreakerState
+	cb := NewCircuitBreaker("test",
+		WithConsecutiveFailuresCircuitBreaker(3),
+		WithCoolDownCircuitBreaker(50*time.Millisecond),
+		WithOnStateChangeCircuitBreaker(func(name string, from, to CircuitBreakerState) { states = append(states, to) }),
+	)
+	errDownstream := errors.New("downstream error")
+	failed := func(context.Context) error { return errDownstream }
+	success := func(context.Context) error { return nil }
+
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, cb.Execute(ctx, failed), errDownstream)
+	}
+	assert.Equal(t, CircuitBreakerOpen, cb.State())
+	assert.ErrorIs(t, cb.Execute(ctx, success), ErrCircuitBreakerOpen)
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, CircuitBreakerHalfOpen, cb.State())
+	assert.ErrorIs(t, cb.Execute(ctx, failed), errDownstream)
+	assert.Equal(t, CircuitBreakerOpen, cb.State())
+
+	time.Sleep(60 * time.Millisecond)
+	assert.NoError(t, cb.Execute(ctx, success))
+	assert.Equal(t, CircuitBreakerClosed, cb.State())
+	assert.Equal(t, []CircuitBreakerState{CircuitBreakerOpen, CircuitBreakerHalfOpen, CircuitBreakerOpen, CircuitBreakerHalfOpen, CircuitBreakerClosed}, states)
+
+	cb = NewCircuitBreaker("ratio", WithConsecutiveFailuresCircuitBreaker(0), WithFailureRatioCircuitBreaker(0.5, 4),
+		WithFallbackCircuitBreaker(func(ctx context.Context, err error) error { return nil }))
+	for _, fn := range []func(context.Context) error{success, failed, success, failed} {
+		assert.NoError(t, cb.Execute(ctx, fn))
+	}
+	assert.Equal(t, CircuitBreakerOpen, cb.State())
+}
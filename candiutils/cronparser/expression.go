@@ -30,6 +30,7 @@ type expression struct {
 	lastWeekDaysOfWeek     map[int]bool
 	daysOfWeekRestricted   bool
 	yearList               []int
+	location               *time.Location
 }
 
 // MustParse returns a new Expression pointer. It expects a well-formed cron
@@ -50,8 +51,15 @@ func MustParse(cronLine string) Schedule {
 // See <https://github.com/gorhill/cronexpr#implementation> for documentation
 // about what is a well-formed cron expression from this library's point of
 // view.
+//
+// Schedule is evaluated in timezone of CRON_TZ= or TZ= prefix if exist, example: "CRON_TZ=Asia/Jakarta 0 9 * * *".
 func Parse(cronLine string) (Schedule, error) {
 
+	loc, cronLine, err := SplitTimezone(cronLine)
+	if err != nil {
+		return nil, err
+	}
+
 	// Maybe one of the built-in aliases is being used
 	cron := cronNormalizer.Replace(cronLine)
 
@@ -65,9 +73,8 @@ func Parse(cronLine string) (Schedule, error) {
 		fieldCount = 7
 	}
 
-	var expr = expression{}
+	var expr = expression{location: loc}
 	var field = 0
-	var err error
 
 	// second field (optional)
 	if fieldCount == 7 {
@@ -128,12 +135,33 @@ func Parse(cronLine string) (Schedule, error) {
 	return &expr, nil
 }
 
+// ParseInLocation like Parse but schedule is evaluated in given location if cron line has no timezone prefix
+func ParseInLocation(cronLine string, loc *time.Location) (Schedule, error) {
+	schedule, err := Parse(cronLine)
+	if err != nil {
+		return nil, err
+	}
+	if expr := schedule.(*expression); expr.location == nil {
+		expr.location = loc
+	}
+	return schedule, nil
+}
+
+// Location method
+func (expr *expression) Location() *time.Location {
+	return expr.location
+}
+
 // Next returns the closest time instant immediately following `fromTime` which
-// matches the cron expression `expr`.
+// matches the cron expression `expr` in location of schedule.
 //
 // The `time.Location` of the returned time instant is the same as that of
 // `fromTime`.
 //
+// Daylight saving time transition: time in the gap (not exist) is run after the gap
+// with same offset from transition, time in the overlap (occur twice) is run once
+// at first occurrence, except hour field is `*` (run in both occurrences).
+//
 // The zero value of time.Time is returned if no matching time instant exists
 // or if a `fromTime` is itself a zero value.
 func (expr *expression) Next(fromTime time.Time) time.Time {
@@ -142,6 +170,40 @@ func (expr *expression) Next(fromTime time.Time) time.Time {
 		return fromTime
 	}
 
+	loc := expr.location
+	if loc == nil {
+		loc = fromTime.Location()
+	}
+	// copy expression, actualDaysOfMonthList is changed when calculate next time
+	e := *expr
+	t := fromTime.In(loc)
+
+	// every hour match, minute and second is same in absolute time if offset is changed in whole hour
+	if len(e.hourList) == 24 {
+		_, offset := t.Zone()
+		next := e.next(t.In(time.FixedZone("", offset)))
+		if next.IsZero() || e.matches(next.In(loc)) {
+			return next.In(fromTime.Location())
+		}
+	}
+
+	// calculate next wall clock time (in UTC, without transition) and resolve to instant in location
+	wall := toWallClock(t)
+	for {
+		wall = e.next(wall)
+		if wall.IsZero() {
+			return wall
+		}
+		instants := resolveWallClock(wall, loc)
+		if instants[0].After(fromTime) {
+			return instants[0].In(fromTime.Location())
+		}
+		// fromTime is in second occurrence of overlap, first occurrence has been passed
+	}
+}
+
+// next calculate next time in location of fromTime (without handling daylight saving time transition)
+func (expr *expression) next(fromTime time.Time) time.Time {
 	// Since expr.nextSecond()-expr.nextMonth() expects that the
 	// supplied time stamp is a perfect match to the underlying cron
 	// expression, and since this function is an entry point where `fromTime`
@@ -216,7 +278,51 @@ func (expr *expression) Next(fromTime time.Time) time.Time {
 	return expr.nextSecond(fromTime)
 }
 
+// matches check time is match with cron expression
+func (expr *expression) matches(t time.Time) bool {
+	return containsInt(expr.yearList, t.Year()) && containsInt(expr.monthList, int(t.Month())) &&
+		containsInt(expr.calculateActualDaysOfMonth(t.Year(), int(t.Month())), t.Day()) &&
+		containsInt(expr.hourList, t.Hour()) && containsInt(expr.minuteList, t.Minute()) &&
+		containsInt(expr.secondList, t.Second())
+}
+
 // NextInterval method
 func (expr *expression) NextInterval(fromTime time.Time) time.Duration {
 	return expr.Next(fromTime).Sub(fromTime)
 }
+
+func containsInt(list []int, v int) bool {
+	i := sort.SearchInts(list, v)
+	return i < len(list) && list[i] == v
+}
+
+// toWallClock convert time to UTC with same wall clock
+func toWallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// resolveWallClock return instants (ascending) of wall clock in location,
+// wall clock in the gap is shifted after the gap and wall clock in the overlap has two instants
+func resolveWallClock(wall time.Time, loc *time.Location) []time.Time {
+	t := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), loc)
+	if !toWallClock(t).Equal(wall) {
+		// wall clock in the gap, use offset before transition
+		_, offsetBefore := t.Add(-24 * time.Hour).Zone()
+		return []time.Time{wall.Add(-time.Duration(offsetBefore) * time.Second).In(loc)}
+	}
+
+	instants := []time.Time{t}
+	_, offset := t.Zone()
+	for _, probe := range []time.Time{t.Add(-24 * time.Hour), t.Add(24 * time.Hour)} {
+		_, probeOffset := probe.Zone()
+		if probeOffset == offset {
+			continue
+		}
+		alt := t.Add(time.Duration(offset-probeOffset) * time.Second)
+		if !alt.Equal(t) && toWallClock(alt.In(loc)).Equal(wall) {
+			instants = append(instants, alt)
+		}
+	}
+	sort.Slice(instants, func(i, j int) bool { return instants[i].Before(instants[j]) })
+	return instants
+}
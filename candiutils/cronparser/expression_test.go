@@ -0,0 +1,80 @@
+package cronexpr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimezone(t *testing.T) {
+	schedule, err := Parse("CRON_TZ=Asia/Jakarta 0 9 * * *")
+	assert.NoError(t, err)
+	assert.Equal(t, "Asia/Jakarta", schedule.Location().String())
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	next := schedule.Next(from)
+	assert.Equal(t, time.Date(2025, 1, 1, 2, 0, 0, 0, time.UTC), next)
+	assert.Equal(t, time.UTC, next.Location())
+
+	schedule, err = ParseInLocation("TZ=UTC 0 9 * * *", time.FixedZone("", 7*3600))
+	assert.NoError(t, err)
+	assert.Equal(t, time.UTC, schedule.Location())
+
+	_, err = Parse("CRON_TZ=Invalid/Zone 0 9 * * *")
+	assert.Error(t, err)
+
+	assert.Equal(t, "CRON_TZ=UTC 0 9 * * *", WithTimezone("TZ=Asia/Jakarta 0 9 * * *", "UTC"))
+}
+
+func TestNextDaylightSavingTime(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	tests := []struct {
+		name, expr string
+		from       time.Time
+		want       []time.Time
+	}{
+		{
+			name: "gap, run after transition",
+			expr: "30 2 * * *",
+			from: time.Date(2025, 3, 9, 1, 0, 0, 0, loc),
+			want: []time.Time{time.Date(2025, 3, 9, 7, 30, 0, 0, time.UTC), time.Date(2025, 3, 10, 6, 30, 0, 0, time.UTC)},
+		},
+		{
+			name: "overlap, fixed hour run once",
+			expr: "30 1 * * *",
+			from: time.Date(2025, 11, 2, 0, 0, 0, 0, loc),
+			want: []time.Time{time.Date(2025, 11, 2, 5, 30, 0, 0, time.UTC), time.Date(2025, 11, 3, 6, 30, 0, 0, time.UTC)},
+		},
+		{
+			name: "overlap, every hour run in both occurrences",
+			expr: "30 * * * *",
+			from: time.Date(2025, 11, 2, 0, 45, 0, 0, loc),
+			want: []time.Time{
+				time.Date(2025, 11, 2, 5, 30, 0, 0, time.UTC), time.Date(2025, 11, 2, 6, 30, 0, 0, time.UTC),
+				time.Date(2025, 11, 2, 7, 30, 0, 0, time.UTC),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseInLocation(tt.expr, loc)
+			assert.NoError(t, err)
+
+			from := tt.from
+			for _, want := range tt.want {
+				from = schedule.Next(from)
+				assert.Equal(t, want, from.UTC())
+			}
+		})
+	}
+
+	// from second occurrence of overlap, first occurrence is not repeated
+	schedule, _ := ParseInLocation("45 1 * * *", loc)
+	from := time.Date(2025, 11, 2, 6, 10, 0, 0, time.UTC) // 01:10 EST
+	assert.Equal(t, time.Date(2025, 11, 3, 6, 45, 0, 0, time.UTC), schedule.Next(from).UTC())
+}
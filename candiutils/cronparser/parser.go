@@ -19,6 +19,8 @@ import (
 type Schedule interface {
 	Next(time.Time) time.Time
 	NextInterval(time.Time) time.Duration
+	// Location of schedule, schedule is evaluated in location of time argument if nil
+	Location() *time.Location
 }
 
 var (
@@ -176,6 +178,34 @@ var cronNormalizer = strings.NewReplacer(
 	"@daily", "0 0 0 * * * *",
 	"@hourly", "0 0 * * * * *")
 
+// SplitTimezone split timezone prefix (CRON_TZ= or TZ=) from cron line, location is nil if cron line has no timezone prefix,
+// example: "CRON_TZ=Asia/Jakarta 0 9 * * *"
+func SplitTimezone(cronLine string) (loc *time.Location, line string, err error) {
+	line = strings.TrimSpace(cronLine)
+	for _, prefix := range []string{"CRON_TZ=", "TZ="} {
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		timezone, rest, _ := strings.Cut(line[len(prefix):], " ")
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return nil, cronLine, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+		}
+		return loc, strings.TrimSpace(rest), nil
+	}
+	return nil, line, nil
+}
+
+// WithTimezone add timezone prefix to cron line, existing timezone prefix is replaced
+func WithTimezone(cronLine, timezone string) string {
+	if timezone == "" {
+		return cronLine
+	}
+	if _, line, err := SplitTimezone(cronLine); err == nil {
+		cronLine = line
+	}
+	return "CRON_TZ=" + timezone + " " + cronLine
+}
+
 func (expr *expression) secondFieldHandler(s string) error {
 	var err error
 	expr.secondList, err = genericFieldHandler(s, secondDescriptor)
@@ -0,0 +1,294 @@
+package candiutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/tracer"
+)
+
+// DataLoader, batch and cache load data by key in one request scope for solve N+1 query problem
+
+const (
+	defaultLoaderMaxBatchSize = 100
+	defaultLoaderWait         = 2 * time.Millisecond
+)
+
+var (
+	// ErrLoaderKeyNotFound error when key is not returned from batch function
+	ErrLoaderKeyNotFound = errors.New("dataloader: key not found")
+
+	dataLoaderRegistryContextKey candishared.ContextKey = "dataloader_registry"
+)
+
+type (
+	// LoaderBatchFunc batch function for load all keys in one call, missing key in result map will return ErrLoaderKeyNotFound
+	LoaderBatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+	// LoaderOptions for data loader
+	LoaderOptions struct {
+		Name         string
+		MaxBatchSize int
+		Wait         time.Duration
+		DisableCache bool
+	}
+
+	// LoaderOption function type for setting options
+	LoaderOption func(*LoaderOptions)
+
+	// Loader batch and cache load data by key
+	Loader[K comparable, V any] struct {
+		batchFn LoaderBatchFunc[K, V]
+		opt     LoaderOptions
+
+		mu    sync.Mutex
+		cache map[K]*loaderResult[V]
+		batch *loaderBatch[K, V]
+	}
+
+	loaderResult[V any] struct {
+		done  chan struct{}
+		value V
+		err   error
+	}
+
+	loaderBatch[K comparable, V any] struct {
+		ctx     context.Context
+		keys    []K
+		results map[K]*loaderResult[V]
+		timer   *time.Timer
+	}
+
+	dataLoaderRegistry struct {
+		mu      sync.Mutex
+		loaders map[string]any
+	}
+)
+
+// LoaderSetName set loader name, used in trace span
+func LoaderSetName(name string) LoaderOption {
+	return func(o *LoaderOptions) {
+		o.Name = name
+	}
+}
+
+// LoaderSetMaxBatchSize set max keys in one batch, default is 100
+func LoaderSetMaxBatchSize(max int) LoaderOption {
+	return func(o *LoaderOptions) {
+		o.MaxBatchSize = max
+	}
+}
+
+// LoaderSetWait set wait window for collect keys before dispatch batch, default is 2ms
+func LoaderSetWait(wait time.Duration) LoaderOption {
+	return func(o *LoaderOptions) {
+		o.Wait = wait
+	}
+}
+
+// LoaderSetDisableCache disable cache loaded value in loader
+func LoaderSetDisableCache(disable bool) LoaderOption {
+	return func(o *LoaderOptions) {
+		o.DisableCache = disable
+	}
+}
+
+// NewLoader construct new data loader
+func NewLoader[K comparable, V any](batchFn LoaderBatchFunc[K, V], opts ...LoaderOption) *Loader[K, V] {
+	l := &Loader[K, V]{
+		batchFn: batchFn,
+		opt: LoaderOptions{
+			Name:         "DataLoader",
+			MaxBatchSize: defaultLoaderMaxBatchSize,
+			Wait:         defaultLoaderWait,
+		},
+		cache: make(map[K]*loaderResult[V]),
+	}
+	for _, opt := range opts {
+		opt(&l.opt)
+	}
+	return l
+}
+
+// Load value by key, wait until batch containing the key is dispatched
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	res := l.schedule(ctx, key)
+	select {
+	case <-res.done:
+		return res.value, res.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// LoadMany load values by keys in one batch, return first error if any key is failed
+func (l *Loader[K, V]) LoadMany(ctx context.Context, keys []K) ([]V, error) {
+	results := make([]*loaderResult[V], len(keys))
+	for i, key := range keys {
+		results[i] = l.schedule(ctx, key)
+	}
+
+	values := make([]V, len(keys))
+	for i, res := range results {
+		select {
+		case <-res.done:
+		case <-ctx.Done():
+			return values, ctx.Err()
+		}
+		if res.err != nil {
+			return values, res.err
+		}
+		values[i] = res.value
+	}
+	return values, nil
+}
+
+// Prime set value for key to cache
+func (l *Loader[K, V]) Prime(key K, value V) {
+	if l.opt.DisableCache {
+		return
+	}
+	res := &loaderResult[V]{done: make(chan struct{}), value: value}
+	close(res.done)
+
+	l.mu.Lock()
+	l.cache[key] = res
+	l.mu.Unlock()
+}
+
+// Clear remove key from cache
+func (l *Loader[K, V]) Clear(key K) {
+	l.mu.Lock()
+	delete(l.cache, key)
+	l.mu.Unlock()
+}
+
+func (l *Loader[K, V]) schedule(ctx context.Context, key K) *loaderResult[V] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if res, ok := l.cache[key]; ok {
+		return res
+	}
+	if l.batch != nil {
+		if res, ok := l.batch.results[key]; ok {
+			return res
+		}
+	}
+
+	res := &loaderResult[V]{done: make(chan struct{})}
+	if !l.opt.DisableCache {
+		l.cache[key] = res
+	}
+
+	if l.batch == nil {
+		batch := &loaderBatch[K, V]{ctx: ctx, results: make(map[K]*loaderResult[V])}
+		batch.timer = time.AfterFunc(l.opt.Wait, func() {
+			l.mu.Lock()
+			if l.batch != batch {
+				l.mu.Unlock()
+				return
+			}
+			l.batch = nil
+			l.mu.Unlock()
+			l.dispatch(batch)
+		})
+		l.batch = batch
+	}
+	l.batch.keys = append(l.batch.keys, key)
+	l.batch.results[key] = res
+
+	if l.opt.MaxBatchSize > 0 && len(l.batch.keys) >= l.opt.MaxBatchSize {
+		batch := l.batch
+		batch.timer.Stop()
+		l.batch = nil
+		go l.dispatch(batch)
+	}
+	return res
+}
+
+func (l *Loader[K, V]) dispatch(batch *loaderBatch[K, V]) {
+	trace, ctx := tracer.StartTraceWithContext(context.WithoutCancel(batch.ctx), "DataLoader:"+l.opt.Name)
+	trace.SetTag("batch_size", len(batch.keys))
+	trace.Log("keys", fmt.Sprintf("%v", batch.keys))
+
+	var values map[K]V
+	var err error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("dataloader: panic in batch function: %v", r)
+			}
+		}()
+		values, err = l.batchFn(ctx, batch.keys)
+	}()
+	trace.Finish(tracer.FinishWithError(err))
+
+	for key, res := range batch.results {
+		if err != nil {
+			res.err = err
+		} else if value, ok := values[key]; ok {
+			res.value = value
+		} else {
+			res.err = ErrLoaderKeyNotFound
+		}
+		close(res.done)
+	}
+
+	if err != nil && !l.opt.DisableCache {
+		// failed result is not cached, next load will retry
+		l.mu.Lock()
+		for key, res := range batch.results {
+			if l.cache[key] == res {
+				delete(l.cache, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// WithDataLoaderRegistry install request scoped data loader registry to context, installed by graphql handler
+// for each request and can be used in rest handler with DataLoaderHTTPMiddleware
+func WithDataLoaderRegistry(ctx context.Context) context.Context {
+	if _, ok := candishared.GetValueFromContext(ctx, dataLoaderRegistryContextKey).(*dataLoaderRegistry); ok {
+		return ctx
+	}
+	return candishared.SetToContext(ctx, dataLoaderRegistryContextKey, &dataLoaderRegistry{loaders: make(map[string]any)})
+}
+
+// DataLoaderHTTPMiddleware install request scoped data loader registry in http request context
+func DataLoaderHTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		next.ServeHTTP(w, req.WithContext(WithDataLoaderRegistry(req.Context())))
+	})
+}
+
+// GetLoader get request scoped loader by name from context, loader is created once per request with batchFn and opts.
+// If registry is not installed in context, new loader is returned on each call (no batching between calls)
+func GetLoader[K comparable, V any](ctx context.Context, name string, batchFn LoaderBatchFunc[K, V], opts ...LoaderOption) *Loader[K, V] {
+	opts = append([]LoaderOption{LoaderSetName(name)}, opts...)
+	registry, ok := candishared.GetValueFromContext(ctx, dataLoaderRegistryContextKey).(*dataLoaderRegistry)
+	if !ok {
+		return NewLoader(batchFn, opts...)
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if loader, ok := registry.loaders[name].(*Loader[K, V]); ok {
+		return loader
+	}
+	if _, exist := registry.loaders[name]; exist {
+		// same name registered with different key/value type
+		return NewLoader(batchFn, opts...)
+	}
+	loader := NewLoader(batchFn, opts...)
+	registry.loaders[name] = loader
+	return loader
+}
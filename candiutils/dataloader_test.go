@@ -0,0 +1,102 @@
+package candiutils
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestBatchFunc batch function send each dispatched batch keys to returned channel,
+// negative key is not returned from batch function
+func newTestBatchFunc() (LoaderBatchFunc[int, string], chan []int) {
+	batches := make(chan []int, 10)
+	return func(ctx context.Context, keys []int) (map[int]string, error) {
+		batches <- slices.Clone(keys)
+		result := make(map[int]string, len(keys))
+		for _, key := range keys {
+			if key >= 0 {
+				result[key] = strconv.Itoa(key)
+			}
+		}
+		return result, nil
+	}, batches
+}
+
+func TestLoader(t *testing.T) {
+	// batch is dispatched only when max batch size is reached, wait timer is never fired in test
+	const wait = time.Hour
+
+	t.Run("concurrent load is batched and deduplicated", func(t *testing.T) {
+		batchFn, batches := newTestBatchFunc()
+		ctx := WithDataLoaderRegistry(context.Background())
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(key int) {
+				defer wg.Done()
+				value, err := GetLoader(ctx, "number", batchFn, LoaderSetMaxBatchSize(7), LoaderSetWait(wait)).Load(ctx, key%7)
+				assert.NoError(t, err)
+				assert.Equal(t, strconv.Itoa(key%7), value)
+			}(i)
+		}
+		wg.Wait()
+
+		require.Len(t, batches, 1)
+		keys := <-batches
+		slices.Sort(keys)
+		assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6}, keys)
+
+		// cached in request scope
+		values, err := GetLoader(ctx, "number", batchFn).LoadMany(ctx, []int{1, 2, 3})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1", "2", "3"}, values)
+		assert.Empty(t, batches)
+	})
+
+	t.Run("split by max batch size", func(t *testing.T) {
+		batchFn, batches := newTestBatchFunc()
+		loader := NewLoader(batchFn, LoaderSetMaxBatchSize(5), LoaderSetWait(wait))
+		values, err := loader.LoadMany(context.Background(), []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+		assert.NoError(t, err)
+		assert.Len(t, values, 10)
+
+		require.Len(t, batches, 2)
+		var keys []int
+		for range 2 {
+			batch := <-batches
+			assert.Len(t, batch, 5)
+			keys = append(keys, batch...)
+		}
+		slices.Sort(keys)
+		assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, keys)
+	})
+
+	t.Run("dispatched after wait", func(t *testing.T) {
+		batchFn, batches := newTestBatchFunc()
+		value, err := NewLoader(batchFn).Load(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "1", value)
+		assert.Equal(t, []int{1}, <-batches)
+	})
+
+	t.Run("key not found", func(t *testing.T) {
+		batchFn, _ := newTestBatchFunc()
+		_, err := NewLoader(batchFn).Load(context.Background(), -1)
+		assert.True(t, errors.Is(err, ErrLoaderKeyNotFound))
+	})
+
+	t.Run("batch function error", func(t *testing.T) {
+		failed := NewLoader(func(ctx context.Context, keys []string) (map[string]int, error) {
+			return nil, errors.New("database down")
+		})
+		_, err := failed.Load(context.Background(), "a")
+		assert.EqualError(t, err, "database down")
+	})
+}
@@ -0,0 +1,314 @@
+package candiutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/golangid/candi/candihelper"
+	"github.com/golangid/candi/logger"
+)
+
+// HTTP recorder, record request/response pairs of http client to cassette file and replay it for deterministic (offline) test
+
+const (
+	// HTTPRecordModeRecord mode, execute real request and save interactions to cassette (overwrite existing cassette)
+	HTTPRecordModeRecord HTTPRecordMode = "record"
+	// HTTPRecordModeReplay mode, serve response from cassette, return error if interaction not found
+	HTTPRecordModeReplay HTTPRecordMode = "replay"
+	// HTTPRecordModeReplayOrRecord mode, serve response from cassette, execute and save real request if interaction not found
+	HTTPRecordModeReplayOrRecord HTTPRecordMode = "replay_or_record"
+)
+
+var (
+	// ErrHTTPRecordNotFound error returned in replay mode if no recorded interaction match the request
+	ErrHTTPRecordNotFound = errors.New("http recorder: recorded interaction not found")
+)
+
+type (
+	// HTTPRecordMode type
+	HTTPRecordMode string
+
+	// HTTPRecordMatcher match request (after masked and redacted) with recorded interaction
+	HTTPRecordMatcher func(req *HTTPRecordedRequest, interaction *HTTPInteraction) bool
+
+	// HTTPInteraction recorded request and response pair
+	HTTPInteraction struct {
+		Request  HTTPRecordedRequest  `json:"request"`
+		Response HTTPRecordedResponse `json:"response"`
+	}
+
+	// HTTPRecordedRequest recorded request
+	HTTPRecordedRequest struct {
+		Method string      `json:"method"`
+		URL    string      `json:"url"`
+		Header http.Header `json:"header,omitempty"`
+		Body   string      `json:"body,omitempty"`
+	}
+
+	// HTTPRecordedResponse recorded response
+	HTTPRecordedResponse struct {
+		StatusCode int         `json:"status_code"`
+		Header     http.Header `json:"header,omitempty"`
+		Body       string      `json:"body,omitempty"`
+	}
+
+	// HTTPRecorderOption function type for setting http recorder options
+	HTTPRecorderOption func(*HTTPRecorder)
+
+	// HTTPRecorder implementation of http.RoundTripper with record/replay mode
+	HTTPRecorder struct {
+		mu            sync.Mutex
+		cassettePath  string
+		mode          HTTPRecordMode
+		transport     http.RoundTripper
+		masker        logger.Masker
+		redactHeaders []string
+		matchers      []HTTPRecordMatcher
+
+		interactions []*HTTPInteraction
+		replayed     []bool
+	}
+)
+
+// HTTPRecorderSetTransport set real transport for record mode, default is http.DefaultTransport
+func HTTPRecorderSetTransport(transport http.RoundTripper) HTTPRecorderOption {
+	return func(r *HTTPRecorder) {
+		r.transport = transport
+	}
+}
+
+// HTTPRecorderSetMasker set masker for redact sensitive value in URL and body before saved to cassette (see logger.NewMasker)
+func HTTPRecorderSetMasker(masker logger.Masker) HTTPRecorderOption {
+	return func(r *HTTPRecorder) {
+		r.masker = masker
+	}
+}
+
+// HTTPRecorderSetRedactHeaders set headers to redact before saved to cassette, default is Authorization, Cookie and Set-Cookie
+func HTTPRecorderSetRedactHeaders(headers ...string) HTTPRecorderOption {
+	return func(r *HTTPRecorder) {
+		r.redactHeaders = headers
+	}
+}
+
+// HTTPRecorderSetMatchers set rules for match request with recorded interaction, default is match method and URL
+func HTTPRecorderSetMatchers(matchers ...HTTPRecordMatcher) HTTPRecorderOption {
+	return func(r *HTTPRecorder) {
+		r.matchers = matchers
+	}
+}
+
+// HTTPRecordMatchMethod match request method
+func HTTPRecordMatchMethod(req *HTTPRecordedRequest, interaction *HTTPInteraction) bool {
+	return req.Method == interaction.Request.Method
+}
+
+// HTTPRecordMatchURL match full request URL (include query)
+func HTTPRecordMatchURL(req *HTTPRecordedRequest, interaction *HTTPInteraction) bool {
+	return req.URL == interaction.Request.URL
+}
+
+// HTTPRecordMatchURLPath match request URL without query
+func HTTPRecordMatchURLPath(req *HTTPRecordedRequest, interaction *HTTPInteraction) bool {
+	path, _, _ := strings.Cut(req.URL, "?")
+	recorded, _, _ := strings.Cut(interaction.Request.URL, "?")
+	return path == recorded
+}
+
+// HTTPRecordMatchBody match request body
+func HTTPRecordMatchBody(req *HTTPRecordedRequest, interaction *HTTPInteraction) bool {
+	return req.Body == interaction.Request.Body
+}
+
+// HTTPRecordMatchHeaders match value of request headers
+func HTTPRecordMatchHeaders(headers ...string) HTTPRecordMatcher {
+	return func(req *HTTPRecordedRequest, interaction *HTTPInteraction) bool {
+		for _, header := range headers {
+			if req.Header.Get(header) != interaction.Request.Header.Get(header) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// NewHTTPRecorder create http recorder with cassette file (JSON), use with HTTPRequestSetClient(recorder.Client()), example:
+//
+//	recorder, err := candiutils.NewHTTPRecorder("testdata/payment.json", candiutils.HTTPRecordModeReplay)
+//	httpReq := candiutils.NewHTTPRequest(candiutils.HTTPRequestSetClient(recorder.Client()))
+func NewHTTPRecorder(cassettePath string, mode HTTPRecordMode, opts ...HTTPRecorderOption) (*HTTPRecorder, error) {
+	r := &HTTPRecorder{
+		cassettePath:  cassettePath,
+		mode:          mode,
+		transport:     http.DefaultTransport,
+		redactHeaders: []string{candihelper.HeaderAuthorization, "Cookie", "Set-Cookie"},
+		matchers:      []HTTPRecordMatcher{HTTPRecordMatchMethod, HTTPRecordMatchURL},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if mode == HTTPRecordModeRecord {
+		return r, nil
+	}
+	content, err := os.ReadFile(cassettePath)
+	if err != nil {
+		if mode == HTTPRecordModeReplayOrRecord && errors.Is(err, os.ErrNotExist) {
+			return r, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &r.interactions); err != nil {
+		return nil, fmt.Errorf("http recorder: invalid cassette %s: %w", cassettePath, err)
+	}
+	r.replayed = make([]bool, len(r.interactions))
+	return r, nil
+}
+
+// Client create http client with recorder as transport
+func (r *HTTPRecorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implement http.RoundTripper
+func (r *HTTPRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	maskedReq := r.maskRequest(req, body)
+
+	if r.mode != HTTPRecordModeRecord {
+		if interaction := r.find(&maskedReq); interaction != nil {
+			return interaction.Response.toHTTPResponse(req), nil
+		}
+		if r.mode == HTTPRecordModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrHTTPRecordNotFound, maskedReq.Method, maskedReq.URL)
+		}
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := &HTTPInteraction{
+		Request: maskedReq,
+		Response: HTTPRecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     r.redactHeader(resp.Header),
+			Body:       r.mask(string(respBody)),
+		},
+	}
+	return resp, r.save(interaction)
+}
+
+// find first not replayed interaction matched with request, fallback to first matched interaction
+func (r *HTTPRecorder) find(req *HTTPRecordedRequest) *HTTPInteraction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matched := -1
+	for i, interaction := range r.interactions {
+		if !r.match(req, interaction) {
+			continue
+		}
+		if !r.replayed[i] {
+			r.replayed[i] = true
+			return interaction
+		}
+		if matched < 0 {
+			matched = i
+		}
+	}
+	if matched < 0 {
+		return nil
+	}
+	return r.interactions[matched]
+}
+
+func (r *HTTPRecorder) match(req *HTTPRecordedRequest, interaction *HTTPInteraction) bool {
+	for _, matcher := range r.matchers {
+		if !matcher(req, interaction) {
+			return false
+		}
+	}
+	return true
+}
+
+// save append interaction and write all interactions to cassette file
+func (r *HTTPRecorder) save(interaction *HTTPInteraction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.interactions = append(r.interactions, interaction)
+	r.replayed = append(r.replayed, true)
+
+	content, err := json.MarshalIndent(r.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.cassettePath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.cassettePath, content, 0644)
+}
+
+func (r *HTTPRecorder) maskRequest(req *http.Request, body []byte) HTTPRecordedRequest {
+	return HTTPRecordedRequest{
+		Method: req.Method,
+		URL:    r.mask(req.URL.String()),
+		Header: r.redactHeader(req.Header),
+		Body:   r.mask(string(body)),
+	}
+}
+
+func (r *HTTPRecorder) mask(text string) string {
+	if r.masker == nil || text == "" {
+		return text
+	}
+	return r.masker.Mask(text)
+}
+
+func (r *HTTPRecorder) redactHeader(header http.Header) http.Header {
+	header = header.Clone()
+	for _, key := range r.redactHeaders {
+		if _, ok := header[http.CanonicalHeaderKey(key)]; ok {
+			header.Set(key, "xxxxx")
+		}
+	}
+	return header
+}
+
+func (r *HTTPRecordedResponse) toHTTPResponse(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader([]byte(r.Body))),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
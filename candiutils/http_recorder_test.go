@@ -0,0 +1,46 @@
+package candiutils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golangid/candi/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"token":"secret-token","status":"paid"}`))
+	}))
+	cassette := filepath.Join(t.TempDir(), "payment.json")
+	reqBody := []byte(`{"order_id":"1","password":"secret"}`)
+
+	recorder, err := NewHTTPRecorder(cassette, HTTPRecordModeRecord, HTTPRecorderSetMasker(logger.NewMasker("password", "token")),
+		HTTPRecorderSetMatchers(HTTPRecordMatchMethod, HTTPRecordMatchURL, HTTPRecordMatchBody))
+	assert.NoError(t, err)
+	httpReq := NewHTTPRequest(HTTPRequestSetClient(recorder.Client()))
+	respBody, _, err := httpReq.Do(context.Background(), http.MethodPost, server.URL+"/pay", reqBody, map[string]string{"Authorization": "Bearer abc"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"token":"secret-token","status":"paid"}`, string(respBody))
+	server.Close()
+
+	content, _ := os.ReadFile(cassette)
+	assert.NotContains(t, string(content), "secret")
+	assert.NotContains(t, string(content), "Bearer abc")
+
+	recorder, err = NewHTTPRecorder(cassette, HTTPRecordModeReplay, HTTPRecorderSetMasker(logger.NewMasker("password", "token")),
+		HTTPRecorderSetMatchers(HTTPRecordMatchMethod, HTTPRecordMatchURL, HTTPRecordMatchBody))
+	assert.NoError(t, err)
+	httpReq = NewHTTPRequest(HTTPRequestSetClient(recorder.Client()), HTTPRequestSetRetries(0))
+	respBody, respCode, err := httpReq.Do(context.Background(), http.MethodPost, server.URL+"/pay", reqBody, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, respCode)
+	assert.Contains(t, string(respBody), `"status":"paid"`)
+
+	_, _, err = httpReq.Do(context.Background(), http.MethodPost, server.URL+"/refund", reqBody, nil)
+	assert.ErrorIs(t, err, ErrHTTPRecordNotFound)
+}
@@ -7,10 +7,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
 	"net/http/httputil"
+	"strconv"
 	"time"
 
+	"github.com/golangid/candi/candihelper"
+	"github.com/golangid/candi/candishared"
 	"github.com/golangid/candi/tracer"
 )
 
@@ -19,6 +23,7 @@ type (
 	HTTPRequest interface {
 		DoRequest(ctx context.Context, method, url string, requestBody []byte, headers map[string]string) (result *HTTPRequestResult, err error)
 		Do(context context.Context, method, url string, reqBody []byte, headers map[string]string) (respBody []byte, respCode int, err error)
+		DoStream(ctx context.Context, method, url string, requestBody []byte, headers map[string]string) (result *HTTPStreamResult, err error)
 	}
 
 	httpClientDo interface {
@@ -27,12 +32,21 @@ type (
 
 	// httpRequestImpl struct
 	httpRequestImpl struct {
-		client httpClientDo
+		client   httpClientDo
+		clientDo HTTPClientDoFunc
 
 		breakerName               string
+		fallback                  func(ctx context.Context, err error) (*HTTPRequestResult, error)
+		middlewares               []HTTPClientMiddleware
 		timeout                   time.Duration
+		timeoutSet                bool
 		retries                   int
 		sleepBetweenRetry         time.Duration
+		maxBackoff                time.Duration
+		retryDeadline             time.Duration
+		retryStatusCodes          map[int]struct{}
+		retryOnNetworkError       bool
+		idempotencyKeyHeader      string
 		tlsConfig                 *tls.Config
 		minHTTPErrorCodeThreshold int
 	}
@@ -43,24 +57,70 @@ type (
 		RespCode int
 	}
 
+	// HTTPStreamResult struct, result of streaming request
+	HTTPStreamResult struct {
+		Body     io.ReadCloser
+		Header   http.Header
+		RespCode int
+	}
+
 	// HTTPRequestOption func type
 	HTTPRequestOption func(*httpRequestImpl)
 )
 
-// HTTPRequestSetRetries option func
+// HTTPRequestSetRetries option func, set max retries after first attempt, default is 0 (no retry)
 func HTTPRequestSetRetries(retries int) HTTPRequestOption {
 	return func(h *httpRequestImpl) {
 		h.retries = retries
 	}
 }
 
-// HTTPRequestSetSleepBetweenRetry option func
+// HTTPRequestSetSleepBetweenRetry option func, set initial backoff of retry (doubled every attempt with jitter), default is 500ms
 func HTTPRequestSetSleepBetweenRetry(sleepBetweenRetry time.Duration) HTTPRequestOption {
 	return func(h *httpRequestImpl) {
 		h.sleepBetweenRetry = sleepBetweenRetry
 	}
 }
 
+// HTTPRequestSetMaxBackoff option func, set max backoff between retry, default is 30 seconds
+func HTTPRequestSetMaxBackoff(maxBackoff time.Duration) HTTPRequestOption {
+	return func(h *httpRequestImpl) {
+		h.maxBackoff = maxBackoff
+	}
+}
+
+// HTTPRequestSetRetryDeadline option func, set total time budget across all attempts, retry is stopped if next attempt exceed deadline
+func HTTPRequestSetRetryDeadline(deadline time.Duration) HTTPRequestOption {
+	return func(h *httpRequestImpl) {
+		h.retryDeadline = deadline
+	}
+}
+
+// HTTPRequestSetRetryStatusCodes option func, set response codes to retry, default is 408, 429, 500, 502, 503 and 504
+func HTTPRequestSetRetryStatusCodes(codes ...int) HTTPRequestOption {
+	return func(h *httpRequestImpl) {
+		h.retryStatusCodes = make(map[int]struct{}, len(codes))
+		for _, code := range codes {
+			h.retryStatusCodes[code] = struct{}{}
+		}
+	}
+}
+
+// HTTPRequestSetRetryOnNetworkError option func, retry on network error (ex: connection refused, timeout), default is true
+func HTTPRequestSetRetryOnNetworkError(retry bool) HTTPRequestOption {
+	return func(h *httpRequestImpl) {
+		h.retryOnNetworkError = retry
+	}
+}
+
+// HTTPRequestSetIdempotencyKeyHeader option func, non idempotent method (POST, PATCH) is retried only if request has this header,
+// default is Idempotency-Key
+func HTTPRequestSetIdempotencyKeyHeader(header string) HTTPRequestOption {
+	return func(h *httpRequestImpl) {
+		h.idempotencyKeyHeader = header
+	}
+}
+
 // HTTPRequestSetTLS option func
 func HTTPRequestSetTLS(tlsConfig *tls.Config) HTTPRequestOption {
 	return func(h *httpRequestImpl) {
@@ -75,21 +135,37 @@ func HTTPRequestSetHTTPErrorCodeThreshold(minHTTPStatusCode int) HTTPRequestOpti
 	}
 }
 
-// HTTPRequestSetTimeout option func
+// HTTPRequestSetTimeout option func, set timeout for each attempt, default is 10 seconds or timeout of client (HTTPRequestSetClient)
 func HTTPRequestSetTimeout(timeout time.Duration) HTTPRequestOption {
 	return func(h *httpRequestImpl) {
 		h.timeout = timeout
+		h.timeoutSet = true
 	}
 }
 
-// HTTPRequestSetBreakerName option func
+// HTTPRequestSetBreakerName option func, use circuit breaker with name from registry (see RegisterCircuitBreaker for custom options),
+// transport error and response code 5xx or 429 is counted as failure. Default is empty (without circuit breaker)
 func HTTPRequestSetBreakerName(breakerName string) HTTPRequestOption {
 	return func(h *httpRequestImpl) {
 		h.breakerName = breakerName
 	}
 }
 
-// HTTPRequestSetClient option func
+// HTTPRequestSetFallback option func, called when request is rejected by circuit breaker or failed
+func HTTPRequestSetFallback(fallback func(ctx context.Context, err error) (*HTTPRequestResult, error)) HTTPRequestOption {
+	return func(h *httpRequestImpl) {
+		h.fallback = fallback
+	}
+}
+
+// HTTPRequestAddMiddlewares option func, add middlewares for each attempt of request (ex: auth token injection, signing)
+func HTTPRequestAddMiddlewares(middlewares ...HTTPClientMiddleware) HTTPRequestOption {
+	return func(h *httpRequestImpl) {
+		h.middlewares = append(h.middlewares, middlewares...)
+	}
+}
+
+// HTTPRequestSetClient option func, timeout of the client is used as default timeout if HTTPRequestSetTimeout is not set
 func HTTPRequestSetClient(cl *http.Client) HTTPRequestOption {
 	return func(h *httpRequestImpl) {
 		h.client = cl
@@ -102,26 +178,36 @@ func NewHTTPRequest(opts ...HTTPRequestOption) HTTPRequest {
 	httpReq := new(httpRequestImpl)
 
 	// set default value
-	httpReq.retries = 5
 	httpReq.sleepBetweenRetry = 500 * time.Millisecond
+	httpReq.maxBackoff = 30 * time.Second
+	httpReq.retryOnNetworkError = true
+	httpReq.idempotencyKeyHeader = "Idempotency-Key"
+	httpReq.retryStatusCodes = map[int]struct{}{
+		http.StatusRequestTimeout: {}, http.StatusTooManyRequests: {}, http.StatusInternalServerError: {},
+		http.StatusBadGateway: {}, http.StatusServiceUnavailable: {}, http.StatusGatewayTimeout: {},
+	}
 	httpReq.minHTTPErrorCodeThreshold = http.StatusBadRequest
 	httpReq.timeout = 10 * time.Second
-	httpReq.breakerName = "default"
 
 	for _, o := range opts {
 		o(httpReq)
 	}
+	if cl, ok := httpReq.client.(*http.Client); ok && cl != nil && !httpReq.timeoutSet {
+		httpReq.timeout = cl.Timeout
+	}
 
-	// set http client
+	// set http client, timeout is applied for each attempt (see WithHTTPRequestTimeout for override per request)
 	if httpReq.client == nil {
-		client := &http.Client{
-			Timeout: httpReq.timeout,
-		}
+		client := &http.Client{}
 		if httpReq.tlsConfig != nil {
 			client.Transport = &http.Transport{TLSClientConfig: httpReq.tlsConfig}
 		}
 		httpReq.client = client
 	}
+	httpReq.clientDo = httpReq.client.Do
+	for i := len(httpReq.middlewares) - 1; i >= 0; i-- {
+		httpReq.clientDo = httpReq.middlewares[i](httpReq.clientDo)
+	}
 
 	return httpReq
 }
@@ -139,33 +225,60 @@ func (req *httpRequestImpl) Do(ctx context.Context, method, url string, requestB
 	return httpResult.Bytes(), httpResult.RespCode, nil
 }
 
+// DoRequest execute http request, retry with exponential backoff and jitter (or Retry-After response header) for retryable
+// response code or network error. Non idempotent method (POST, PATCH) is retried only if request has idempotency key header
 func (req *httpRequestImpl) DoRequest(ctx context.Context, method, url string, requestBody []byte, headers map[string]string) (result *HTTPRequestResult, err error) {
+	result, _, err = req.do(ctx, method, url, requestBody, headers, false)
+	return result, err
+}
+
+// DoStream execute http request like DoRequest without buffering response body (ex: large download),
+// Body in result must be closed by caller if result is not nil (include when error is returned)
+func (req *httpRequestImpl) DoStream(ctx context.Context, method, url string, requestBody []byte, headers map[string]string) (result *HTTPStreamResult, err error) {
+	_, resp, err := req.do(ctx, method, url, requestBody, headers, true)
+	if resp == nil {
+		return nil, err
+	}
+	return &HTTPStreamResult{Body: resp.Body, Header: resp.Header, RespCode: resp.StatusCode}, err
+}
+
+func (req *httpRequestImpl) do(ctx context.Context, method, url string, requestBody []byte, headers map[string]string, stream bool) (result *HTTPRequestResult, resp *http.Response, err error) {
 	// set request http
-	httpReq, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(requestBody))
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(requestBody))
 	if err != nil {
 		tracer.SetError(ctx, err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	// set tracer
 	trace, ctx := tracer.StartTraceWithContext(ctx, fmt.Sprintf("HTTP Request: %s %s", method, httpReq.URL.Host))
 	defer func() { trace.Finish(tracer.FinishWithError(err)) }()
+	httpReq = httpReq.WithContext(ctx)
 
 	if headers == nil {
 		headers = map[string]string{}
 	}
 	trace.InjectRequestHeader(headers)
+	if requestID := candishared.ParseRequestIDFromContext(ctx); requestID != "" {
+		if _, ok := headers[candihelper.HeaderXRequestID]; !ok {
+			headers[candihelper.HeaderXRequestID] = requestID
+		}
+	}
 
 	// iterate optional data of headers
 	for key, value := range headers {
 		httpReq.Header.Set(key, value)
 	}
 
+	timeout := req.timeout
+	if t, ok := ctx.Value(httpRequestTimeoutKey{}).(time.Duration); ok {
+		timeout = t
+	}
+
 	trace.SetTag("http.method", httpReq.Method)
 	trace.SetTag("http.url", httpReq.URL.String())
 	trace.SetTag("http.url_path", httpReq.URL.Path)
-	trace.SetTag("http.timeout", req.timeout.String())
-	trace.SetTag("http.breaker_name", req.breakerName)
+	trace.SetTag("http.timeout", timeout.String())
 
 	dumpRequest, _ := httputil.DumpRequest(httpReq, false)
 	trace.SetTag("http.request", dumpRequest)
@@ -173,26 +286,173 @@ func (req *httpRequestImpl) DoRequest(ctx context.Context, method, url string, r
 		trace.Log("request.body", requestBody)
 	}
 
-	resp, err := req.client.Do(httpReq)
+	var breaker *CircuitBreaker
+	if req.breakerName != "" {
+		trace.SetTag("http.breaker_name", req.breakerName)
+		breaker = GetCircuitBreaker(req.breakerName)
+	}
+
+	maxRetries := req.retries
+	if !isIdempotentMethod(httpReq.Method) && httpReq.Header.Get(req.idempotencyKeyHeader) == "" {
+		maxRetries = 0
+	}
+	var deadline time.Time
+	if req.retryDeadline > 0 {
+		deadline = time.Now().Add(req.retryDeadline)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			httpReq = httpReq.Clone(ctx)
+			httpReq.Body, _ = httpReq.GetBody()
+		}
+		result, resp, err = req.doAttempt(ctx, trace, breaker, httpReq, timeout, stream)
+		if attempt >= maxRetries || !req.isRetryable(ctx, result, err) {
+			break
+		}
+		wait := req.backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get(candihelper.HeaderRetryAfter)); ok && retryAfter > wait {
+				wait = retryAfter
+				if req.maxBackoff > 0 {
+					wait = min(wait, req.maxBackoff)
+				}
+			}
+		}
+		reason := fmt.Sprint(err)
+		if err == nil {
+			reason = resp.Status
+		}
+		if !deadline.IsZero() && time.Now().Add(wait).After(deadline) {
+			trace.Log("retry.deadline_exceeded", fmt.Sprintf("attempt %d: %s, next retry in %s exceed deadline", attempt+1, reason, wait))
+			break
+		}
+		if stream && resp != nil {
+			resp.Body.Close()
+		}
+		trace.Log("retry", fmt.Sprintf("attempt %d: %s, retry in %s", attempt+1, reason, wait))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err = ctx.Err()
+			return result, nil, err
+		case <-timer.C:
+		}
+	}
+
+	failure := isHTTPFailure(result, err)
+	if err == nil && resp != nil && req.minHTTPErrorCodeThreshold != 0 && resp.StatusCode >= req.minHTTPErrorCodeThreshold {
+		err = errors.New(resp.Status)
+	}
+	if failure && req.fallback != nil && !stream {
+		if err == nil {
+			err = errors.New(http.StatusText(result.RespCode))
+		}
+		result, err = req.fallback(ctx, err)
+	}
+	return result, resp, err
+}
+
+// doAttempt execute one attempt of http request through middlewares and circuit breaker (if exist),
+// response body is not read if stream is true
+func (req *httpRequestImpl) doAttempt(ctx context.Context, trace tracer.Tracer, breaker *CircuitBreaker, httpReq *http.Request,
+	timeout time.Duration, stream bool) (result *HTTPRequestResult, resp *http.Response, err error) {
+
+	if breaker != nil {
+		done, breakerErr := breaker.Allow(ctx)
+		if breakerErr != nil {
+			trace.Log("http.breaker_state", breakerErr.Error())
+			return nil, nil, breakerErr
+		}
+		defer func() { done(isHTTPFailure(result, err)) }()
+	}
+
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		var attemptCtx context.Context
+		attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		httpReq = httpReq.WithContext(attemptCtx)
+	}
+
+	resp, err = req.clientDo(httpReq)
 	if err != nil && resp == nil {
-		return nil, err
+		cancel()
+		return nil, nil, err
 	}
-	defer resp.Body.Close()
 
 	result = &HTTPRequestResult{
 		Buffer:   &bytes.Buffer{},
 		RespCode: resp.StatusCode,
 	}
-	io.Copy(result.Buffer, resp.Body)
-
 	dumpResponse, _ := httputil.DumpResponse(resp, false)
 	trace.SetTag("http.response", dumpResponse)
 	trace.SetTag("response.code", resp.StatusCode)
 	trace.SetTag("response.status", resp.Status)
+
+	if stream {
+		resp.Body = &cancelReadCloser{ReadCloser: resp.Body, cancel: cancel}
+		return result, resp, err
+	}
+
+	defer cancel()
+	defer resp.Body.Close()
+	io.Copy(result.Buffer, resp.Body)
 	trace.Log("response.body", result.Bytes())
+	return result, resp, err
+}
 
-	if req.minHTTPErrorCodeThreshold != 0 && resp.StatusCode >= req.minHTTPErrorCodeThreshold {
-		err = errors.New(resp.Status)
+func (req *httpRequestImpl) isRetryable(ctx context.Context, result *HTTPRequestResult, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrCircuitBreakerOpen) || errors.Is(err, ErrCircuitBreakerTooManyRequests) {
+		return false
+	}
+	if err != nil {
+		return req.retryOnNetworkError
+	}
+	_, ok := req.retryStatusCodes[result.RespCode]
+	return ok
+}
+
+// backoff exponential backoff with equal jitter, random duration in [backoff/2, backoff)
+func (req *httpRequestImpl) backoff(attempt int) time.Duration {
+	backoff := req.sleepBetweenRetry << attempt
+	if backoff <= 0 || (req.maxBackoff > 0 && backoff > req.maxBackoff) {
+		backoff = req.maxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	half := backoff / 2
+	return half + time.Duration(rand.Int64N(int64(backoff-half)+1))
+}
+
+// parseRetryAfter parse Retry-After header value in seconds or HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isHTTPFailure transport error or response code 5xx or 429, counted as failure by circuit breaker
+func isHTTPFailure(result *HTTPRequestResult, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
 	}
-	return
+	return result != nil && (result.RespCode >= http.StatusInternalServerError || result.RespCode == http.StatusTooManyRequests)
 }
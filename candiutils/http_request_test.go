@@ -0,0 +1,106 @@
+package candiutils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPRequestRetry(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	// no retry by default
+	_, respCode, err := NewHTTPRequest().Do(context.Background(), http.MethodGet, server.URL, nil, nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, respCode)
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	httpReq := NewHTTPRequest(HTTPRequestSetRetries(5), HTTPRequestSetSleepBetweenRetry(time.Millisecond))
+	respBody, respCode, err := httpReq.Do(context.Background(), http.MethodGet, server.URL, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, respCode)
+	assert.Equal(t, "ok", string(respBody))
+	assert.Equal(t, 3, calls)
+
+	// non idempotent method without idempotency key is not retried
+	calls = 0
+	_, respCode, err = httpReq.Do(context.Background(), http.MethodPost, server.URL, []byte("{}"), nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, respCode)
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	_, respCode, err = httpReq.Do(context.Background(), http.MethodPost, server.URL, []byte("{}"), map[string]string{"Idempotency-Key": "key"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, respCode)
+	assert.Equal(t, 3, calls)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	d, ok := parseRetryAfter("3")
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, d)
+
+	d, ok = parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.InDelta(t, float64(time.Minute), float64(d), float64(2*time.Second))
+
+	_, ok = parseRetryAfter("invalid")
+	assert.False(t, ok)
+}
+
+func TestHTTPRequestRetryAfterIsClampedToMaxBackoff(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	httpReq := NewHTTPRequest(HTTPRequestSetRetries(1), HTTPRequestSetSleepBetweenRetry(time.Millisecond),
+		HTTPRequestSetMaxBackoff(20*time.Millisecond))
+	start := time.Now()
+	_, respCode, err := httpReq.Do(context.Background(), http.MethodGet, server.URL, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, respCode)
+	assert.Equal(t, 2, calls)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestHTTPRequestTimeoutOption(t *testing.T) {
+	client := &http.Client{Timeout: time.Minute}
+	tests := []struct {
+		name string
+		opts []HTTPRequestOption
+		want time.Duration
+	}{
+		{name: "default", want: 10 * time.Second},
+		{name: "client timeout", opts: []HTTPRequestOption{HTTPRequestSetClient(client)}, want: time.Minute},
+		{name: "timeout before client", opts: []HTTPRequestOption{HTTPRequestSetTimeout(time.Second), HTTPRequestSetClient(client)}, want: time.Second},
+		{name: "timeout after client", opts: []HTTPRequestOption{HTTPRequestSetClient(client), HTTPRequestSetTimeout(time.Second)}, want: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewHTTPRequest(tt.opts...).(*httpRequestImpl).timeout)
+		})
+	}
+}
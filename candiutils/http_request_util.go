@@ -0,0 +1,166 @@
+package candiutils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/golangid/candi/candihelper"
+)
+
+type (
+	// HTTPClientDoFunc func type for execute http request
+	HTTPClientDoFunc func(req *http.Request) (*http.Response, error)
+
+	// HTTPClientMiddleware func type for wrap http request execution (ex: auth token injection, signing)
+	HTTPClientMiddleware func(next HTTPClientDoFunc) HTTPClientDoFunc
+
+	// HTTPResponseError error from typed http request (DoJSON) with response code and body
+	HTTPResponseError struct {
+		RespCode int
+		Message  string
+		Body     []byte
+	}
+
+	// MultipartForm builder for multipart/form-data request body
+	MultipartForm struct {
+		buff   bytes.Buffer
+		writer *multipart.Writer
+		err    error
+	}
+
+	httpRequestTimeoutKey struct{}
+
+	cancelReadCloser struct {
+		io.ReadCloser
+		cancel context.CancelFunc
+	}
+)
+
+// Error implement error
+func (e *HTTPResponseError) Error() string {
+	if len(e.Body) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Message, e.Body)
+}
+
+// WithHTTPRequestTimeout override timeout (for each attempt) of http request with the context
+func WithHTTPRequestTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, httpRequestTimeoutKey{}, timeout)
+}
+
+// DoJSON execute http request with JSON encoded request body (if not nil) and decode JSON response body to Resp
+func DoJSON[Resp any](ctx context.Context, httpReq HTTPRequest, method, url string, requestBody any, headers map[string]string) (resp Resp, err error) {
+	reqHeaders := map[string]string{
+		candihelper.HeaderAccept: candihelper.HeaderMIMEApplicationJSON,
+	}
+	var body []byte
+	if requestBody != nil {
+		if body, err = json.Marshal(requestBody); err != nil {
+			return resp, err
+		}
+		reqHeaders[candihelper.HeaderContentType] = candihelper.HeaderMIMEApplicationJSON
+	}
+	for key, value := range headers {
+		reqHeaders[key] = value
+	}
+
+	result, err := httpReq.DoRequest(ctx, method, url, body, reqHeaders)
+	if err != nil {
+		if result != nil {
+			return resp, &HTTPResponseError{RespCode: result.RespCode, Message: err.Error(), Body: result.Bytes()}
+		}
+		return resp, err
+	}
+	if result == nil || result.Len() == 0 {
+		return resp, nil
+	}
+	if err = json.Unmarshal(result.Bytes(), &resp); err != nil {
+		return resp, &HTTPResponseError{RespCode: result.RespCode, Message: "failed decode response: " + err.Error(), Body: result.Bytes()}
+	}
+	return resp, nil
+}
+
+// HTTPClientBearerToken middleware, set Authorization header with bearer token from tokenFunc (ex: cached oauth2 token)
+func HTTPClientBearerToken(tokenFunc func(ctx context.Context) (string, error)) HTTPClientMiddleware {
+	return func(next HTTPClientDoFunc) HTTPClientDoFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			token, err := tokenFunc(req.Context())
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set(candihelper.HeaderAuthorization, "Bearer "+token)
+			return next(req)
+		}
+	}
+}
+
+// NewMultipartForm create multipart/form-data builder, example:
+//
+//	body, contentType, err := candiutils.NewMultipartForm().AddField("name", "doc").AddFile("file", "doc.pdf", file).Build()
+//	httpReq.DoRequest(ctx, http.MethodPost, url, body, map[string]string{candihelper.HeaderContentType: contentType})
+func NewMultipartForm() *MultipartForm {
+	m := &MultipartForm{}
+	m.writer = multipart.NewWriter(&m.buff)
+	return m
+}
+
+// AddField add form field
+func (m *MultipartForm) AddField(name, value string) *MultipartForm {
+	if m.err == nil {
+		m.err = m.writer.WriteField(name, value)
+	}
+	return m
+}
+
+// AddFile add file from reader with content type application/octet-stream
+func (m *MultipartForm) AddFile(fieldName, fileName string, content io.Reader) *MultipartForm {
+	return m.AddFileWithContentType(fieldName, fileName, candihelper.HeaderMIMEOctetStream, content)
+}
+
+// AddFileWithContentType add file from reader with content type
+func (m *MultipartForm) AddFileWithContentType(fieldName, fileName, contentType string, content io.Reader) *MultipartForm {
+	if m.err != nil {
+		return m
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(fieldName), escapeQuotes(fileName)))
+	header.Set(candihelper.HeaderContentType, contentType)
+	part, err := m.writer.CreatePart(header)
+	if err != nil {
+		m.err = err
+		return m
+	}
+	_, m.err = io.Copy(part, content)
+	return m
+}
+
+// Build return request body and content type (with boundary) for request header
+func (m *MultipartForm) Build() (body []byte, contentType string, err error) {
+	if m.err != nil {
+		return nil, "", m.err
+	}
+	if err := m.writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return m.buff.Bytes(), m.writer.FormDataContentType(), nil
+}
+
+// Close cancel request context of streaming response after body closed
+func (c *cancelReadCloser) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+func escapeQuotes(s string) string {
+	return strings.NewReplacer("\\", "\\\\", `"`, "\\\"").Replace(s)
+}
@@ -4,9 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/codebase/interfaces"
+	"github.com/golangid/candi/logger"
 	"github.com/gomodule/redigo/redis"
+	"github.com/google/uuid"
 )
 
 // Lock implementation of interfaces.Locker, lock concurrent process either in one runtime or multiple runtimes
@@ -21,10 +27,12 @@ type (
 	// NoopLocker empty locker
 	NoopLocker struct{}
 
-	// Options for RedisLocker
+	// Options for RedisLocker, SQLLocker and MongoLocker
 	LockerOptions struct {
-		Prefix string
-		TTL    time.Duration
+		Prefix       string
+		TTL          time.Duration
+		RedlockPools []*redis.Pool
+		Table        string
 	}
 
 	// Option function type for setting options
@@ -45,6 +53,21 @@ func WithTTLLocker(ttl time.Duration) LockerOption {
 	}
 }
 
+// WithRedlockLocker enable Redlock mode with other independent redis nodes (pool in constructor is included),
+// lock is acquired if majority of nodes is acquired, fencing token of lock lease is not supported in this mode
+func WithRedlockLocker(pools ...*redis.Pool) LockerOption {
+	return func(o *LockerOptions) {
+		o.RedlockPools = pools
+	}
+}
+
+// WithTableLocker sets the table (SQLLocker) or collection (MongoLocker) name of lock record, default is candi_locks
+func WithTableLocker(table string) LockerOption {
+	return func(o *LockerOptions) {
+		o.Table = table
+	}
+}
+
 // NewRedisLocker constructor
 func NewRedisLocker(pool *redis.Pool, opts ...LockerOption) *RedisLocker {
 	lockeroptions := LockerOptions{
@@ -67,60 +90,54 @@ func (r *RedisLocker) GetTTLLocker() time.Duration {
 	return r.lockeroptions.TTL
 }
 
+// IsLocked method, return true if lock is held (include lock lease from AcquireLock), otherwise acquire the lock
 func (r *RedisLocker) IsLocked(key string) bool {
-	conn := r.pool.Get()
-	defer conn.Close()
+	return r.isLocked(key, 0)
+}
 
-	lockKey := fmt.Sprintf("%s:%s", r.lockeroptions.Prefix, key)
-	incr, err := redis.Int64(conn.Do("INCR", lockKey))
-	if err != nil {
-		return false
+// IsLockedTTL method, same as IsLocked with TTL of lock (default TTL if zero)
+func (r *RedisLocker) IsLockedTTL(key string, TTL time.Duration) bool {
+	if TTL <= 0 {
+		TTL = r.lockeroptions.TTL
 	}
-
-	return incr > 1
+	return r.isLocked(key, TTL)
 }
 
-func (r *RedisLocker) IsLockedTTL(key string, TTL time.Duration) bool {
+func (r *RedisLocker) isLocked(key string, ttl time.Duration) bool {
 	conn := r.pool.Get()
 	defer conn.Close()
 
 	lockKey := fmt.Sprintf("%s:%s", r.lockeroptions.Prefix, key)
-	incr, err := redis.Int64(conn.Do("INCR", lockKey))
+	incr, err := redis.Int64(legacyLockScript.Do(conn, lockKey, int(ttl.Seconds())))
 	if err != nil {
 		return false
 	}
 
-	var expireTime time.Duration
-	if TTL > 0 {
-		expireTime = TTL
-	} else {
-		expireTime = r.lockeroptions.TTL
-	}
-
-	if expireTime > 0 {
-		conn.Do("EXPIRE", lockKey, int(expireTime.Seconds()))
-	}
-
 	return incr > 1
 }
 
+// HasBeenLocked method
 func (r *RedisLocker) HasBeenLocked(key string) bool {
 	conn := r.pool.Get()
 	defer conn.Close()
 
 	lockKey := fmt.Sprintf("%s:%s", r.lockeroptions.Prefix, key)
-	incr, _ := redis.Int64(conn.Do("GET", lockKey))
+	value, _ := redis.String(conn.Do("GET", lockKey))
+	if incr, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return incr > 0
+	}
 
-	return incr > 0
+	// lock lease from AcquireLock, value is owner token
+	return value != ""
 }
 
-// Unlock method
+// Unlock method, release lock from IsLocked/IsLockedTTL, lock lease from AcquireLock must be released by the owner
 func (r *RedisLocker) Unlock(key string) {
 	conn := r.pool.Get()
 	defer conn.Close()
 
 	lockKey := fmt.Sprintf("%s:%s", r.lockeroptions.Prefix, key)
-	conn.Do("DEL", lockKey)
+	legacyUnlockScript.Do(conn, lockKey)
 }
 
 // Reset method
@@ -157,7 +174,7 @@ func (r *RedisLocker) Disconnect(ctx context.Context) error {
 	return nil
 }
 
-// Lock method
+// Lock method, wait until lock released by another process or timeout, lock lease is renewed automatically until unlockFunc called
 func (r *RedisLocker) Lock(key string, timeout time.Duration) (unlockFunc func(), err error) {
 	if timeout <= 0 {
 		return func() {}, errors.New("timeout must be positive")
@@ -166,45 +183,119 @@ func (r *RedisLocker) Lock(key string, timeout time.Duration) (unlockFunc func()
 		return func() {}, errors.New("key cannot empty")
 	}
 
-	lockKey := fmt.Sprintf("%s:%s", r.lockeroptions.Prefix, key)
-	unlockFunc = func() { r.Unlock(key) }
-	if !r.IsLocked(key) {
-		return unlockFunc, nil
+	lease, err := r.AcquireLock(context.Background(), key, candishared.LockSetWaitTimeout(timeout), candishared.LockSetAutoRenew(true))
+	if err != nil {
+		if errors.Is(err, candishared.ErrLockNotAcquired) {
+			return func() {}, errors.New("timeout when waiting unlock another process")
+		}
+		return func() {}, err
 	}
+	return func() { lease.Release(context.Background()) }, nil
+}
 
-	conn := r.pool.Get()
-	conn.Do("CONFIG", "SET", "notify-keyspace-events", "KEA")
-
-	eventChannel := "__key*__:del"
-	psc := &redis.PubSubConn{Conn: conn}
-	psc.PSubscribe(eventChannel)
-	defer func() { psc.Unsubscribe(); conn.Close() }()
-
-	wait := make(chan error)
-	go func() {
-		for {
-			switch msg := psc.Receive().(type) {
-			case redis.Message:
-				if msg.Pattern == eventChannel && lockKey == string(msg.Data) && !r.IsLocked(key) {
-					wait <- nil
-					return
-				}
+// AcquireLock method, lock with owner token (SET NX) and fencing token, in Redlock mode (see WithRedlockLocker)
+// lock is acquired if majority of redis nodes is acquired within lock TTL and fencing token is not supported (always zero)
+func (r *RedisLocker) AcquireLock(ctx context.Context, key string, opts ...candishared.LockOption) (interfaces.LockLease, error) {
+	if key == "" {
+		return nil, errors.New("key cannot empty")
+	}
+	opt := candishared.ParseLockOptions(r.lockeroptions.TTL, opts...)
+	lockKey := fmt.Sprintf("%s:%s", r.lockeroptions.Prefix, key)
+	fencingKey := fmt.Sprintf("%s-fencing:%s", r.lockeroptions.Prefix, key)
+	pools := append([]*redis.Pool{r.pool}, r.lockeroptions.RedlockPools...)
+	quorum := len(pools)/2 + 1
+	ttlMillis := opt.TTL.Milliseconds()
+
+	return acquireLockWithRetry(ctx, opt, func(ctx context.Context) (interfaces.LockLease, error) {
+		token := opt.Token
+		if token == "" {
+			token = uuid.NewString()
+		}
+		start := time.Now()
+
+		var acquired int
+		var fencingToken int64
+		for _, pool := range pools {
+			fencing, err := redisDoScript(ctx, pool, lockAcquireScript, lockKey, fencingKey, token, ttlMillis)
+			if err != nil || fencing == 0 {
+				continue
+			}
+			acquired++
+			fencingToken = fencing
+		}
+		// counter of each node is independent, max of counters in quorum is not monotonic across lock owners
+		if len(pools) > 1 {
+			fencingToken = 0
+		}
 
-			case error:
-				wait <- msg
-				return
+		// clock drift factor of Redlock algorithm
+		validity := opt.TTL - time.Since(start) - (opt.TTL/100 + 2*time.Millisecond)
+		if acquired < quorum || validity <= 0 {
+			for _, pool := range pools {
+				redisDoScript(ctx, pool, lockReleaseScript, lockKey, token)
 			}
+			return nil, nil
 		}
-	}()
 
-	select {
-	case err := <-wait:
-		return unlockFunc, err
+		quorumDo := func(ctx context.Context, script *redis.Script, args ...any) (bool, error) {
+			var success int
+			var lastErr error
+			for _, pool := range pools {
+				res, err := redisDoScript(ctx, pool, script, append([]any{lockKey, token}, args...)...)
+				if err != nil {
+					lastErr = err
+					continue
+				}
+				if res == 1 {
+					success++
+				}
+			}
+			if success == 0 && lastErr != nil {
+				return false, lastErr
+			}
+			return success >= quorum, nil
+		}
+		return newLockLease(key, token, fencingToken, opt,
+			func(ctx context.Context) (bool, error) { return quorumDo(ctx, lockRefreshScript, ttlMillis) },
+			func(ctx context.Context) (bool, error) { return quorumDo(ctx, lockReleaseScript) },
+		), nil
+	})
+}
 
-	case <-time.After(timeout):
-		r.Unlock(key)
-		return unlockFunc, errors.New("timeout when waiting unlock another process")
+// GetLockOwner method, return owner token of lock lease from AcquireLock, in Redlock mode the token must be held by majority of redis nodes
+func (r *RedisLocker) GetLockOwner(ctx context.Context, key string) (string, error) {
+	lockKey := fmt.Sprintf("%s:%s", r.lockeroptions.Prefix, key)
+	pools := append([]*redis.Pool{r.pool}, r.lockeroptions.RedlockPools...)
+
+	owners := make(map[string]int, len(pools))
+	var lastErr error
+	for _, pool := range pools {
+		conn, err := pool.GetContext(ctx)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		value, err := redis.String(conn.Do("GET", lockKey))
+		conn.Close()
+		if err != nil && !errors.Is(err, redis.ErrNil) {
+			lastErr = err
+			continue
+		}
+		// skip empty and counter from IsLocked
+		if _, err := strconv.ParseInt(value, 10, 64); value != "" && err != nil {
+			owners[value]++
+		}
 	}
+
+	for owner, count := range owners {
+		if count >= len(pools)/2+1 {
+			return owner, nil
+		}
+	}
+	if len(owners) == 0 && lastErr != nil {
+		return "", lastErr
+	}
+	return "", nil
 }
 
 // NoopLocker
@@ -234,3 +325,166 @@ func (NoopLocker) GetPrefixLocker() string { return "" }
 
 // GetTTLLocker method
 func (NoopLocker) GetTTLLocker() time.Duration { return 0 }
+
+// GetLockOwner method
+func (NoopLocker) GetLockOwner(context.Context, string) (string, error) { return "", nil }
+
+// AcquireLock method
+func (NoopLocker) AcquireLock(ctx context.Context, key string, opts ...candishared.LockOption) (interfaces.LockLease, error) {
+	noop := func(context.Context) (bool, error) { return true, nil }
+	return newLockLease(key, "", 0, candishared.LockOptions{}, noop, noop), nil
+}
+
+var (
+	// KEYS[1] lock key, ARGV[1] ttl in second (no expire if zero). Return 2 if key is held by lock lease (value is owner token),
+	// otherwise increment the counter
+	legacyLockScript = redis.NewScript(1, `
+local value = redis.call('GET', KEYS[1])
+if value and not tonumber(value) then
+	return 2
+end
+local incr = redis.call('INCR', KEYS[1])
+if tonumber(ARGV[1]) > 0 then
+	redis.call('EXPIRE', KEYS[1], ARGV[1])
+end
+return incr`)
+	// KEYS[1] lock key, delete counter from legacyLockScript only
+	legacyUnlockScript = redis.NewScript(1, `
+local value = redis.call('GET', KEYS[1])
+if value and not tonumber(value) then
+	return 0
+end
+return redis.call('DEL', KEYS[1])`)
+	// KEYS[1] lock key, KEYS[2] fencing key, ARGV[1] owner token, ARGV[2] ttl in millisecond. Return fencing token, 0 if not acquired
+	lockAcquireScript = redis.NewScript(2, `
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return redis.call('INCR', KEYS[2])
+end
+return 0`)
+	// KEYS[1] lock key, ARGV[1] owner token, ARGV[2] ttl in millisecond
+	lockRefreshScript = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0`)
+	// KEYS[1] lock key, ARGV[1] owner token
+	lockReleaseScript = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`)
+)
+
+func redisDoScript(ctx context.Context, pool *redis.Pool, script *redis.Script, keysAndArgs ...any) (int64, error) {
+	conn, err := pool.GetContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	return redis.Int64(script.Do(conn, keysAndArgs...))
+}
+
+// acquireLockWithRetry try acquire lock until acquired or wait timeout, tryAcquire return nil lease if lock is held by another owner
+func acquireLockWithRetry(ctx context.Context, opt candishared.LockOptions, tryAcquire func(context.Context) (interfaces.LockLease, error)) (interfaces.LockLease, error) {
+	deadline := time.Now().Add(opt.WaitTimeout)
+	for {
+		lease, err := tryAcquire(ctx)
+		if err != nil || lease != nil {
+			return lease, err
+		}
+		if !time.Now().Before(deadline) {
+			return nil, candishared.ErrLockNotAcquired
+		}
+
+		timer := time.NewTimer(min(opt.RetryInterval, time.Until(deadline)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// lockLease implementation of interfaces.LockLease for any lock backend
+type lockLease struct {
+	key, token   string
+	fencingToken int64
+	refreshFunc  func(context.Context) (bool, error)
+	releaseFunc  func(context.Context) (bool, error)
+	lost         chan struct{}
+	lostOnce     sync.Once
+}
+
+func newLockLease(key, token string, fencingToken int64, opt candishared.LockOptions,
+	refreshFunc, releaseFunc func(context.Context) (bool, error)) *lockLease {
+
+	l := &lockLease{
+		key: key, token: token, fencingToken: fencingToken,
+		refreshFunc: refreshFunc, releaseFunc: releaseFunc,
+		lost: make(chan struct{}),
+	}
+	if opt.AutoRenew && opt.TTL > 0 {
+		go l.renew(opt.TTL)
+	}
+	return l
+}
+
+func (l *lockLease) Key() string           { return l.key }
+func (l *lockLease) Token() string         { return l.token }
+func (l *lockLease) FencingToken() int64   { return l.fencingToken }
+func (l *lockLease) Lost() <-chan struct{} { return l.lost }
+
+func (l *lockLease) Refresh(ctx context.Context) error {
+	ok, err := l.refreshFunc(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		l.markLost()
+		return candishared.ErrLockNotOwned
+	}
+	return nil
+}
+
+func (l *lockLease) Release(ctx context.Context) error {
+	l.markLost()
+	ok, err := l.releaseFunc(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return candishared.ErrLockNotOwned
+	}
+	return nil
+}
+
+func (l *lockLease) markLost() {
+	l.lostOnce.Do(func() { close(l.lost) })
+}
+
+// renew refresh lease every TTL/3, lock is lost if not owned anymore or cannot be refreshed until lease expired
+func (l *lockLease) renew(ttl time.Duration) {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	lastRenew := time.Now()
+	for {
+		select {
+		case <-l.lost:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), ttl/3)
+			err := l.Refresh(ctx)
+			cancel()
+			switch {
+			case err == nil:
+				lastRenew = time.Now()
+			case errors.Is(err, candishared.ErrLockNotOwned) || time.Since(lastRenew) >= ttl:
+				logger.LogYellow(fmt.Sprintf("[LOCKER] lock %s is lost: %v", l.key, err))
+				l.markLost()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package candiutils
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/codebase/interfaces"
+	"github.com/gomodule/redigo/redis"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// conformance test for all locker implementation, SQLite is tested in temporary file (require cgo),
+// other real backend is tested if env is set: TEST_LOCKER_REDIS_HOST (ex: localhost:6379), TEST_LOCKER_POSTGRES_DSN,
+// TEST_LOCKER_MYSQL_DSN (ex: root:root@tcp(localhost:3306)/test) and TEST_LOCKER_MONGO_URI (ex: mongodb://localhost:27017/test)
+
+func TestLockerConformance(t *testing.T) {
+	prefix := "candi-test-" + uuid.NewString()
+
+	t.Run("memory store", func(t *testing.T) {
+		locker := &SQLLocker{storeLocker: newStoreLocker(&memoryLockStore{locks: map[string]*memoryLock{}}, WithPrefixLocker(prefix))}
+		testLockerConformance(t, locker)
+	})
+
+	t.Run("redis", func(t *testing.T) {
+		host := os.Getenv("TEST_LOCKER_REDIS_HOST")
+		if host == "" {
+			t.Skip("TEST_LOCKER_REDIS_HOST is not set")
+		}
+		pool := &redis.Pool{Dial: func() (redis.Conn, error) { return redis.Dial("tcp", host) }}
+		defer pool.Close()
+		testLockerConformance(t, NewRedisLocker(pool, WithPrefixLocker(prefix)))
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv("TEST_LOCKER_POSTGRES_DSN")
+		if dsn == "" {
+			t.Skip("TEST_LOCKER_POSTGRES_DSN is not set")
+		}
+		db, err := sql.Open("postgres", dsn)
+		require.NoError(t, err)
+		defer db.Close()
+		locker, err := NewSQLLocker(db, SQLLockerPostgres, WithPrefixLocker(prefix))
+		require.NoError(t, err)
+		testLockerConformance(t, locker)
+	})
+
+	t.Run("mysql", func(t *testing.T) {
+		dsn := os.Getenv("TEST_LOCKER_MYSQL_DSN")
+		if dsn == "" {
+			t.Skip("TEST_LOCKER_MYSQL_DSN is not set")
+		}
+		db, err := sql.Open("mysql", dsn)
+		require.NoError(t, err)
+		defer db.Close()
+		locker, err := NewSQLLocker(db, SQLLockerMySQL, WithPrefixLocker(prefix))
+		require.NoError(t, err)
+		testLockerConformance(t, locker)
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "locker.db")+"?_busy_timeout=5000")
+		require.NoError(t, err)
+		defer db.Close()
+		if err := db.Ping(); err != nil {
+			t.Skipf("sqlite is not available: %v", err)
+		}
+		db.SetMaxOpenConns(1)
+		locker, err := NewSQLLocker(db, SQLLockerSQLite, WithPrefixLocker(prefix))
+		require.NoError(t, err)
+		testLockerConformance(t, locker)
+	})
+
+	t.Run("mongo", func(t *testing.T) {
+		uri := os.Getenv("TEST_LOCKER_MONGO_URI")
+		if uri == "" {
+			t.Skip("TEST_LOCKER_MONGO_URI is not set")
+		}
+		client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(uri))
+		require.NoError(t, err)
+		defer client.Disconnect(context.Background())
+		locker, err := NewMongoLocker(client.Database("candi_test"), WithPrefixLocker(prefix))
+		require.NoError(t, err)
+		testLockerConformance(t, locker)
+	})
+}
+
+func testLockerConformance(t *testing.T, locker interfaces.Locker) {
+	ctx := context.Background()
+
+	t.Run("is locked", func(t *testing.T) {
+		assert.False(t, locker.IsLocked("is-locked"))
+		assert.True(t, locker.IsLocked("is-locked"))
+		assert.True(t, locker.HasBeenLocked("is-locked"))
+		locker.Unlock("is-locked")
+		assert.False(t, locker.HasBeenLocked("is-locked"))
+		assert.False(t, locker.IsLocked("is-locked"))
+		locker.Unlock("is-locked")
+	})
+
+	t.Run("is locked with lock lease", func(t *testing.T) {
+		lease, err := locker.AcquireLock(ctx, "lease", candishared.LockSetTTL(time.Second))
+		require.NoError(t, err)
+
+		assert.True(t, locker.IsLocked("lease"))
+		assert.True(t, locker.IsLockedTTL("lease", time.Second))
+		assert.True(t, locker.HasBeenLocked("lease"))
+		locker.Unlock("lease")
+		assert.True(t, locker.HasBeenLocked("lease"))
+		assert.NoError(t, lease.Refresh(ctx))
+		assert.NoError(t, lease.Release(ctx))
+
+		assert.False(t, locker.IsLocked("lease"))
+		_, err = locker.AcquireLock(ctx, "lease", candishared.LockSetTTL(time.Second))
+		assert.ErrorIs(t, err, candishared.ErrLockNotAcquired)
+		locker.Unlock("lease")
+	})
+
+	t.Run("acquire and release", func(t *testing.T) {
+		lease, err := locker.AcquireLock(ctx, "acquire", candishared.LockSetTTL(time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, "acquire", lease.Key())
+
+		_, err = locker.AcquireLock(ctx, "acquire", candishared.LockSetTTL(time.Second))
+		assert.ErrorIs(t, err, candishared.ErrLockNotAcquired)
+
+		assert.NoError(t, lease.Refresh(ctx))
+		assert.NoError(t, lease.Release(ctx))
+		assert.ErrorIs(t, lease.Release(ctx), candishared.ErrLockNotOwned)
+
+		next, err := locker.AcquireLock(ctx, "acquire", candishared.LockSetTTL(time.Second))
+		require.NoError(t, err)
+		assert.Greater(t, next.FencingToken(), lease.FencingToken())
+		assert.NotEqual(t, lease.Token(), next.Token())
+		assert.NoError(t, next.Release(ctx))
+	})
+
+	t.Run("lock owner", func(t *testing.T) {
+		ownerGetter, ok := locker.(interfaces.LockOwnerGetter)
+		require.True(t, ok)
+
+		owner, err := ownerGetter.GetLockOwner(ctx, "owner")
+		assert.NoError(t, err)
+		assert.Empty(t, owner)
+
+		lease, err := locker.AcquireLock(ctx, "owner", candishared.LockSetTTL(time.Second), candishared.LockSetToken("instance-1/token"))
+		require.NoError(t, err)
+		assert.Equal(t, "instance-1/token", lease.Token())
+		owner, err = ownerGetter.GetLockOwner(ctx, "owner")
+		assert.NoError(t, err)
+		assert.Equal(t, "instance-1/token", owner)
+		assert.NoError(t, lease.Release(ctx))
+
+		owner, err = ownerGetter.GetLockOwner(ctx, "owner")
+		assert.NoError(t, err)
+		assert.Empty(t, owner)
+
+		assert.False(t, locker.IsLocked("owner"))
+		owner, err = ownerGetter.GetLockOwner(ctx, "owner")
+		assert.NoError(t, err)
+		assert.Empty(t, owner)
+		locker.Unlock("owner")
+	})
+
+	t.Run("expired lease", func(t *testing.T) {
+		lease, err := locker.AcquireLock(ctx, "expired", candishared.LockSetTTL(100*time.Millisecond))
+		require.NoError(t, err)
+		time.Sleep(200 * time.Millisecond)
+
+		next, err := locker.AcquireLock(ctx, "expired", candishared.LockSetTTL(time.Second))
+		require.NoError(t, err)
+		assert.ErrorIs(t, lease.Refresh(ctx), candishared.ErrLockNotOwned)
+		assert.ErrorIs(t, lease.Release(ctx), candishared.ErrLockNotOwned)
+		assert.NoError(t, next.Release(ctx))
+	})
+
+	t.Run("lock wait", func(t *testing.T) {
+		unlock, err := locker.Lock("wait", time.Second)
+		require.NoError(t, err)
+
+		_, err = locker.Lock("wait", 100*time.Millisecond)
+		assert.Error(t, err)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			time.Sleep(50 * time.Millisecond)
+			unlock()
+		}()
+		unlock, err = locker.Lock("wait", time.Second)
+		assert.NoError(t, err)
+		unlock()
+		wg.Wait()
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		assert.False(t, locker.IsLocked("reset-1"))
+		assert.False(t, locker.IsLocked("reset-2"))
+		locker.Reset("reset-*")
+		assert.False(t, locker.HasBeenLocked("reset-1"))
+		assert.False(t, locker.HasBeenLocked("reset-2"))
+	})
+}
+
+type (
+	memoryLockStore struct {
+		mu    sync.Mutex
+		locks map[string]*memoryLock
+	}
+	memoryLock struct {
+		owner        string
+		fencingToken int64
+		expiredAt    time.Time
+	}
+)
+
+func (m *memoryLockStore) acquire(ctx context.Context, lockKey, owner string, now, expiredAt time.Time) (int64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock, ok := m.locks[lockKey]
+	if !ok {
+		lock = &memoryLock{}
+		m.locks[lockKey] = lock
+	}
+	if lock.expiredAt.After(now) {
+		return 0, false, nil
+	}
+	lock.owner, lock.expiredAt = owner, expiredAt
+	lock.fencingToken++
+	return lock.fencingToken, true, nil
+}
+
+func (m *memoryLockStore) refresh(ctx context.Context, lockKey, owner string, now, expiredAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock, ok := m.locks[lockKey]
+	if !ok || lock.owner != owner || !lock.expiredAt.After(now) {
+		return false, nil
+	}
+	lock.expiredAt = expiredAt
+	return true, nil
+}
+
+func (m *memoryLockStore) release(ctx context.Context, lockKey, owner string, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock, ok := m.locks[lockKey]
+	if !ok || lock.owner != owner || !lock.expiredAt.After(now) {
+		return false, nil
+	}
+	lock.owner, lock.expiredAt = "", now
+	return true, nil
+}
+
+func (m *memoryLockStore) releaseLegacy(ctx context.Context, lockKey string, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock, ok := m.locks[lockKey]
+	if !ok || !strings.HasPrefix(lock.owner, legacyLockOwnerPrefix) || !lock.expiredAt.After(now) {
+		return false, nil
+	}
+	lock.owner, lock.expiredAt = "", now
+	return true, nil
+}
+
+func (m *memoryLockStore) releasePattern(ctx context.Context, pattern string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for lockKey, lock := range m.locks {
+		if ok, _ := path.Match(pattern, lockKey); ok && lock.expiredAt.After(now) {
+			lock.owner, lock.expiredAt = "", now
+		}
+	}
+	return nil
+}
+
+func (m *memoryLockStore) owner(ctx context.Context, lockKey string, now time.Time) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock, ok := m.locks[lockKey]
+	if !ok || !lock.expiredAt.After(now) {
+		return "", nil
+	}
+	return lock.owner, nil
+}
+
+func (m *memoryLockStore) isLocked(ctx context.Context, lockKey string, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock, ok := m.locks[lockKey]
+	return ok && lock.expiredAt.After(now), nil
+}
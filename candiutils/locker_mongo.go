@@ -0,0 +1,125 @@
+package candiutils
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type (
+	// MongoLocker lock using mongo collection, expired lock record is removed by TTL index on expired_at
+	// (after 24 hours grace period for keep fencing token increasing in active lock key)
+	MongoLocker struct {
+		storeLocker
+	}
+
+	mongoLockStore struct {
+		coll *mongo.Collection
+	}
+)
+
+const mongoLockerExpireGrace = 24 * time.Hour
+
+// NewMongoLocker constructor, create TTL index of lock collection (see WithTableLocker)
+func NewMongoLocker(db *mongo.Database, opts ...LockerOption) (*MongoLocker, error) {
+	locker := &MongoLocker{}
+	store := &mongoLockStore{}
+	locker.storeLocker = newStoreLocker(store, opts...)
+	store.coll = db.Collection(locker.lockeroptions.Table)
+
+	_, err := store.coll.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "expired_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(mongoLockerExpireGrace.Seconds())),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return locker, nil
+}
+
+func (m *mongoLockStore) acquire(ctx context.Context, lockKey, owner string, now, expiredAt time.Time) (fencingToken int64, ok bool, err error) {
+	var lock struct {
+		FencingToken int64 `bson:"fencing_token"`
+	}
+	err = m.coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": lockKey, "expired_at": bson.M{"$lte": now}},
+		bson.M{
+			"$set": bson.M{"owner": owner, "expired_at": expiredAt},
+			"$inc": bson.M{"fencing_token": 1},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&lock)
+	if err != nil {
+		// lock is held by another owner, upsert is rejected with same _id
+		if mongo.IsDuplicateKeyError(err) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return lock.FencingToken, true, nil
+}
+
+func (m *mongoLockStore) refresh(ctx context.Context, lockKey, owner string, now, expiredAt time.Time) (bool, error) {
+	res, err := m.coll.UpdateOne(ctx,
+		bson.M{"_id": lockKey, "owner": owner, "expired_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"expired_at": expiredAt}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (m *mongoLockStore) release(ctx context.Context, lockKey, owner string, now time.Time) (bool, error) {
+	res, err := m.coll.UpdateOne(ctx,
+		bson.M{"_id": lockKey, "owner": owner, "expired_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"owner": "", "expired_at": now}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (m *mongoLockStore) releaseLegacy(ctx context.Context, lockKey string, now time.Time) (bool, error) {
+	res, err := m.coll.UpdateOne(ctx,
+		bson.M{"_id": lockKey, "owner": bson.M{"$regex": "^" + regexp.QuoteMeta(legacyLockOwnerPrefix)}, "expired_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"owner": "", "expired_at": now}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (m *mongoLockStore) releasePattern(ctx context.Context, pattern string, now time.Time) error {
+	regex := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+	_, err := m.coll.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$regex": regex}, "expired_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"owner": "", "expired_at": now}},
+	)
+	return err
+}
+
+func (m *mongoLockStore) owner(ctx context.Context, lockKey string, now time.Time) (string, error) {
+	var lock struct {
+		Owner string `bson:"owner"`
+	}
+	err := m.coll.FindOne(ctx, bson.M{"_id": lockKey, "expired_at": bson.M{"$gt": now}}).Decode(&lock)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", nil
+	}
+	return lock.Owner, err
+}
+
+func (m *mongoLockStore) isLocked(ctx context.Context, lockKey string, now time.Time) (bool, error) {
+	err := m.coll.FindOne(ctx, bson.M{"_id": lockKey, "expired_at": bson.M{"$gt": now}}).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,166 @@
+package candiutils
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SQLLockerPostgres dialect
+	SQLLockerPostgres SQLLockerDialect = "postgres"
+	// SQLLockerMySQL dialect
+	SQLLockerMySQL SQLLockerDialect = "mysql"
+	// SQLLockerSQLite dialect (SQLite 3.35 or later)
+	SQLLockerSQLite SQLLockerDialect = "sqlite"
+)
+
+type (
+	// SQLLockerDialect type
+	SQLLockerDialect string
+
+	// SQLLocker lock using lock table in SQL database (Postgres, MySQL or SQLite),
+	// lock table is used instead of advisory lock (pg_advisory_lock / GET_LOCK) because advisory lock is bound to
+	// one database session and cannot be used with TTL and fencing token in connection pool
+	SQLLocker struct {
+		storeLocker
+	}
+
+	sqlLockStore struct {
+		db      *sql.DB
+		dialect SQLLockerDialect
+		queries struct {
+			acquire, selectFencing, refresh, release, releaseLegacy, releasePattern, owner, isLocked string
+		}
+	}
+)
+
+// NewSQLLocker constructor, create lock table if not exist (see WithTableLocker)
+func NewSQLLocker(db *sql.DB, dialect SQLLockerDialect, opts ...LockerOption) (*SQLLocker, error) {
+	locker := &SQLLocker{}
+	store := &sqlLockStore{db: db, dialect: dialect}
+	locker.storeLocker = newStoreLocker(store, opts...)
+	if err := store.init(locker.lockeroptions.Table); err != nil {
+		return nil, err
+	}
+	return locker, nil
+}
+
+func (s *sqlLockStore) init(table string) error {
+	q := &s.queries
+	switch s.dialect {
+	case SQLLockerPostgres, SQLLockerSQLite:
+		q.acquire = `INSERT INTO ` + table + ` (lock_key, owner, fencing_token, expired_at) VALUES (?, ?, 1, ?)
+			ON CONFLICT (lock_key) DO UPDATE SET owner = EXCLUDED.owner, fencing_token = ` + table + `.fencing_token + 1, expired_at = EXCLUDED.expired_at
+			WHERE ` + table + `.expired_at <= ?
+			RETURNING fencing_token`
+	case SQLLockerMySQL:
+		// assignments is evaluated from left to right, expired_at must be updated last
+		q.acquire = `INSERT INTO ` + table + ` (lock_key, owner, fencing_token, expired_at) VALUES (?, ?, 1, ?)
+			ON DUPLICATE KEY UPDATE owner = IF(expired_at <= ?, VALUES(owner), owner),
+			fencing_token = IF(expired_at <= ?, fencing_token + 1, fencing_token),
+			expired_at = IF(expired_at <= ?, VALUES(expired_at), expired_at)`
+		q.selectFencing = `SELECT fencing_token FROM ` + table + ` WHERE lock_key = ? AND owner = ?`
+	default:
+		return fmt.Errorf("sql locker: unsupported dialect %q", s.dialect)
+	}
+	q.refresh = `UPDATE ` + table + ` SET expired_at = ? WHERE lock_key = ? AND owner = ? AND expired_at > ?`
+	q.release = `UPDATE ` + table + ` SET owner = '', expired_at = ? WHERE lock_key = ? AND owner = ? AND expired_at > ?`
+	q.releaseLegacy = `UPDATE ` + table + ` SET owner = '', expired_at = ? WHERE lock_key = ? AND owner LIKE '` + legacyLockOwnerPrefix + `%' AND expired_at > ?`
+	q.releasePattern = `UPDATE ` + table + ` SET owner = '', expired_at = ? WHERE lock_key LIKE ? ESCAPE '!' AND expired_at > ?`
+	q.owner = `SELECT owner FROM ` + table + ` WHERE lock_key = ? AND expired_at > ?`
+	q.isLocked = `SELECT COUNT(*) FROM ` + table + ` WHERE lock_key = ? AND expired_at > ?`
+	for _, query := range []*string{&q.acquire, &q.selectFencing, &q.refresh, &q.release, &q.releaseLegacy, &q.releasePattern, &q.owner, &q.isLocked} {
+		*query = s.rebind(*query)
+	}
+
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS ` + table + ` (
+		lock_key VARCHAR(255) NOT NULL PRIMARY KEY,
+		owner VARCHAR(255) NOT NULL DEFAULT '',
+		fencing_token BIGINT NOT NULL DEFAULT 0,
+		expired_at BIGINT NOT NULL DEFAULT 0
+	)`)
+	return err
+}
+
+// rebind replace ? placeholder to $n for postgres
+func (s *sqlLockStore) rebind(query string) string {
+	if s.dialect != SQLLockerPostgres || query == "" {
+		return query
+	}
+	var b strings.Builder
+	var n int
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func (s *sqlLockStore) acquire(ctx context.Context, lockKey, owner string, now, expiredAt time.Time) (fencingToken int64, ok bool, err error) {
+	if s.dialect != SQLLockerMySQL {
+		err = s.db.QueryRowContext(ctx, s.queries.acquire, lockKey, owner, expiredAt.UnixMilli(), now.UnixMilli()).Scan(&fencingToken)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return fencingToken, err == nil, err
+	}
+
+	if _, err = s.db.ExecContext(ctx, s.queries.acquire, lockKey, owner, expiredAt.UnixMilli(),
+		now.UnixMilli(), now.UnixMilli(), now.UnixMilli()); err != nil {
+		return 0, false, err
+	}
+	err = s.db.QueryRowContext(ctx, s.queries.selectFencing, lockKey, owner).Scan(&fencingToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return fencingToken, err == nil, err
+}
+
+func (s *sqlLockStore) refresh(ctx context.Context, lockKey, owner string, now, expiredAt time.Time) (bool, error) {
+	return s.execAffected(ctx, s.queries.refresh, expiredAt.UnixMilli(), lockKey, owner, now.UnixMilli())
+}
+
+func (s *sqlLockStore) release(ctx context.Context, lockKey, owner string, now time.Time) (bool, error) {
+	return s.execAffected(ctx, s.queries.release, now.UnixMilli(), lockKey, owner, now.UnixMilli())
+}
+
+func (s *sqlLockStore) releaseLegacy(ctx context.Context, lockKey string, now time.Time) (bool, error) {
+	return s.execAffected(ctx, s.queries.releaseLegacy, now.UnixMilli(), lockKey, now.UnixMilli())
+}
+
+func (s *sqlLockStore) releasePattern(ctx context.Context, pattern string, now time.Time) error {
+	_, err := s.db.ExecContext(ctx, s.queries.releasePattern, now.UnixMilli(), likePattern(pattern), now.UnixMilli())
+	return err
+}
+
+func (s *sqlLockStore) owner(ctx context.Context, lockKey string, now time.Time) (owner string, err error) {
+	err = s.db.QueryRowContext(ctx, s.queries.owner, lockKey, now.UnixMilli()).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return owner, err
+}
+
+func (s *sqlLockStore) isLocked(ctx context.Context, lockKey string, now time.Time) (bool, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, s.queries.isLocked, lockKey, now.UnixMilli()).Scan(&count)
+	return count > 0, err
+}
+
+func (s *sqlLockStore) execAffected(ctx context.Context, query string, args ...any) (bool, error) {
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
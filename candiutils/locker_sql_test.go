@@ -0,0 +1,65 @@
+package candiutils
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLLockStoreRebind(t *testing.T) {
+	tests := []struct {
+		dialect     SQLLockerDialect
+		query, want string
+	}{
+		{dialect: SQLLockerPostgres, query: `UPDATE t SET a = ? WHERE b = ? AND c = ?`, want: `UPDATE t SET a = $1 WHERE b = $2 AND c = $3`},
+		{dialect: SQLLockerPostgres, query: `SELECT 1`, want: `SELECT 1`},
+		{dialect: SQLLockerPostgres, query: ``, want: ``},
+		{dialect: SQLLockerMySQL, query: `UPDATE t SET a = ? WHERE b = ?`, want: `UPDATE t SET a = ? WHERE b = ?`},
+		{dialect: SQLLockerSQLite, query: `UPDATE t SET a = ? WHERE b = ?`, want: `UPDATE t SET a = ? WHERE b = ?`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.dialect)+" "+tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, (&sqlLockStore{dialect: tt.dialect}).rebind(tt.query))
+		})
+	}
+}
+
+func TestSQLLockStoreInit(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "locker.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		t.Skipf("sqlite is not available: %v", err)
+	}
+
+	t.Run("postgres", func(t *testing.T) {
+		store := &sqlLockStore{db: db, dialect: SQLLockerPostgres}
+		require.NoError(t, store.init("candi_locks"))
+		q := store.queries
+		for _, query := range []string{q.acquire, q.refresh, q.release, q.releaseLegacy, q.releasePattern, q.owner, q.isLocked} {
+			assert.NotContains(t, query, "?")
+			assert.Contains(t, query, "candi_locks")
+		}
+		assert.Contains(t, q.acquire, "$4")
+		assert.Contains(t, q.acquire, "RETURNING fencing_token")
+		assert.Empty(t, q.selectFencing)
+	})
+
+	t.Run("mysql", func(t *testing.T) {
+		store := &sqlLockStore{db: db, dialect: SQLLockerMySQL}
+		require.NoError(t, store.init("candi_locks"))
+		q := store.queries
+		assert.Equal(t, 6, strings.Count(q.acquire, "?"))
+		assert.Contains(t, q.acquire, "ON DUPLICATE KEY UPDATE")
+		assert.Equal(t, 2, strings.Count(q.selectFencing, "?"))
+		assert.NotContains(t, q.release, "$")
+	})
+
+	t.Run("unsupported dialect", func(t *testing.T) {
+		assert.Error(t, (&sqlLockStore{db: db, dialect: "oracle"}).init("candi_locks"))
+	})
+}
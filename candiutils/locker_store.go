@@ -0,0 +1,180 @@
+package candiutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/codebase/interfaces"
+	"github.com/google/uuid"
+)
+
+// Locker implementation of interfaces.Locker with lock record in database (see SQLLocker and MongoLocker),
+// lock record is kept after released for keep fencing token increasing
+
+type (
+	// lockStore backend of storeLocker, time of lock record is from application clock (all runtimes must have synced clock)
+	lockStore interface {
+		// acquire lock if not exist or expired, return fencing token and false if lock is held by another owner
+		acquire(ctx context.Context, lockKey, owner string, now, expiredAt time.Time) (fencingToken int64, ok bool, err error)
+		// refresh expired time if lock is still owned by owner
+		refresh(ctx context.Context, lockKey, owner string, now, expiredAt time.Time) (bool, error)
+		// release lock if still owned by owner
+		release(ctx context.Context, lockKey, owner string, now time.Time) (bool, error)
+		// releaseLegacy release lock if owner has legacyLockOwnerPrefix (acquired by IsLocked)
+		releaseLegacy(ctx context.Context, lockKey string, now time.Time) (bool, error)
+		// releasePattern release all lock match with pattern (with * wildcard)
+		releasePattern(ctx context.Context, pattern string, now time.Time) error
+		// owner get owner of lock, empty if lock is not exist or expired
+		owner(ctx context.Context, lockKey string, now time.Time) (string, error)
+		// isLocked check lock is exist and not expired
+		isLocked(ctx context.Context, lockKey string, now time.Time) (bool, error)
+	}
+
+	storeLocker struct {
+		store         lockStore
+		lockeroptions LockerOptions
+	}
+)
+
+const (
+	// legacyLockTTL TTL for lock without TTL (IsLocked), lock must be released with Unlock
+	legacyLockTTL = 100 * 365 * 24 * time.Hour
+	// legacyLockOwnerPrefix prefix of lock owner from IsLocked, Unlock only release this owner so lock from AcquireLock is kept
+	legacyLockOwnerPrefix = "legacy:"
+)
+
+func newStoreLocker(store lockStore, opts ...LockerOption) storeLocker {
+	lockeroptions := LockerOptions{
+		Prefix: "LOCKFOR",
+		Table:  "candi_locks",
+	}
+	for _, opt := range opts {
+		opt(&lockeroptions)
+	}
+	return storeLocker{store: store, lockeroptions: lockeroptions}
+}
+
+func (s *storeLocker) lockKey(key string) string {
+	return fmt.Sprintf("%s:%s", s.lockeroptions.Prefix, key)
+}
+
+// GetPrefixLocker returns the prefix used for keys
+func (s *storeLocker) GetPrefixLocker() string {
+	return s.lockeroptions.Prefix + ":"
+}
+
+// GetTTLLocker returns the default TTL for keys
+func (s *storeLocker) GetTTLLocker() time.Duration {
+	return s.lockeroptions.TTL
+}
+
+// IsLocked method, return true if lock is held, otherwise acquire the lock (without TTL)
+func (s *storeLocker) IsLocked(key string) bool {
+	return s.IsLockedTTL(key, legacyLockTTL)
+}
+
+// IsLockedTTL method, return true if lock is held, otherwise acquire the lock with TTL (default TTL if zero)
+func (s *storeLocker) IsLockedTTL(key string, ttl time.Duration) bool {
+	if ttl <= 0 {
+		ttl = s.lockeroptions.TTL
+	}
+	if ttl <= 0 {
+		ttl = legacyLockTTL
+	}
+	now := time.Now()
+	_, ok, err := s.store.acquire(context.Background(), s.lockKey(key), legacyLockOwnerPrefix+uuid.NewString(), now, now.Add(ttl))
+	if err != nil {
+		return false
+	}
+	return !ok
+}
+
+// HasBeenLocked method
+func (s *storeLocker) HasBeenLocked(key string) bool {
+	locked, _ := s.store.isLocked(context.Background(), s.lockKey(key), time.Now())
+	return locked
+}
+
+// Unlock method, release lock from IsLocked/IsLockedTTL, lock lease from AcquireLock must be released by the owner
+func (s *storeLocker) Unlock(key string) {
+	s.store.releaseLegacy(context.Background(), s.lockKey(key), time.Now())
+}
+
+// GetLockOwner method, return owner token of lock lease from AcquireLock
+func (s *storeLocker) GetLockOwner(ctx context.Context, key string) (string, error) {
+	owner, err := s.store.owner(ctx, s.lockKey(key), time.Now())
+	if err != nil || strings.HasPrefix(owner, legacyLockOwnerPrefix) {
+		return "", err
+	}
+	return owner, nil
+}
+
+// Reset method, release all lock match with key pattern
+func (s *storeLocker) Reset(key string) {
+	if err := s.store.releasePattern(context.Background(), s.lockKey(key), time.Now()); err != nil {
+		fmt.Println("Error when reset locker: ", key, err)
+	}
+}
+
+// Lock method, wait until lock released by another process or timeout, lock lease is renewed automatically until unlockFunc called
+func (s *storeLocker) Lock(key string, timeout time.Duration) (unlockFunc func(), err error) {
+	if timeout <= 0 {
+		return func() {}, errors.New("timeout must be positive")
+	}
+	if key == "" {
+		return func() {}, errors.New("key cannot empty")
+	}
+
+	lease, err := s.AcquireLock(context.Background(), key, candishared.LockSetWaitTimeout(timeout), candishared.LockSetAutoRenew(true))
+	if err != nil {
+		if errors.Is(err, candishared.ErrLockNotAcquired) {
+			return func() {}, errors.New("timeout when waiting unlock another process")
+		}
+		return func() {}, err
+	}
+	return func() { lease.Release(context.Background()) }, nil
+}
+
+// AcquireLock method
+func (s *storeLocker) AcquireLock(ctx context.Context, key string, opts ...candishared.LockOption) (interfaces.LockLease, error) {
+	if key == "" {
+		return nil, errors.New("key cannot empty")
+	}
+	opt := candishared.ParseLockOptions(s.lockeroptions.TTL, opts...)
+	lockKey := s.lockKey(key)
+
+	return acquireLockWithRetry(ctx, opt, func(ctx context.Context) (interfaces.LockLease, error) {
+		owner := opt.Token
+		if owner == "" {
+			owner = uuid.NewString()
+		}
+		now := time.Now()
+		fencingToken, ok, err := s.store.acquire(ctx, lockKey, owner, now, now.Add(opt.TTL))
+		if err != nil || !ok {
+			return nil, err
+		}
+		return newLockLease(key, owner, fencingToken, opt,
+			func(ctx context.Context) (bool, error) {
+				now := time.Now()
+				return s.store.refresh(ctx, lockKey, owner, now, now.Add(opt.TTL))
+			},
+			func(ctx context.Context) (bool, error) {
+				return s.store.release(ctx, lockKey, owner, time.Now())
+			},
+		), nil
+	})
+}
+
+// Disconnect method, database connection is not closed (owned by caller)
+func (s *storeLocker) Disconnect(ctx context.Context) error {
+	return nil
+}
+
+// likePattern convert key pattern with * wildcard to SQL LIKE pattern (escaped with '!')
+func likePattern(pattern string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_", "*", "%").Replace(pattern)
+}
@@ -0,0 +1,56 @@
+package candiutils
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/codebase/interfaces"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockLeaseAutoRenew(t *testing.T) {
+	var refreshed atomic.Int32
+	owned := atomic.Bool{}
+	owned.Store(true)
+
+	lease := newLockLease("key", "token", 1, candishared.LockOptions{TTL: 30 * time.Millisecond, AutoRenew: true},
+		func(context.Context) (bool, error) { refreshed.Add(1); return owned.Load(), nil },
+		func(context.Context) (bool, error) { return owned.Load(), nil },
+	)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Greater(t, refreshed.Load(), int32(0))
+	select {
+	case <-lease.Lost():
+		t.Fatal("lease must not be lost")
+	default:
+	}
+
+	owned.Store(false)
+	select {
+	case <-lease.Lost():
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("lease must be lost")
+	}
+	assert.ErrorIs(t, lease.Release(context.Background()), candishared.ErrLockNotOwned)
+}
+
+func TestAcquireLockWithRetry(t *testing.T) {
+	var attempt int
+	opt := candishared.ParseLockOptions(0, candishared.LockSetWaitTimeout(100*time.Millisecond), candishared.LockSetRetryInterval(10*time.Millisecond))
+	lease, err := acquireLockWithRetry(context.Background(), opt, func(context.Context) (interfaces.LockLease, error) {
+		if attempt++; attempt < 3 {
+			return nil, nil
+		}
+		return NoopLocker{}.AcquireLock(context.Background(), "key")
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, lease)
+	assert.Equal(t, 3, attempt)
+
+	_, err = acquireLockWithRetry(context.Background(), opt, func(context.Context) (interfaces.LockLease, error) { return nil, nil })
+	assert.ErrorIs(t, err, candishared.ErrLockNotAcquired)
+}
@@ -0,0 +1,361 @@
+package candiutils
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/codebase/interfaces"
+	"github.com/gomodule/redigo/redis"
+)
+
+// Rate limiter implementation of interfaces.RateLimiter, token bucket, fixed window or sliding window algorithm
+// either in one runtime or multiple runtimes
+
+const (
+	// RateLimitTokenBucket algorithm, allow burst request up to burst size and refill token at constant rate (default)
+	RateLimitTokenBucket RateLimitAlgorithm = "token_bucket"
+	// RateLimitFixedWindow algorithm, count request in fixed time window (aligned to period)
+	RateLimitFixedWindow RateLimitAlgorithm = "fixed_window"
+	// RateLimitSlidingWindow algorithm, weighted count of previous and current fixed window (sliding window counter)
+	RateLimitSlidingWindow RateLimitAlgorithm = "sliding_window"
+)
+
+type (
+	// RateLimitAlgorithm type
+	RateLimitAlgorithm string
+
+	// RateLimiterOptions for rate limiter
+	RateLimiterOptions struct {
+		Prefix    string
+		Burst     int
+		Algorithm RateLimitAlgorithm
+	}
+
+	// RateLimiterOption function type for setting options
+	RateLimiterOption func(*RateLimiterOptions)
+
+	// LocalRateLimiter in-process rate limiter
+	LocalRateLimiter struct {
+		mu      sync.Mutex
+		limit   int
+		period  time.Duration
+		opt     RateLimiterOptions
+		buckets map[string]*tokenBucket
+		windows map[string]*rateWindow
+		calls   int
+	}
+
+	// CacheRateLimiter distributed rate limiter using redis cache
+	CacheRateLimiter struct {
+		cache  interfaces.Cache
+		limit  int
+		period time.Duration
+		opt    RateLimiterOptions
+	}
+
+	tokenBucket struct {
+		tokens   float64
+		lastTime time.Time
+	}
+
+	rateWindow struct {
+		start            time.Time
+		count, prevCount int
+	}
+)
+
+// WithPrefixRateLimiter sets the prefix for keys
+func WithPrefixRateLimiter(prefix string) RateLimiterOption {
+	return func(o *RateLimiterOptions) {
+		o.Prefix = prefix
+	}
+}
+
+// WithAlgorithmRateLimiter sets rate limit algorithm, default is token bucket
+func WithAlgorithmRateLimiter(algorithm RateLimitAlgorithm) RateLimiterOption {
+	return func(o *RateLimiterOptions) {
+		o.Algorithm = algorithm
+	}
+}
+
+// WithBurstRateLimiter sets max burst request (only for token bucket algorithm), default is same with limit
+func WithBurstRateLimiter(burst int) RateLimiterOption {
+	return func(o *RateLimiterOptions) {
+		o.Burst = burst
+	}
+}
+
+// validateRateLimitPeriod period is stored in milliseconds precision in cache rate limiter
+func validateRateLimitPeriod(period time.Duration) {
+	if period < time.Millisecond {
+		panic(fmt.Sprintf("rate limiter: period must be at least 1ms, got %s", period))
+	}
+}
+
+func newRateLimiterOptions(limit int, opts ...RateLimiterOption) RateLimiterOptions {
+	opt := RateLimiterOptions{Prefix: "RATELIMIT", Burst: limit, Algorithm: RateLimitTokenBucket}
+	for _, o := range opts {
+		o(&opt)
+	}
+	if opt.Burst <= 0 {
+		opt.Burst = limit
+	}
+	return opt
+}
+
+// NewLocalRateLimiter constructor, allow limit request per period for each key in this runtime,
+// panic if period is less than 1 millisecond
+func NewLocalRateLimiter(limit int, period time.Duration, opts ...RateLimiterOption) *LocalRateLimiter {
+	validateRateLimitPeriod(period)
+	return &LocalRateLimiter{
+		limit:   limit,
+		period:  period,
+		opt:     newRateLimiterOptions(limit, opts...),
+		buckets: make(map[string]*tokenBucket),
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// Allow method
+func (l *LocalRateLimiter) Allow(ctx context.Context, key string) (candishared.RateLimitResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	rate := float64(l.limit) / float64(l.period)
+	capacity := float64(l.opt.Burst)
+
+	l.calls++
+	if l.calls%1000 == 0 {
+		l.removeFullBuckets(now, rate, capacity)
+	}
+
+	if l.opt.Algorithm == RateLimitFixedWindow || l.opt.Algorithm == RateLimitSlidingWindow {
+		return l.allowWindow(key, now), nil
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, lastTime: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(capacity, bucket.tokens+float64(now.Sub(bucket.lastTime))*rate)
+	bucket.lastTime = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	return tokenBucketResult(allowed, l.opt.Burst, bucket.tokens, capacity, rate), nil
+}
+
+func (l *LocalRateLimiter) allowWindow(key string, now time.Time) candishared.RateLimitResult {
+	start := now.Truncate(l.period)
+	window, ok := l.windows[key]
+	if !ok {
+		window = &rateWindow{start: start}
+		l.windows[key] = window
+	}
+	if !window.start.Equal(start) {
+		window.prevCount = 0
+		if window.start.Add(l.period).Equal(start) {
+			window.prevCount = window.count
+		}
+		window.start, window.count = start, 0
+	}
+
+	elapsed := now.Sub(start)
+	allowed := windowAllowed(l.opt.Algorithm, l.limit, l.period, elapsed, window.prevCount, window.count)
+	if allowed {
+		window.count++
+	}
+	return windowResult(l.opt.Algorithm, allowed, l.limit, l.period, elapsed, window.prevCount, window.count)
+}
+
+// removeFullBuckets remove idle bucket that has been restored to full capacity and expired window
+func (l *LocalRateLimiter) removeFullBuckets(now time.Time, rate, capacity float64) {
+	for key, bucket := range l.buckets {
+		if bucket.tokens+float64(now.Sub(bucket.lastTime))*rate >= capacity {
+			delete(l.buckets, key)
+		}
+	}
+	for key, window := range l.windows {
+		if now.Sub(window.start) >= 2*l.period {
+			delete(l.windows, key)
+		}
+	}
+}
+
+// NewCacheRateLimiter constructor, allow limit request per period for each key across multiple runtimes,
+// cache must support EVAL command (redis), panic if period is less than 1 millisecond
+func NewCacheRateLimiter(cache interfaces.Cache, limit int, period time.Duration, opts ...RateLimiterOption) *CacheRateLimiter {
+	validateRateLimitPeriod(period)
+	return &CacheRateLimiter{
+		cache:  cache,
+		limit:  limit,
+		period: period,
+		opt:    newRateLimiterOptions(limit, opts...),
+	}
+}
+
+// NewRedisRateLimiter constructor, allow limit request per period for each key across multiple runtimes,
+// panic if period is less than 1 millisecond
+func NewRedisRateLimiter(pool *redis.Pool, limit int, period time.Duration, opts ...RateLimiterOption) *CacheRateLimiter {
+	return NewCacheRateLimiter(&redisPoolCommander{pool: pool}, limit, period, opts...)
+}
+
+const tokenBucketScript = `
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+local data = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(data[1]) or capacity
+local ts = tonumber(data[2]) or now
+tokens = math.min(capacity, tokens + (now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], ttl)
+return {allowed, tostring(tokens)}
+`
+
+const windowScript = `
+local limit = tonumber(ARGV[1])
+local weight = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+local prev = tonumber(redis.call("GET", KEYS[2]) or "0")
+local count = tonumber(redis.call("GET", KEYS[1]) or "0")
+local allowed = 0
+if prev * weight + count + 1 <= limit then
+	count = redis.call("INCR", KEYS[1])
+	redis.call("PEXPIRE", KEYS[1], ttl)
+	allowed = 1
+end
+return {allowed, prev, count}
+`
+
+// Allow method
+func (c *CacheRateLimiter) Allow(ctx context.Context, key string) (res candishared.RateLimitResult, err error) {
+	if c.opt.Algorithm == RateLimitFixedWindow || c.opt.Algorithm == RateLimitSlidingWindow {
+		return c.allowWindow(ctx, key)
+	}
+
+	capacity := float64(c.opt.Burst)
+	ratePerMs := float64(c.limit) / float64(c.period.Milliseconds())
+	ttl := int64(math.Ceil(capacity/ratePerMs)) + 1000
+
+	reply, err := redis.Values(c.cache.DoCommand(ctx, true, "EVAL", tokenBucketScript, 1,
+		fmt.Sprintf("%s:%s", c.opt.Prefix, key), capacity, ratePerMs, time.Now().UnixMilli(), ttl))
+	if err != nil {
+		return res, err
+	}
+	if len(reply) != 2 {
+		return res, fmt.Errorf("rate limiter: invalid reply %v", reply)
+	}
+	allowed, _ := redis.Int(reply[0], nil)
+	tokensStr, _ := redis.String(reply[1], nil)
+	tokens, _ := strconv.ParseFloat(tokensStr, 64)
+
+	return tokenBucketResult(allowed == 1, c.opt.Burst, tokens, capacity, ratePerMs/float64(time.Millisecond)), nil
+}
+
+func (c *CacheRateLimiter) allowWindow(ctx context.Context, key string) (res candishared.RateLimitResult, err error) {
+	now := time.Now()
+	index := now.UnixMilli() / c.period.Milliseconds()
+	elapsed := now.Sub(time.UnixMilli(index * c.period.Milliseconds()))
+	weight := 0.0
+	if c.opt.Algorithm == RateLimitSlidingWindow {
+		weight = float64(c.period-elapsed) / float64(c.period)
+	}
+
+	reply, err := redis.Ints(c.cache.DoCommand(ctx, true, "EVAL", windowScript, 2,
+		fmt.Sprintf("%s:%s:%d", c.opt.Prefix, key, index), fmt.Sprintf("%s:%s:%d", c.opt.Prefix, key, index-1),
+		c.limit, strconv.FormatFloat(weight, 'f', -1, 64), 2*c.period.Milliseconds()))
+	if err != nil {
+		return res, err
+	}
+	if len(reply) != 3 {
+		return res, fmt.Errorf("rate limiter: invalid reply %v", reply)
+	}
+	return windowResult(c.opt.Algorithm, reply[0] == 1, c.limit, c.period, elapsed, reply[1], reply[2]), nil
+}
+
+// windowAllowed check request is allowed in fixed/sliding window, count is request in current window before this request
+func windowAllowed(algorithm RateLimitAlgorithm, limit int, period, elapsed time.Duration, prevCount, count int) bool {
+	return windowEstimate(algorithm, period, elapsed, prevCount, count)+1 <= float64(limit)
+}
+
+func windowEstimate(algorithm RateLimitAlgorithm, period, elapsed time.Duration, prevCount, count int) float64 {
+	if algorithm != RateLimitSlidingWindow {
+		return float64(count)
+	}
+	return float64(prevCount)*float64(period-elapsed)/float64(period) + float64(count)
+}
+
+// windowResult construct result, count is request in current window include this request if allowed
+func windowResult(algorithm RateLimitAlgorithm, allowed bool, limit int, period, elapsed time.Duration, prevCount, count int) candishared.RateLimitResult {
+	untilNextWindow := period - elapsed
+	res := candishared.RateLimitResult{
+		Allowed:    allowed,
+		Limit:      limit,
+		Remaining:  int(math.Max(0, math.Floor(float64(limit)-windowEstimate(algorithm, period, elapsed, prevCount, count)))),
+		ResetAfter: untilNextWindow,
+	}
+	if algorithm == RateLimitSlidingWindow && count > 0 {
+		// request in current window still counted (weighted) in next window
+		res.ResetAfter += period
+	}
+	if allowed {
+		return res
+	}
+
+	res.RetryAfter = untilNextWindow
+	if algorithm == RateLimitSlidingWindow {
+		if count+1 <= limit && prevCount > 0 {
+			// wait until weighted previous window count decrease enough in current window
+			res.RetryAfter = untilNextWindow - time.Duration(float64(limit-count-1)*float64(period)/float64(prevCount))
+		} else if count > 0 {
+			// wait in next window, current window count become previous window count
+			res.RetryAfter += time.Duration(float64(period) * math.Max(0, 1-float64(limit-1)/float64(count)))
+		}
+		if res.RetryAfter < 0 {
+			res.RetryAfter = 0
+		}
+	}
+	return res
+}
+
+// tokenBucketResult construct result, rate in tokens per nanosecond
+func tokenBucketResult(allowed bool, limit int, tokens, capacity, rate float64) candishared.RateLimitResult {
+	res := candishared.RateLimitResult{
+		Allowed:    allowed,
+		Limit:      limit,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((capacity - tokens) / rate),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) / rate)
+	}
+	return res
+}
+
+// redisPoolCommander adapter redis pool for command execution
+type redisPoolCommander struct {
+	interfaces.Cache
+	pool *redis.Pool
+}
+
+func (r *redisPoolCommander) DoCommand(ctx context.Context, isWrite bool, command string, args ...any) (reply any, err error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+	return conn.Do(command, args...)
+}
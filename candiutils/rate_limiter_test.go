@@ -0,0 +1,64 @@
+package candiutils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golangid/candi/candishared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewLocalRateLimiter(3, time.Second)
+
+	for i := 0; i < 3; i++ {
+		res, err := limiter.Allow(ctx, "client-a")
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 2-i, res.Remaining)
+	}
+
+	res, _ := limiter.Allow(ctx, "client-a")
+	assert.False(t, res.Allowed)
+	assert.Greater(t, res.RetryAfter, time.Duration(0))
+	retryAfter := res.RetryAfter
+
+	// other key has own quota
+	res, _ = limiter.Allow(ctx, "client-b")
+	assert.True(t, res.Allowed)
+
+	time.Sleep(retryAfter)
+	res, _ = limiter.Allow(ctx, "client-a")
+	assert.True(t, res.Allowed)
+}
+
+func TestLocalRateLimiterWindow(t *testing.T) {
+	ctx := context.Background()
+	for _, algorithm := range []RateLimitAlgorithm{RateLimitFixedWindow, RateLimitSlidingWindow} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			limiter := NewLocalRateLimiter(3, 200*time.Millisecond, WithAlgorithmRateLimiter(algorithm))
+
+			var res candishared.RateLimitResult
+			for i := 0; i < 10; i++ {
+				if res, _ = limiter.Allow(ctx, "client-a"); !res.Allowed {
+					break
+				}
+			}
+			assert.False(t, res.Allowed)
+			assert.Equal(t, 0, res.Remaining)
+			assert.Greater(t, res.RetryAfter, time.Duration(0))
+
+			time.Sleep(res.RetryAfter + 5*time.Millisecond)
+			res, _ = limiter.Allow(ctx, "client-a")
+			assert.True(t, res.Allowed)
+		})
+	}
+}
+
+func TestRateLimiterInvalidPeriod(t *testing.T) {
+	assert.Panics(t, func() { NewLocalRateLimiter(1, time.Microsecond) })
+	assert.Panics(t, func() { NewCacheRateLimiter(nil, 1, 0) })
+	assert.NotPanics(t, func() { NewLocalRateLimiter(1, time.Millisecond) })
+}
@@ -32,6 +32,10 @@ func main() {
 
 	flag.BoolVar(&flagParam.run, "run", false, "[service runner] run selected service or all service in monorepo")
 	flag.StringVar(&flagParam.serviceName, "service", "", `Describe service name (if run multiple services, separate by comma)`)
+
+	flag.StringVar(&flagParam.persistedQueryManifest, "register-persisted-queries", "", "[graphql] register persisted query manifest file to graphql server allow-list")
+	flag.StringVar(&flagParam.graphqlEndpoint, "graphql-endpoint", "http://localhost:8000/graphql", "[graphql] graphql server endpoint for register persisted queries, "+
+		"authorized with BASIC_AUTH_USERNAME and BASIC_AUTH_PASS env")
 	flag.Parse()
 
 	tpl = template.New(flagParam.libraryNameFlag)
@@ -46,6 +50,9 @@ func main() {
 	case flagParam.run:
 		serviceRunner(flagParam.serviceName)
 
+	case flagParam.persistedQueryManifest != "":
+		registerPersistedQueries(flagParam.graphqlEndpoint, flagParam.persistedQueryManifest)
+
 	case flagParam.initMonorepo:
 		monorepoGenerator(flagParam)
 
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+func registerPersistedQueries(graphqlEndpoint, manifestFile string) {
+	manifest, err := os.ReadFile(manifestFile)
+	if err != nil {
+		fmt.Printf(RedFormat, err.Error())
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(graphqlEndpoint, "/")+"/persisted-queries", bytes.NewReader(manifest))
+	if err != nil {
+		fmt.Printf(RedFormat, err.Error())
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(os.Getenv("BASIC_AUTH_USERNAME"), os.Getenv("BASIC_AUTH_PASS"))
+
+	resp, err := (&http.Client{Timeout: time.Minute}).Do(req)
+	if err != nil {
+		fmt.Printf(RedFormat, err.Error())
+		return
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		fmt.Printf(RedFormat, fmt.Sprintf("Failed register persisted queries (%d): %s", resp.StatusCode, body))
+		return
+	}
+	logger.Printf("Success register persisted queries from %s: %s", manifestFile, body)
+}
@@ -54,6 +54,7 @@ type flagParameter struct {
 	initService, addModule, addHandler, initMonorepo, version, isMonorepo         bool
 	serviceName, moduleName, monorepoProjectName                                  string
 	modules                                                                       []string
+	persistedQueryManifest, graphqlEndpoint                                       string
 }
 
 func (f *flagParameter) parseMonorepoFlag() error {
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"reflect"
 	"strings"
 	"sync"
@@ -18,6 +19,7 @@ import (
 	cronexpr "github.com/golangid/candi/candiutils/cronparser"
 	"github.com/golangid/candi/codebase/factory"
 	"github.com/golangid/candi/codebase/factory/types"
+	"github.com/golangid/candi/codebase/interfaces"
 	"github.com/golangid/candi/logger"
 	"github.com/golangid/candi/tracer"
 )
@@ -33,6 +35,8 @@ type cronWorker struct {
 	semaphore                    []chan struct{}
 	wg                           sync.WaitGroup
 	activeJobs                   []*Job
+	leaderElection               *leaderElection
+	httpServer                   *http.Server
 }
 
 // NewWorker create new cron worker
@@ -80,6 +84,9 @@ func NewWorker(service factory.ServiceFactory, opts ...OptionFunc) factory.AppSe
 			}
 		}
 	}
+	if c.opt.leaderElection {
+		c.leaderElection = newLeaderElection(c.opt.locker, fmt.Sprintf(leaderLockPattern, service.Name()), c.opt.leaderElectionTTL)
+	}
 	fmt.Printf("\x1b[34;1m⇨ Cron worker running with %d jobs\x1b[0m\n\n", len(c.activeJobs))
 
 	c.ctx, c.ctxCancelFunc = context.WithCancel(context.Background())
@@ -87,6 +94,14 @@ func NewWorker(service factory.ServiceFactory, opts ...OptionFunc) factory.AppSe
 }
 
 func (c *cronWorker) Serve() {
+	if c.leaderElection != nil {
+		go c.leaderElection.run(c.ctx, c.recoverMisfire)
+	} else {
+		go c.recoverMisfire()
+	}
+	if c.opt.httpPort > 0 {
+		go c.serveHTTP()
+	}
 
 	for _, job := range c.activeJobs {
 		c.workers[job.WorkerIndex].Chan = reflect.ValueOf(job.ticker.C)
@@ -129,7 +144,7 @@ func (c *cronWorker) Serve() {
 				return
 			}
 
-			c.processJob(j)
+			c.processJob(j, TriggerSchedule)
 		}(job)
 	}
 
@@ -159,6 +174,9 @@ func (c *cronWorker) Shutdown(ctx context.Context) {
 
 	c.wg.Wait()
 	c.ctxCancelFunc()
+	if c.httpServer != nil {
+		c.httpServer.Shutdown(ctx)
+	}
 	c.opt.locker.Reset(fmt.Sprintf(lockPattern, c.service.Name(), "*"))
 }
 
@@ -166,32 +184,55 @@ func (c *cronWorker) Name() string {
 	return string(types.Scheduler)
 }
 
-func (c *cronWorker) processJob(job *Job) {
-	ctx := c.ctx
-	if job.Handler.DisableTrace {
-		ctx = tracer.SkipTraceContext(ctx)
+func (c *cronWorker) processJob(job *Job, trigger string) {
+	// only leader run the schedule
+	if c.leaderElection != nil {
+		if _, isLeader := c.leaderElection.leader(); !isLeader {
+			return
+		}
 	}
 
-	// lock for multiple worker (if running on multiple pods/instance)
+	// lock for multiple worker (if running on multiple pods/instance) and manual trigger
 	if c.opt.locker.IsLocked(c.getLockKey(job.HandlerName)) {
 		logger.LogYellow("cron_worker > job " + job.HandlerName + " is locked")
 		return
 	}
 	defer c.opt.locker.Unlock(c.getLockKey(job.HandlerName))
 
+	c.runJob(job, trigger)
+}
+
+// runJob execute job handlers, job lock must be held by caller
+func (c *cronWorker) runJob(job *Job, trigger string) {
+	ctx := c.ctx
+	if job.Handler.DisableTrace {
+		ctx = tracer.SkipTraceContext(ctx)
+	}
+
+	var leaderLease interfaces.LockLease
+	if c.leaderElection != nil {
+		leaderLease, _ = c.leaderElection.leader()
+	}
+
 	var err error
+	startedAt := time.Now()
 	trace, ctx := tracer.StartTraceFromHeader(ctx, "CronScheduler", make(map[string]string, 0))
 	defer func() {
 		if r := recover(); r != nil {
 			trace.SetTag("panic", true)
 			err = fmt.Errorf("%v", r)
 		}
+		c.saveRun(ctx, job, trigger, startedAt, err)
 		trace.Finish(tracer.FinishWithError(err))
 	}()
 
 	trace.SetTag("cron_expr", job.Interval)
 	trace.SetTag("job_name", job.HandlerName)
+	trace.SetTag("trigger", trigger)
 	trace.Log("job_param", job.Params)
+	if leaderLease != nil {
+		trace.SetTag("leader_fencing_token", leaderLease.FencingToken())
+	}
 
 	if c.opt.debugMode {
 		log.Printf("\x1b[35;3mCron Scheduler: executing task '%s' (interval: %s)\x1b[0m", job.HandlerName, job.Interval)
@@ -228,16 +269,23 @@ func (c *cronWorker) stopAllJob() {
 }
 
 func (c *cronWorker) registerNextInterval(j *Job) {
+	now := time.Now()
 	if j.schedule != nil {
+		interval := j.schedule.NextInterval(now)
 		j.ticker.Stop()
-		j.ticker = time.NewTicker(j.schedule.NextInterval(time.Now()))
+		j.ticker = time.NewTicker(interval)
 		c.workers[j.WorkerIndex].Chan = reflect.ValueOf(j.ticker.C)
+		j.nextRunAt.Store(now.Add(interval).UnixNano())
 
 	} else if j.nextDuration != nil {
 		j.ticker.Stop()
 		j.ticker = time.NewTicker(*j.nextDuration)
 		c.workers[j.WorkerIndex].Chan = reflect.ValueOf(j.ticker.C)
 		j.nextDuration = nil
+		j.nextRunAt.Store(now.Add(j.repeat).UnixNano())
+
+	} else {
+		j.nextRunAt.Store(now.Add(j.repeat).UnixNano())
 	}
 
 	c.refreshWorker()
@@ -252,7 +300,14 @@ func (c *cronWorker) addJob(job *Job) (err error) {
 		return errors.New("handler name cannot empty")
 	}
 
-	duration, nextDuration, err := candihelper.ParseDurationExpression(job.Interval)
+	loc, interval, err := cronexpr.SplitTimezone(job.Interval)
+	if err != nil {
+		return err
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	duration, nextDuration, err := candihelper.ParseDurationExpressionInLocation(interval, loc)
 	if err != nil {
 		job.schedule, err = cronexpr.Parse(job.Interval)
 		if err != nil {
@@ -261,9 +316,12 @@ func (c *cronWorker) addJob(job *Job) (err error) {
 		duration = job.schedule.NextInterval(time.Now())
 	}
 
+	job.repeat = duration
 	if nextDuration > 0 {
 		job.nextDuration = &nextDuration
+		job.repeat = nextDuration
 	}
+	job.nextRunAt.Store(time.Now().Add(duration).UnixNano())
 
 	job.ticker = time.NewTicker(duration)
 	job.WorkerIndex = len(c.workers)
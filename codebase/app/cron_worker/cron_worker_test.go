@@ -0,0 +1,151 @@
+package cronworker
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/codebase/factory/types"
+	mockfactory "github.com/golangid/candi/mocks/codebase/factory"
+	mockdeps "github.com/golangid/candi/mocks/codebase/factory/dependency"
+	mockinterfaces "github.com/golangid/candi/mocks/codebase/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore in memory Store
+type fakeStore struct {
+	mu        sync.Mutex
+	states    map[string]*ScheduleState
+	histories []RunHistory
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{states: map[string]*ScheduleState{}}
+}
+
+func (f *fakeStore) GetScheduleState(ctx context.Context, jobName string) (*ScheduleState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if state, ok := f.states[jobName]; ok {
+		copied := *state
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (f *fakeStore) SaveScheduleState(ctx context.Context, state *ScheduleState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	copied := *state
+	f.states[state.JobName] = &copied
+	return nil
+}
+
+func (f *fakeStore) SaveRunHistory(ctx context.Context, history *RunHistory) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.histories = append(f.histories, *history)
+	return nil
+}
+
+func (f *fakeStore) FindRunHistories(ctx context.Context, jobName string, page, limit int) (histories []RunHistory, total int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, history := range f.histories {
+		if history.JobName == jobName {
+			histories = append(histories, history)
+		}
+	}
+	total = len(histories)
+	histories = histories[min((page-1)*limit, total):min(page*limit, total)]
+	return histories, total, nil
+}
+
+func (f *fakeStore) runHistories() []RunHistory {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]RunHistory{}, f.histories...)
+}
+
+// newTestCronWorker create cron worker without module, basic auth of http api is valid if username is "user"
+func newTestCronWorker(t *testing.T, opts ...OptionFunc) *cronWorker {
+	mw := &mockinterfaces.Middleware{}
+	mw.On("HTTPBasicAuth", mock.Anything).Return(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if username, _, _ := req.BasicAuth(); username != "user" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	})
+	deps := &mockdeps.Dependency{}
+	deps.On("GetMiddleware").Return(mw)
+	service := &mockfactory.ServiceFactory{}
+	service.On("Name").Return(types.Service("test"))
+	service.On("GetDependency").Return(deps)
+
+	c := &cronWorker{
+		service: service,
+		opt:     option{maxGoroutines: 1, locker: newFakeLocker(), misfirePolicy: MisfireSkip},
+		workers: make([]reflect.SelectCase, 2),
+	}
+	for _, opt := range opts {
+		opt(&c.opt)
+	}
+	c.ctx, c.ctxCancelFunc = context.WithCancel(context.Background())
+	t.Cleanup(c.ctxCancelFunc)
+	return c
+}
+
+func addTestJob(t *testing.T, c *cronWorker, name, interval string, handlerFunc types.WorkerHandlerFunc) *Job {
+	job := &Job{HandlerName: name, Interval: interval, Handler: types.WorkerHandler{HandlerFuncs: []types.WorkerHandlerFunc{handlerFunc}}}
+	require.NoError(t, c.addJob(job))
+	t.Cleanup(job.ticker.Stop)
+	c.semaphore = append(c.semaphore, make(chan struct{}, c.opt.maxGoroutines))
+	return job
+}
+
+func TestProcessJob(t *testing.T) {
+	t.Run("job lock", func(t *testing.T) {
+		c := newTestCronWorker(t)
+		var runs int
+		job := addTestJob(t, c, "job", "1h", func(*candishared.EventContext) error { runs++; return nil })
+
+		c.processJob(job, TriggerSchedule)
+		assert.Equal(t, 1, runs)
+		assert.False(t, c.opt.locker.HasBeenLocked(c.getLockKey("job")))
+
+		c.opt.locker.IsLocked(c.getLockKey("job"))
+		c.processJob(job, TriggerSchedule)
+		assert.Equal(t, 1, runs)
+	})
+
+	t.Run("leader election", func(t *testing.T) {
+		locker := newFakeLocker()
+		c := newTestCronWorker(t, SetLocker(locker))
+		c.leaderElection = newLeaderElection(locker, "test:cron-worker-leader", time.Minute)
+		var runs int
+		job := addTestJob(t, c, "job", "1h", func(*candishared.EventContext) error { runs++; return nil })
+
+		c.processJob(job, TriggerSchedule)
+		assert.Equal(t, 0, runs)
+
+		lease, err := locker.AcquireLock(context.Background(), "test:cron-worker-leader")
+		require.NoError(t, err)
+		c.leaderElection.setLease(lease)
+		c.processJob(job, TriggerSchedule)
+		assert.Equal(t, 1, runs)
+
+		// job lock is held by manual trigger in another instance
+		locker.IsLocked(c.getLockKey("job"))
+		c.processJob(job, TriggerSchedule)
+		assert.Equal(t, 1, runs)
+	})
+}
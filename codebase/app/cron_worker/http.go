@@ -0,0 +1,145 @@
+package cronworker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golangid/candi/candihelper"
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/logger"
+	"github.com/golangid/candi/wrapper"
+)
+
+// JobSchedule response of list schedules api
+type JobSchedule struct {
+	JobName    string     `json:"job_name"`
+	Interval   string     `json:"interval"`
+	Params     string     `json:"params"`
+	NextRunAt  time.Time  `json:"next_run_at"`
+	LastRunAt  *time.Time `json:"last_run_at,omitempty"`
+	LastStatus string     `json:"last_status,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+// serveHTTP serve cron worker http endpoint if http port is set
+func (c *cronWorker) serveHTTP() {
+	c.httpServer = &http.Server{Addr: fmt.Sprintf(":%d", c.opt.httpPort), Handler: c.httpHandler()}
+	if err := c.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.LogRed("cron_worker > http server: " + err.Error())
+	}
+}
+
+// httpHandler jobs api is protected with basic auth, leader health is open for health probe
+func (c *cronWorker) httpHandler() http.Handler {
+	basicAuth := c.service.GetDependency().GetMiddleware().HTTPBasicAuth
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health/leader", c.handleLeaderHealth)
+	mux.Handle("GET /jobs", basicAuth(http.HandlerFunc(c.handleListSchedules)))
+	mux.Handle("GET /jobs/{name}/histories", basicAuth(http.HandlerFunc(c.handleListRunHistories)))
+	mux.Handle("POST /jobs/{name}/trigger", basicAuth(http.HandlerFunc(c.handleTriggerJob)))
+	return mux
+}
+
+// handleLeaderHealth response leader status of current instance
+func (c *cronWorker) handleLeaderHealth(w http.ResponseWriter, req *http.Request) {
+	status := LeaderStatus{}
+	if c.leaderElection != nil {
+		status = c.leaderElection.status(req.Context())
+	}
+	w.Header().Set(candihelper.HeaderContentType, candihelper.HeaderMIMEApplicationJSON)
+	json.NewEncoder(w).Encode(status)
+}
+
+func (c *cronWorker) handleListSchedules(w http.ResponseWriter, req *http.Request) {
+	schedules := make([]JobSchedule, 0, len(c.activeJobs))
+	for _, job := range c.activeJobs {
+		schedule := JobSchedule{
+			JobName: job.HandlerName, Interval: job.Interval, Params: job.Params, NextRunAt: job.NextRunAt(),
+		}
+		if c.opt.store != nil {
+			if state, err := c.opt.store.GetScheduleState(req.Context(), job.HandlerName); err == nil && state != nil {
+				schedule.LastRunAt, schedule.LastStatus, schedule.LastError = state.LastRunAt, state.LastStatus, state.LastError
+			}
+		}
+		schedules = append(schedules, schedule)
+	}
+	wrapper.NewHTTPResponse(http.StatusOK, "List schedules", schedules).JSON(w)
+}
+
+func (c *cronWorker) handleListRunHistories(w http.ResponseWriter, req *http.Request) {
+	job := c.findJob(req.PathValue("name"))
+	if job == nil {
+		wrapper.NewHTTPResponse(http.StatusNotFound, "Job not found").JSON(w)
+		return
+	}
+	if c.opt.store == nil {
+		wrapper.NewHTTPResponse(http.StatusNotImplemented, "Cron worker store is not set").JSON(w)
+		return
+	}
+
+	page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	histories, total, err := c.opt.store.FindRunHistories(req.Context(), job.HandlerName, page, limit)
+	if err != nil {
+		wrapper.NewHTTPResponse(http.StatusInternalServerError, "Failed get run histories", err).JSON(w)
+		return
+	}
+	if histories == nil {
+		histories = []RunHistory{}
+	}
+	wrapper.NewHTTPResponse(http.StatusOK, "List run histories", candishared.NewMeta(page, limit, total), histories).JSON(w)
+}
+
+// handleTriggerJob run job manually in any instance (include follower in leader election),
+// job is run with same max goroutines and job lock as scheduled run
+func (c *cronWorker) handleTriggerJob(w http.ResponseWriter, req *http.Request) {
+	job := c.findJob(req.PathValue("name"))
+	if job == nil {
+		wrapper.NewHTTPResponse(http.StatusNotFound, "Job not found").JSON(w)
+		return
+	}
+
+	semaphore := c.semaphore[job.WorkerIndex-2]
+	select {
+	case semaphore <- struct{}{}:
+	default:
+		wrapper.NewHTTPResponse(http.StatusTooManyRequests, "Job "+job.HandlerName+" reached max goroutines").JSON(w)
+		return
+	}
+	if c.opt.locker.IsLocked(c.getLockKey(job.HandlerName)) {
+		<-semaphore
+		wrapper.NewHTTPResponse(http.StatusConflict, "Job "+job.HandlerName+" is running").JSON(w)
+		return
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer func() {
+			c.opt.locker.Unlock(c.getLockKey(job.HandlerName))
+			<-semaphore
+			c.wg.Done()
+		}()
+		c.runJob(job, TriggerManual)
+	}()
+	wrapper.NewHTTPResponse(http.StatusAccepted, "Job "+job.HandlerName+" is triggered").JSON(w)
+}
+
+func (c *cronWorker) findJob(name string) *Job {
+	for _, job := range c.activeJobs {
+		if job.HandlerName == name {
+			return job
+		}
+	}
+	return nil
+}
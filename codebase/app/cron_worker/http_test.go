@@ -0,0 +1,124 @@
+package cronworker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golangid/candi/candishared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	store := newFakeStore()
+	c := newTestCronWorker(t, SetStore(store))
+	release := make(chan struct{})
+	addTestJob(t, c, "job", "1h", func(*candishared.EventContext) error { <-release; return nil })
+	handler := c.httpHandler()
+
+	serve := func(method, path string, auth bool) (int, map[string]any) {
+		req := httptest.NewRequest(method, path, nil)
+		if auth {
+			req.SetBasicAuth("user", "pass")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var resp map[string]any
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+
+	t.Run("leader health without auth", func(t *testing.T) {
+		code, resp := serve(http.MethodGet, "/health/leader", false)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, false, resp["enabled"])
+	})
+
+	t.Run("jobs api with basic auth", func(t *testing.T) {
+		for _, req := range [][2]string{
+			{http.MethodGet, "/jobs"}, {http.MethodGet, "/jobs/job/histories"}, {http.MethodPost, "/jobs/job/trigger"},
+		} {
+			code, _ := serve(req[0], req[1], false)
+			assert.Equal(t, http.StatusUnauthorized, code, req[1])
+		}
+	})
+
+	t.Run("trigger job", func(t *testing.T) {
+		code, _ := serve(http.MethodPost, "/jobs/unknown/trigger", true)
+		assert.Equal(t, http.StatusNotFound, code)
+
+		code, _ = serve(http.MethodPost, "/jobs/job/trigger", true)
+		assert.Equal(t, http.StatusAccepted, code)
+		require.Eventually(t, func() bool { return c.opt.locker.HasBeenLocked(c.getLockKey("job")) }, time.Second, 5*time.Millisecond)
+
+		// reached max goroutines (1)
+		code, _ = serve(http.MethodPost, "/jobs/job/trigger", true)
+		assert.Equal(t, http.StatusTooManyRequests, code)
+
+		// job lock is held by running job
+		c.opt.maxGoroutines = 2
+		c.semaphore[0] = make(chan struct{}, 2)
+		code, _ = serve(http.MethodPost, "/jobs/job/trigger", true)
+		assert.Equal(t, http.StatusConflict, code)
+		assert.Empty(t, c.semaphore[0])
+
+		close(release)
+		c.wg.Wait()
+		assert.False(t, c.opt.locker.HasBeenLocked(c.getLockKey("job")))
+		histories := store.runHistories()
+		require.Len(t, histories, 1)
+		assert.Equal(t, TriggerManual, histories[0].Trigger)
+	})
+
+	t.Run("trigger job in follower", func(t *testing.T) {
+		locker := newFakeLocker()
+		c := newTestCronWorker(t, SetLocker(locker))
+		c.leaderElection = newLeaderElection(locker, "test:cron-worker-leader", time.Minute)
+		var runs int
+		addTestJob(t, c, "job", "1h", func(*candishared.EventContext) error { runs++; return nil })
+
+		req := httptest.NewRequest(http.MethodPost, "/jobs/job/trigger", nil)
+		req.SetBasicAuth("user", "pass")
+		rec := httptest.NewRecorder()
+		c.httpHandler().ServeHTTP(rec, req)
+		c.wg.Wait()
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Equal(t, 1, runs)
+	})
+
+	t.Run("list schedules", func(t *testing.T) {
+		code, resp := serve(http.MethodGet, "/jobs", true)
+		assert.Equal(t, http.StatusOK, code)
+		schedules := resp["data"].([]any)
+		require.Len(t, schedules, 1)
+		schedule := schedules[0].(map[string]any)
+		assert.Equal(t, "job", schedule["job_name"])
+		assert.Equal(t, "1h", schedule["interval"])
+		assert.Equal(t, RunStatusSuccess, schedule["last_status"])
+	})
+
+	t.Run("list run histories", func(t *testing.T) {
+		code, _ := serve(http.MethodGet, "/jobs/unknown/histories", true)
+		assert.Equal(t, http.StatusNotFound, code)
+
+		for range 2 {
+			store.SaveRunHistory(context.Background(), &RunHistory{JobName: "job", Trigger: TriggerSchedule})
+		}
+		code, resp := serve(http.MethodGet, "/jobs/job/histories?page=2&limit=2", true)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Len(t, resp["data"], 1)
+		assert.Equal(t, float64(3), resp["meta"].(map[string]any)["totalRecords"])
+
+		c := newTestCronWorker(t)
+		addTestJob(t, c, "job", "1h", func(*candishared.EventContext) error { return nil })
+		req := httptest.NewRequest(http.MethodGet, "/jobs/job/histories", nil)
+		req.SetBasicAuth("user", "pass")
+		rec := httptest.NewRecorder()
+		c.httpHandler().ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotImplemented, rec.Code)
+	})
+}
@@ -1,6 +1,7 @@
 package cronworker
 
 import (
+	"sync/atomic"
 	"time"
 
 	cronexpr "github.com/golangid/candi/candiutils/cronparser"
@@ -17,4 +18,19 @@ type Job struct {
 	ticker       *time.Ticker        `json:"-"`
 	schedule     cronexpr.Schedule   `json:"-"`
 	nextDuration *time.Duration      `json:"-"`
+	repeat       time.Duration
+	nextRunAt    atomic.Int64
+}
+
+// NextRunAt next schedule of job
+func (j *Job) NextRunAt() time.Time {
+	return time.Unix(0, j.nextRunAt.Load())
+}
+
+// next return schedule after from time
+func (j *Job) next(from time.Time) time.Time {
+	if j.schedule != nil {
+		return j.schedule.Next(from)
+	}
+	return from.Add(j.repeat)
 }
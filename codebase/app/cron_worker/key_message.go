@@ -1,6 +1,10 @@
 package cronworker
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	cronexpr "github.com/golangid/candi/candiutils/cronparser"
+)
 
 const (
 	lockPattern = "%s:cron-worker-lock:%s"
@@ -11,6 +15,7 @@ type CronJobKey struct {
 	JobName  string `json:"jobName"`
 	Args     string `json:"args"`
 	Interval string `json:"interval"`
+	Timezone string `json:"timezone,omitempty"`
 }
 
 // String implement stringer
@@ -31,6 +36,10 @@ Allowed interval:
 	- 23:00@daily, will repeated at 23:00 every day
 	- 23:00@weekly, will repeated at 23:00 every week
 	- 23:00@10s, will repeated at 23:00 and next repeat every 10 seconds
+
+* interval with timezone prefix (default is local timezone), example:
+	- CRON_TZ=Asia/Jakarta 0 9 * * *
+	- TZ=Asia/Jakarta 23:00@daily
 */
 func CreateCronJobKey(jobName, args, interval string) string {
 	return CronJobKey{
@@ -38,9 +47,17 @@ func CreateCronJobKey(jobName, args, interval string) string {
 	}.String()
 }
 
-// ParseCronJobKey helper
+// CreateCronJobKeyWithTimezone helper, interval is evaluated in timezone (IANA name, example: Asia/Jakarta),
+// interval with timezone prefix (example: CRON_TZ=Asia/Jakarta 0 9 * * *) is also allowed in CreateCronJobKey
+func CreateCronJobKeyWithTimezone(jobName, args, interval, timezone string) string {
+	return CronJobKey{
+		JobName: jobName, Args: args, Interval: interval, Timezone: timezone,
+	}.String()
+}
+
+// ParseCronJobKey helper, timezone is included in interval as CRON_TZ= prefix
 func ParseCronJobKey(str string) (jobName, args, interval string) {
 	var cronKey CronJobKey
 	json.Unmarshal([]byte(str), &cronKey)
-	return cronKey.JobName, cronKey.Args, cronKey.Interval
+	return cronKey.JobName, cronKey.Args, cronexpr.WithTimezone(cronKey.Interval, cronKey.Timezone)
 }
@@ -0,0 +1,131 @@
+package cronworker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/codebase/interfaces"
+	"github.com/golangid/candi/logger"
+	"github.com/google/uuid"
+)
+
+// leader election, only one replica (leader) run all schedules, follower take over leadership after lease of leader expired
+
+const (
+	leaderLockPattern = "%s:cron-worker-leader"
+	// leaderTokenSeparator separator of instance id and unique id in owner token of leader lock
+	leaderTokenSeparator = "/"
+)
+
+type (
+	leaderElection struct {
+		mu         sync.RWMutex
+		instanceID string
+		locker     interfaces.Locker
+		key        string
+		ttl        time.Duration
+		lease      interfaces.LockLease
+		since      time.Time
+	}
+
+	// LeaderStatus status of leader election in current instance
+	LeaderStatus struct {
+		Enabled      bool       `json:"enabled"`
+		InstanceID   string     `json:"instance_id"`
+		IsLeader     bool       `json:"is_leader"`
+		LeaderID     string     `json:"leader_id,omitempty"`
+		LeaderSince  *time.Time `json:"leader_since,omitempty"`
+		FencingToken int64      `json:"fencing_token,omitempty"`
+	}
+)
+
+func newLeaderElection(locker interfaces.Locker, key string, ttl time.Duration) *leaderElection {
+	instanceID, _ := os.Hostname()
+	return &leaderElection{
+		instanceID: fmt.Sprintf("%s-%d", instanceID, os.Getpid()),
+		locker:     locker, key: key, ttl: ttl,
+	}
+}
+
+// run campaign until ctx canceled, leadership is released when ctx canceled, onElected is called after elected as leader
+func (l *leaderElection) run(ctx context.Context, onElected func()) {
+	for {
+		// instance id is kept in owner token of leader lock, so follower can report who is the leader
+		token := l.instanceID + leaderTokenSeparator + uuid.NewString()
+		lease, err := l.locker.AcquireLock(ctx, l.key,
+			candishared.LockSetTTL(l.ttl), candishared.LockSetAutoRenew(true), candishared.LockSetToken(token))
+		switch {
+		case err == nil:
+			l.setLease(lease)
+			logger.LogGreen(fmt.Sprintf("[CRON-WORKER] instance %s is elected as leader (fencing token: %d)", l.instanceID, lease.FencingToken()))
+			if onElected != nil {
+				go onElected()
+			}
+
+			select {
+			case <-ctx.Done():
+				l.setLease(nil)
+				lease.Release(context.Background())
+				return
+			case <-lease.Lost():
+				l.setLease(nil)
+				logger.LogYellow(fmt.Sprintf("[CRON-WORKER] instance %s lost leadership", l.instanceID))
+			}
+			continue
+
+		case ctx.Err() != nil:
+			return
+
+		case !errors.Is(err, candishared.ErrLockNotAcquired):
+			logger.LogRed("[CRON-WORKER] leader election: " + err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(l.ttl / 3):
+		}
+	}
+}
+
+func (l *leaderElection) setLease(lease interfaces.LockLease) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lease, l.since = lease, time.Now()
+}
+
+// leader return current lease if this instance is leader
+func (l *leaderElection) leader() (interfaces.LockLease, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.lease, l.lease != nil
+}
+
+// status return leader status, leader id is read from owner token of leader lock if this instance is follower
+func (l *leaderElection) status(ctx context.Context) (status LeaderStatus) {
+	l.mu.RLock()
+	status.Enabled = true
+	status.InstanceID = l.instanceID
+	if l.lease != nil {
+		since := l.since
+		status.IsLeader, status.LeaderID, status.LeaderSince, status.FencingToken = true, l.instanceID, &since, l.lease.FencingToken()
+	}
+	l.mu.RUnlock()
+
+	if ownerGetter, ok := l.locker.(interfaces.LockOwnerGetter); ok && !status.IsLeader {
+		token, err := ownerGetter.GetLockOwner(ctx, l.key)
+		if err != nil {
+			logger.LogRed("[CRON-WORKER] leader status: " + err.Error())
+		}
+		if i := strings.LastIndex(token, leaderTokenSeparator); i > 0 {
+			status.LeaderID = token[:i]
+		}
+	}
+	return status
+}
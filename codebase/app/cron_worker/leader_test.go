@@ -0,0 +1,209 @@
+package cronworker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/codebase/interfaces"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	// fakeLocker in memory locker, lease is not expired automatically (see expire)
+	fakeLocker struct {
+		interfaces.Locker
+		mu       sync.Mutex
+		leases   map[string]*fakeLease
+		locked   map[string]bool
+		fencing  int64
+		failFrom map[string]bool
+	}
+	fakeLease struct {
+		locker       *fakeLocker
+		key, token   string
+		fencingToken int64
+		lost         chan struct{}
+		lostOnce     sync.Once
+	}
+)
+
+func newFakeLocker() *fakeLocker {
+	return &fakeLocker{leases: map[string]*fakeLease{}, locked: map[string]bool{}, failFrom: map[string]bool{}}
+}
+
+func (f *fakeLocker) IsLocked(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	locked := f.locked[key] || f.leases[key] != nil
+	f.locked[key] = true
+	return locked
+}
+
+func (f *fakeLocker) HasBeenLocked(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.locked[key] || f.leases[key] != nil
+}
+
+func (f *fakeLocker) Unlock(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.locked, key)
+}
+
+func (f *fakeLocker) Reset(key string) {}
+
+func (f *fakeLocker) AcquireLock(ctx context.Context, key string, opts ...candishared.LockOption) (interfaces.LockLease, error) {
+	opt := candishared.ParseLockOptions(0, opts...)
+	if opt.Token == "" {
+		opt.Token = uuid.NewString()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for instanceID := range f.failFrom {
+		if strings.HasPrefix(opt.Token, instanceID) {
+			return nil, errors.New("connection refused")
+		}
+	}
+	if f.leases[key] != nil || f.locked[key] {
+		return nil, candishared.ErrLockNotAcquired
+	}
+	f.fencing++
+	lease := &fakeLease{locker: f, key: key, token: opt.Token, fencingToken: f.fencing, lost: make(chan struct{})}
+	f.leases[key] = lease
+	return lease, nil
+}
+
+func (f *fakeLocker) GetLockOwner(ctx context.Context, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if lease := f.leases[key]; lease != nil {
+		return lease.token, nil
+	}
+	return "", nil
+}
+
+// expire simulate lease of key is expired (ex: renewal is failed)
+func (f *fakeLocker) expire(key string) {
+	f.mu.Lock()
+	lease := f.leases[key]
+	delete(f.leases, key)
+	f.mu.Unlock()
+	if lease != nil {
+		lease.markLost()
+	}
+}
+
+func (l *fakeLease) Key() string           { return l.key }
+func (l *fakeLease) Token() string         { return l.token }
+func (l *fakeLease) FencingToken() int64   { return l.fencingToken }
+func (l *fakeLease) Lost() <-chan struct{} { return l.lost }
+func (l *fakeLease) Refresh(ctx context.Context) error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+	if l.locker.leases[l.key] != l {
+		return candishared.ErrLockNotOwned
+	}
+	return nil
+}
+func (l *fakeLease) Release(ctx context.Context) error {
+	l.markLost()
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+	if l.locker.leases[l.key] != l {
+		return candishared.ErrLockNotOwned
+	}
+	delete(l.locker.leases, l.key)
+	return nil
+}
+func (l *fakeLease) markLost() { l.lostOnce.Do(func() { close(l.lost) }) }
+
+func TestLeaderElection(t *testing.T) {
+	const key = "test:cron-worker-leader"
+	locker := newFakeLocker()
+	isLeader := func(l *leaderElection) func() bool {
+		return func() bool { _, ok := l.leader(); return ok }
+	}
+
+	var mu sync.Mutex
+	elected := map[string]int{}
+	runElection := func(instanceID string) (*leaderElection, context.CancelFunc, <-chan struct{}) {
+		l := newLeaderElection(locker, key, 30*time.Millisecond)
+		l.instanceID = instanceID
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			l.run(ctx, func() { mu.Lock(); elected[instanceID]++; mu.Unlock() })
+		}()
+		return l, cancel, done
+	}
+
+	leaderA, cancelA, doneA := runElection("instance-a")
+	defer cancelA()
+	require.Eventually(t, isLeader(leaderA), time.Second, 5*time.Millisecond)
+
+	leaderB, cancelB, doneB := runElection("instance-b")
+	defer cancelB()
+
+	t.Run("election", func(t *testing.T) {
+		time.Sleep(50 * time.Millisecond)
+		assert.False(t, isLeader(leaderB)())
+
+		status := leaderA.status(context.Background())
+		assert.True(t, status.Enabled)
+		assert.True(t, status.IsLeader)
+		assert.Equal(t, "instance-a", status.LeaderID)
+		assert.NotNil(t, status.LeaderSince)
+		assert.Equal(t, int64(1), status.FencingToken)
+
+		status = leaderB.status(context.Background())
+		assert.Equal(t, "instance-b", status.InstanceID)
+		assert.False(t, status.IsLeader)
+		assert.Equal(t, "instance-a", status.LeaderID)
+		assert.Nil(t, status.LeaderSince)
+	})
+
+	t.Run("take over after lease lost", func(t *testing.T) {
+		locker.mu.Lock()
+		locker.failFrom["instance-a"] = true
+		locker.mu.Unlock()
+		locker.expire(key)
+
+		require.Eventually(t, isLeader(leaderB), time.Second, 5*time.Millisecond)
+		assert.False(t, isLeader(leaderA)())
+
+		status := leaderA.status(context.Background())
+		assert.False(t, status.IsLeader)
+		assert.Equal(t, "instance-b", status.LeaderID)
+		assert.Equal(t, int64(2), leaderB.status(context.Background()).FencingToken)
+	})
+
+	t.Run("release on shutdown", func(t *testing.T) {
+		cancelB()
+		<-doneB
+		assert.False(t, isLeader(leaderB)())
+		owner, _ := locker.GetLockOwner(context.Background(), key)
+		assert.Empty(t, owner)
+		assert.Empty(t, leaderA.status(context.Background()).LeaderID)
+
+		locker.mu.Lock()
+		delete(locker.failFrom, "instance-a")
+		locker.mu.Unlock()
+		require.Eventually(t, isLeader(leaderA), time.Second, 5*time.Millisecond)
+		cancelA()
+		<-doneA
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[string]int{"instance-a": 2, "instance-b": 1}, elected)
+}
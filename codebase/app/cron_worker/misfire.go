@@ -0,0 +1,116 @@
+package cronworker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/logger"
+	"github.com/golangid/candi/tracer"
+)
+
+const (
+	misfireLockPattern = "%s:cron-worker-misfire:%s"
+	maxMisfireCatchUp  = 100
+)
+
+// recoverMisfire check missed schedule from stored state and run job based on misfire policy
+func (c *cronWorker) recoverMisfire() {
+	if c.opt.store == nil {
+		return
+	}
+
+	for _, job := range c.activeJobs {
+		if c.ctx.Err() != nil {
+			return
+		}
+		c.recoverJobMisfire(job)
+	}
+}
+
+func (c *cronWorker) recoverJobMisfire(job *Job) {
+	// lock for multiple worker (if running on multiple pods/instance), state is updated before lock released
+	lease, err := c.opt.locker.AcquireLock(c.ctx, fmt.Sprintf(misfireLockPattern, c.service.Name(), job.HandlerName),
+		candishared.LockSetTTL(time.Minute), candishared.LockSetAutoRenew(true))
+	if err != nil {
+		return
+	}
+	defer lease.Release(context.WithoutCancel(c.ctx))
+
+	state, err := c.opt.store.GetScheduleState(c.ctx, job.HandlerName)
+	if err != nil || state == nil || state.Interval != job.Interval {
+		// new or changed schedule
+		state = &ScheduleState{JobName: job.HandlerName, Interval: job.Interval}
+	}
+
+	var missed int
+	now := time.Now()
+	for next := state.NextRunAt; !next.IsZero() && next.Before(now) && missed < maxMisfireCatchUp; next = job.next(next) {
+		missed++
+	}
+
+	if missed > 0 {
+		logger.LogYellow(fmt.Sprintf("[CRON-WORKER] job %s missed %d schedule since %s (misfire policy: %s)",
+			job.HandlerName, missed, state.NextRunAt.Format(time.RFC3339), c.opt.misfirePolicy))
+
+		runs := 0
+		switch c.opt.misfirePolicy {
+		case MisfireRunOnce:
+			runs = 1
+		case MisfireCatchUp:
+			runs = missed
+		}
+		for i := 0; i < runs && c.ctx.Err() == nil; i++ {
+			c.wg.Add(1)
+			c.processJob(job, TriggerMisfire)
+			c.wg.Done()
+		}
+		if runs > 0 {
+			// state is saved after job executed
+			return
+		}
+	}
+
+	state.NextRunAt = job.NextRunAt()
+	if err := c.opt.store.SaveScheduleState(c.ctx, state); err != nil {
+		logger.LogRed("cron_worker > save schedule state: " + err.Error())
+	}
+}
+
+// saveRun save schedule state and run history if store is set
+func (c *cronWorker) saveRun(ctx context.Context, job *Job, trigger string, startedAt time.Time, err error) {
+	if c.opt.store == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	history := RunHistory{
+		JobName:    job.HandlerName,
+		Params:     job.Params,
+		Interval:   job.Interval,
+		Trigger:    trigger,
+		Status:     RunStatusSuccess,
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+		TraceID:    tracer.GetTraceID(ctx),
+	}
+	history.Duration = history.FinishedAt.Sub(history.StartedAt)
+	if err != nil {
+		history.Status, history.Error = RunStatusFailure, err.Error()
+	}
+	if err := c.opt.store.SaveRunHistory(ctx, &history); err != nil {
+		logger.LogRed("cron_worker > save run history: " + err.Error())
+	}
+
+	if err := c.opt.store.SaveScheduleState(ctx, &ScheduleState{
+		JobName:    job.HandlerName,
+		Interval:   job.Interval,
+		LastRunAt:  &startedAt,
+		LastStatus: history.Status,
+		LastError:  history.Error,
+		NextRunAt:  job.NextRunAt(),
+	}); err != nil {
+		logger.LogRed("cron_worker > save schedule state: " + err.Error())
+	}
+}
@@ -0,0 +1,104 @@
+package cronworker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golangid/candi/candishared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverJobMisfire(t *testing.T) {
+	tests := []struct {
+		name                    string
+		policy                  MisfirePolicy
+		stateInterval, interval string
+		wantRuns                int
+	}{
+		{name: "skip", policy: MisfireSkip, stateInterval: "1h", interval: "1h", wantRuns: 0},
+		{name: "run once", policy: MisfireRunOnce, stateInterval: "1h", interval: "1h", wantRuns: 1},
+		{name: "catch up", policy: MisfireCatchUp, stateInterval: "1h", interval: "1h", wantRuns: 4},
+		{name: "catch up is limited", policy: MisfireCatchUp, stateInterval: "1m", interval: "1m", wantRuns: maxMisfireCatchUp},
+		{name: "changed schedule", policy: MisfireCatchUp, stateInterval: "1h", interval: "2h", wantRuns: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			c := newTestCronWorker(t, SetStore(store), SetMisfirePolicy(tt.policy))
+			var runs int
+			job := addTestJob(t, c, "job", tt.interval, func(*candishared.EventContext) error { runs++; return nil })
+
+			// 4 schedules is missed with 1 hour interval
+			store.SaveScheduleState(context.Background(), &ScheduleState{
+				JobName: "job", Interval: tt.stateInterval, NextRunAt: time.Now().Add(-210 * time.Minute),
+			})
+			c.recoverJobMisfire(job)
+
+			assert.Equal(t, tt.wantRuns, runs)
+			histories := store.runHistories()
+			assert.Len(t, histories, tt.wantRuns)
+			for _, history := range histories {
+				assert.Equal(t, TriggerMisfire, history.Trigger)
+			}
+
+			state, _ := store.GetScheduleState(context.Background(), "job")
+			require.NotNil(t, state)
+			assert.Equal(t, tt.interval, state.Interval)
+			assert.WithinDuration(t, job.NextRunAt(), state.NextRunAt, 0)
+			assert.False(t, c.opt.locker.HasBeenLocked(c.getLockKey("job")))
+		})
+	}
+
+	t.Run("locked by another instance", func(t *testing.T) {
+		store := newFakeStore()
+		c := newTestCronWorker(t, SetStore(store), SetMisfirePolicy(MisfireRunOnce))
+		var runs int
+		job := addTestJob(t, c, "job", "1h", func(*candishared.EventContext) error { runs++; return nil })
+		store.SaveScheduleState(context.Background(), &ScheduleState{
+			JobName: "job", Interval: "1h", NextRunAt: time.Now().Add(-time.Hour),
+		})
+
+		lease, err := c.opt.locker.AcquireLock(context.Background(), "test:cron-worker-misfire:job")
+		require.NoError(t, err)
+		defer lease.Release(context.Background())
+		c.recoverJobMisfire(job)
+		assert.Equal(t, 0, runs)
+	})
+}
+
+func TestSaveRun(t *testing.T) {
+	store := newFakeStore()
+	c := newTestCronWorker(t, SetStore(store))
+	job := addTestJob(t, c, "job", "1h", func(*candishared.EventContext) error { return nil })
+	job.Params = "params"
+
+	startedAt := time.Now().Add(-time.Second)
+	c.saveRun(context.Background(), job, TriggerSchedule, startedAt, nil)
+	c.saveRun(context.Background(), job, TriggerManual, startedAt, errors.New("failed"))
+
+	histories := store.runHistories()
+	require.Len(t, histories, 2)
+	assert.Equal(t, RunStatusSuccess, histories[0].Status)
+	assert.Equal(t, TriggerSchedule, histories[0].Trigger)
+	assert.Equal(t, "params", histories[0].Params)
+	assert.Equal(t, "1h", histories[0].Interval)
+	assert.GreaterOrEqual(t, histories[0].Duration, time.Second)
+	assert.Equal(t, RunStatusFailure, histories[1].Status)
+	assert.Equal(t, TriggerManual, histories[1].Trigger)
+	assert.Equal(t, "failed", histories[1].Error)
+
+	state, _ := store.GetScheduleState(context.Background(), "job")
+	require.NotNil(t, state)
+	assert.Equal(t, RunStatusFailure, state.LastStatus)
+	assert.Equal(t, "failed", state.LastError)
+	assert.WithinDuration(t, startedAt, *state.LastRunAt, 0)
+	assert.WithinDuration(t, job.NextRunAt(), state.NextRunAt, 0)
+
+	t.Run("without store", func(t *testing.T) {
+		c := newTestCronWorker(t)
+		assert.NotPanics(t, func() { c.saveRun(context.Background(), job, TriggerSchedule, startedAt, nil) })
+	})
+}
@@ -1,6 +1,8 @@
 package cronworker
 
 import (
+	"time"
+
 	"github.com/golangid/candi/candiutils"
 	"github.com/golangid/candi/codebase/factory"
 	"github.com/golangid/candi/codebase/interfaces"
@@ -8,9 +10,14 @@ import (
 
 type (
 	option struct {
-		maxGoroutines int
-		debugMode     bool
-		locker        interfaces.Locker
+		maxGoroutines     int
+		debugMode         bool
+		locker            interfaces.Locker
+		leaderElection    bool
+		leaderElectionTTL time.Duration
+		httpPort          uint16
+		store             Store
+		misfirePolicy     MisfirePolicy
 	}
 
 	// OptionFunc type
@@ -19,10 +26,14 @@ type (
 
 func getDefaultOption(service factory.ServiceFactory) option {
 	opt := option{
-		maxGoroutines: 10,
-		debugMode:     true,
+		maxGoroutines:     10,
+		debugMode:         true,
+		leaderElectionTTL: 15 * time.Second,
+		misfirePolicy:     MisfireSkip,
 	}
-	if redisPool := service.GetDependency().GetRedisPool(); redisPool != nil {
+	if locker := service.GetDependency().GetLocker(); locker != nil {
+		opt.locker = locker
+	} else if redisPool := service.GetDependency().GetRedisPool(); redisPool != nil {
 		opt.locker = candiutils.NewRedisLocker(redisPool.WritePool())
 	} else {
 		opt.locker = &candiutils.NoopLocker{}
@@ -50,3 +61,39 @@ func SetLocker(locker interfaces.Locker) OptionFunc {
 		o.locker = locker
 	}
 }
+
+// SetLeaderElection option func, only elected leader (with lease renewal in locker) run all schedules instead of lock per job tick,
+// another instance take over leadership within leader election TTL if leader is down
+func SetLeaderElection(enable bool) OptionFunc {
+	return func(o *option) {
+		o.leaderElection = enable
+	}
+}
+
+// SetLeaderElectionTTL option func, lease TTL of leader, default is 15 seconds
+func SetLeaderElectionTTL(ttl time.Duration) OptionFunc {
+	return func(o *option) {
+		o.leaderElectionTTL = ttl
+	}
+}
+
+// SetHTTPPort option func, serve cron worker http endpoint (health, list schedules, run histories and trigger job), disabled if port is zero
+func SetHTTPPort(port uint16) OptionFunc {
+	return func(o *option) {
+		o.httpPort = port
+	}
+}
+
+// SetStore option func, persist schedule state (last and next run) and run history (see NewTaskQueueStore)
+func SetStore(store Store) OptionFunc {
+	return func(o *option) {
+		o.store = store
+	}
+}
+
+// SetMisfirePolicy option func, handle schedule missed when no instance is running (store must be set), default is MisfireSkip
+func SetMisfirePolicy(policy MisfirePolicy) OptionFunc {
+	return func(o *option) {
+		o.misfirePolicy = policy
+	}
+}
@@ -0,0 +1,154 @@
+package cronworker
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	taskqueueworker "github.com/golangid/candi/codebase/app/task_queue_worker"
+)
+
+const (
+	// MisfireSkip policy, skip all missed schedule (default)
+	MisfireSkip MisfirePolicy = "skip"
+	// MisfireRunOnce policy, run job once if one or more schedule is missed
+	MisfireRunOnce MisfirePolicy = "run_once"
+	// MisfireCatchUp policy, run job for each missed schedule (max 100 runs)
+	MisfireCatchUp MisfirePolicy = "catch_up"
+
+	// TriggerSchedule job is triggered by schedule
+	TriggerSchedule = "schedule"
+	// TriggerMisfire job is triggered by misfire policy
+	TriggerMisfire = "misfire"
+	// TriggerManual job is triggered manually from http api
+	TriggerManual = "manual"
+
+	// RunStatusSuccess status
+	RunStatusSuccess = string(taskqueueworker.StatusSuccess)
+	// RunStatusFailure status
+	RunStatusFailure = string(taskqueueworker.StatusFailure)
+
+	storeKeyPrefix = "cron_worker:"
+)
+
+type (
+	// MisfirePolicy type, how to handle schedule missed when no instance is running (ex: deploy at scheduled time)
+	MisfirePolicy string
+
+	// Store abstraction of cron worker persistent for schedule state and run history
+	Store interface {
+		GetScheduleState(ctx context.Context, jobName string) (*ScheduleState, error)
+		SaveScheduleState(ctx context.Context, state *ScheduleState) error
+		SaveRunHistory(ctx context.Context, history *RunHistory) error
+		FindRunHistories(ctx context.Context, jobName string, page, limit int) (histories []RunHistory, total int, err error)
+	}
+
+	// ScheduleState model
+	ScheduleState struct {
+		JobName    string     `json:"job_name"`
+		Interval   string     `json:"interval"`
+		LastRunAt  *time.Time `json:"last_run_at,omitempty"`
+		LastStatus string     `json:"last_status,omitempty"`
+		LastError  string     `json:"last_error,omitempty"`
+		NextRunAt  time.Time  `json:"next_run_at"`
+	}
+
+	// RunHistory model
+	RunHistory struct {
+		ID         string        `json:"id"`
+		JobName    string        `json:"job_name"`
+		Params     string        `json:"params"`
+		Interval   string        `json:"interval"`
+		Trigger    string        `json:"trigger"`
+		Status     string        `json:"status"`
+		StartedAt  time.Time     `json:"started_at"`
+		FinishedAt time.Time     `json:"finished_at"`
+		Duration   time.Duration `json:"duration"`
+		Error      string        `json:"error,omitempty"`
+		TraceID    string        `json:"trace_id,omitempty"`
+	}
+
+	// taskQueueStore implementation of Store with task queue worker persistent (SQL or Mongo),
+	// schedule state is saved as configuration and run history is saved as job with task name "cron_worker:{job name}"
+	taskQueueStore struct {
+		persistent taskqueueworker.Persistent
+	}
+
+	runHistoryResult struct {
+		Trigger   string    `json:"trigger"`
+		StartedAt time.Time `json:"started_at"`
+	}
+)
+
+// NewTaskQueueStore create cron worker store from task queue worker persistent (see taskqueueworker.NewSQLPersistent and taskqueueworker.NewMongoPersistent)
+func NewTaskQueueStore(persistent taskqueueworker.Persistent) Store {
+	return &taskQueueStore{persistent: persistent}
+}
+
+func (s *taskQueueStore) GetScheduleState(ctx context.Context, jobName string) (*ScheduleState, error) {
+	cfg, err := s.persistent.GetConfiguration(storeKeyPrefix + jobName)
+	if err != nil {
+		return nil, err
+	}
+	var state ScheduleState
+	if err := json.Unmarshal([]byte(cfg.Value), &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (s *taskQueueStore) SaveScheduleState(ctx context.Context, state *ScheduleState) error {
+	value, _ := json.Marshal(state)
+	return s.persistent.SetConfiguration(&taskqueueworker.Configuration{
+		Key:      storeKeyPrefix + state.JobName,
+		Name:     "Cron Worker Schedule: " + state.JobName,
+		Value:    string(value),
+		IsActive: true,
+	})
+}
+
+func (s *taskQueueStore) SaveRunHistory(ctx context.Context, history *RunHistory) error {
+	result, _ := json.Marshal(runHistoryResult{Trigger: history.Trigger, StartedAt: history.StartedAt})
+	job := taskqueueworker.Job{
+		TaskName:   storeKeyPrefix + history.JobName,
+		Arguments:  history.Params,
+		Interval:   history.Interval,
+		Status:     history.Status,
+		Error:      history.Error,
+		TraceID:    history.TraceID,
+		FinishedAt: history.FinishedAt,
+		Result:     string(result),
+	}
+	if err := s.persistent.SaveJob(ctx, &job); err != nil {
+		return err
+	}
+	history.ID = job.ID
+	return nil
+}
+
+func (s *taskQueueStore) FindRunHistories(ctx context.Context, jobName string, page, limit int) (histories []RunHistory, total int, err error) {
+	filter := &taskqueueworker.Filter{
+		Page: page, Limit: limit, Sort: "-created_at", TaskName: storeKeyPrefix + jobName,
+	}
+	for _, job := range s.persistent.FindAllJob(ctx, filter) {
+		var result runHistoryResult
+		json.Unmarshal([]byte(job.Result), &result)
+		history := RunHistory{
+			ID:         job.ID,
+			JobName:    jobName,
+			Params:     job.Arguments,
+			Interval:   job.Interval,
+			Trigger:    result.Trigger,
+			Status:     job.Status,
+			StartedAt:  result.StartedAt,
+			FinishedAt: job.FinishedAt,
+			Error:      job.Error,
+			TraceID:    job.TraceID,
+		}
+		if !history.StartedAt.IsZero() && !history.FinishedAt.IsZero() {
+			history.Duration = history.FinishedAt.Sub(history.StartedAt)
+		}
+		histories = append(histories, history)
+	}
+	return histories, s.persistent.CountAllJob(ctx, filter), nil
+}
@@ -0,0 +1,426 @@
+package graphqlserver
+
+/*
+	Apollo Federation v2 subgraph support, serve _service { sdl } and _entities query for federation gateway/router
+	https://www.apollographql.com/docs/federation/subgraph-spec
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/codebase/interfaces"
+	"github.com/golangid/candi/tracer"
+	"github.com/golangid/graphql-go"
+	gqlerrors "github.com/golangid/graphql-go/errors"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/formatter"
+)
+
+const (
+	federationSpecURL = "https://specs.apollo.dev/federation/v2.3"
+
+	federationEntitiesField = "_entities"
+	federationServiceField  = "_service"
+)
+
+var (
+	federationEntitiesContextKey candishared.ContextKey = "federationEntities"
+
+	// federationDirectives directives imported from federation spec, declared in schema for parsing only
+	federationDirectives = []string{
+		"@key", "@external", "@requires", "@provides", "@shareable",
+		"@inaccessible", "@override", "@tag", "@extends",
+	}
+	federationDefinitions = `
+scalar _FieldSet
+directive @key(fields: _FieldSet!, resolvable: Boolean = true) repeatable on OBJECT | INTERFACE
+directive @external on OBJECT | FIELD_DEFINITION
+directive @requires(fields: _FieldSet!) on FIELD_DEFINITION
+directive @provides(fields: _FieldSet!) on FIELD_DEFINITION
+directive @shareable repeatable on OBJECT | FIELD_DEFINITION
+directive @inaccessible on FIELD_DEFINITION | OBJECT | INTERFACE | UNION | ARGUMENT_DEFINITION | SCALAR | ENUM | ENUM_VALUE | INPUT_OBJECT | INPUT_FIELD_DEFINITION
+directive @override(from: String!) on FIELD_DEFINITION
+directive @tag(name: String!) repeatable on FIELD_DEFINITION | OBJECT | INTERFACE | UNION | ARGUMENT_DEFINITION | SCALAR | ENUM | ENUM_VALUE | INPUT_OBJECT | INPUT_FIELD_DEFINITION
+directive @extends on OBJECT | INTERFACE
+`
+
+	// schemaDefinitionRegex match schema definition and schema extension (operation types is optional in extension)
+	schemaDefinitionRegex = regexp.MustCompile(`(^|[^\w])(extend\s+schema(\s*@\w+(\([^)]*\))?)*(\s*\{[^}]*\})?|schema(\s*@\w+(\([^)]*\))?)*\s*\{[^}]*\})`)
+)
+
+type entityResolver[T any] struct {
+	typeName    string
+	resolveFunc func(ctx context.Context, representations []map[string]any) ([]T, error)
+}
+
+// NewEntityResolver construct federation entity resolver for type with @key directive, T is resolver type (pointer) of entity type
+// and resolveFunc must return entities in same order with representations (nil value if entity not found)
+func NewEntityResolver[T any](typeName string, resolveFunc func(ctx context.Context, representations []map[string]any) ([]T, error)) interfaces.GraphQLEntityResolver {
+	return &entityResolver[T]{typeName: typeName, resolveFunc: resolveFunc}
+}
+
+func (r *entityResolver[T]) EntityTypeName() string {
+	return r.typeName
+}
+
+func (r *entityResolver[T]) EntityQueryResolver() any {
+	return &entityQuery[T]{}
+}
+
+func (r *entityResolver[T]) ResolveEntities(ctx context.Context, representations []map[string]any) (any, error) {
+	entities, err := r.resolveFunc(ctx, representations)
+	if err != nil {
+		return nil, err
+	}
+	if len(entities) != len(representations) {
+		return nil, fmt.Errorf("entity resolver %s: got %d entities for %d representations", r.typeName, len(entities), len(representations))
+	}
+	return entities, nil
+}
+
+// entityQuery root query resolver for resolve _entities field in entity schema
+type entityQuery[T any] struct{}
+
+func (entityQuery[T]) Entities(ctx context.Context) []T {
+	entities, _ := candishared.GetValueFromContext(ctx, federationEntitiesContextKey).([]T)
+	return entities
+}
+
+type federation struct {
+	sdl      string
+	entities map[string]*federationEntity
+}
+
+type federationEntity struct {
+	resolver interfaces.GraphQLEntityResolver
+	schema   *graphql.Schema
+}
+
+// newFederation build federation handler, schemaSource is full schema with federation definitions and sdl is schema served to gateway
+func newFederation(schemaSource, sdl string, resolvers []interfaces.GraphQLEntityResolver, schemaOpts ...graphql.SchemaOpt) (*federation, error) {
+	f := &federation{
+		sdl: strings.TrimSpace(sdl) + "\n\nextend schema @link(url: \"" + federationSpecURL + "\", import: [\"" +
+			strings.Join(federationDirectives, "\", \"") + "\"])\n",
+		entities: make(map[string]*federationEntity),
+	}
+
+	// each entity type is resolved with internal schema with _entities field as root query
+	baseSchema := schemaDefinitionRegex.ReplaceAllString(schemaSource, "$1")
+	for _, resolver := range resolvers {
+		typeName := resolver.EntityTypeName()
+		if _, ok := f.entities[typeName]; ok {
+			return nil, fmt.Errorf("federation: duplicate entity resolver for type %s", typeName)
+		}
+		entitySchema, err := graphql.ParseSchema(baseSchema+"\nschema { query: _EntityQuery }\ntype _EntityQuery { _entities: ["+typeName+"]! }\n",
+			resolver.EntityQueryResolver(), schemaOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("federation: entity %s: %w", typeName, err)
+		}
+		f.entities[typeName] = &federationEntity{resolver: resolver, schema: entitySchema}
+	}
+	return f, nil
+}
+
+// exec handle federation query (_service and _entities), return nil if query is not federation query
+func (f *federation) exec(ctx context.Context, query, operationName string, variables map[string]any) *graphql.Response {
+	doc, err := parseQuery(query)
+	if err != nil {
+		return nil
+	}
+	op := doc.Operations.ForName(operationName)
+	if op == nil || op.Operation != ast.Query || !isFederationOperation(op) {
+		return nil
+	}
+
+	var data bytes.Buffer
+	var errs []*gqlerrors.QueryError
+	data.WriteByte('{')
+	for i, sel := range op.SelectionSet {
+		field := sel.(*ast.Field)
+		if i > 0 {
+			data.WriteByte(',')
+		}
+		keyJSON, _ := json.Marshal(field.Alias)
+		data.Write(keyJSON)
+		data.WriteByte(':')
+
+		switch field.Name {
+		case "__typename":
+			data.WriteString(`"Query"`)
+
+		case federationServiceField:
+			data.Write(f.serviceData(field))
+
+		case federationEntitiesField:
+			entities, entityErrs := f.resolveEntities(ctx, doc, op, field, variables)
+			data.Write(entities)
+			errs = append(errs, entityErrs...)
+		}
+	}
+	data.WriteByte('}')
+
+	return &graphql.Response{Data: data.Bytes(), Errors: errs}
+}
+
+// isFederationOperation check if root selections of operation is federation field (_service and _entities) and __typename only
+func isFederationOperation(op *ast.OperationDefinition) bool {
+	isFederation := false
+	for _, sel := range op.SelectionSet {
+		field, ok := sel.(*ast.Field)
+		if !ok {
+			return false
+		}
+		switch field.Name {
+		case federationServiceField, federationEntitiesField:
+			isFederation = true
+		case "__typename":
+		default:
+			return false
+		}
+	}
+	return isFederation
+}
+
+func (f *federation) serviceData(field *ast.Field) json.RawMessage {
+	service := make([]string, 0, len(field.SelectionSet))
+	for _, sel := range field.SelectionSet {
+		serviceField, ok := sel.(*ast.Field)
+		if !ok {
+			continue
+		}
+		keyJSON, _ := json.Marshal(serviceField.Alias)
+		var value []byte
+		switch serviceField.Name {
+		case "sdl":
+			value, _ = json.Marshal(f.sdl)
+		case "__typename":
+			value = []byte(`"_Service"`)
+		default:
+			continue
+		}
+		service = append(service, string(keyJSON)+":"+string(value))
+	}
+	return json.RawMessage("{" + strings.Join(service, ",") + "}")
+}
+
+// resolveEntities group representations by __typename, resolve each entity type with entity schema
+// and merge the result in same order with representations
+func (f *federation) resolveEntities(ctx context.Context, doc *ast.QueryDocument, op *ast.OperationDefinition,
+	field *ast.Field, variables map[string]any) (json.RawMessage, []*gqlerrors.QueryError) {
+
+	key := field.Alias
+	arg := field.Arguments.ForName("representations")
+	if arg == nil || arg.Value.Kind != ast.Variable {
+		return []byte("null"), []*gqlerrors.QueryError{{Message: "argument representations must be a variable", Path: []any{key}}}
+	}
+	values, _ := variables[arg.Value.Raw].([]any)
+
+	representations := make([]map[string]any, len(values))
+	groups := make(map[string][]int)
+	for i, value := range values {
+		representation, _ := value.(map[string]any)
+		typeName, _ := representation["__typename"].(string)
+		representations[i] = representation
+		groups[typeName] = append(groups[typeName], i)
+	}
+
+	trace, ctx := tracer.StartTraceWithContext(ctx, "GraphQLFederation:ResolveEntities")
+	trace.SetTag("representations", len(values))
+
+	results := make([]json.RawMessage, len(values))
+	var errs []*gqlerrors.QueryError
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for typeName, indexes := range groups {
+		wg.Add(1)
+		go func(typeName string, indexes []int) {
+			defer wg.Done()
+			items, typeErrs := f.resolveEntityType(ctx, doc, op, field, typeName, indexes, representations, variables)
+
+			mu.Lock()
+			defer mu.Unlock()
+			for i, idx := range indexes {
+				if i < len(items) {
+					results[idx] = items[i]
+				}
+			}
+			for _, err := range typeErrs {
+				// rewrite error path to index in original representations
+				if len(err.Path) > 1 {
+					if i, ok := err.Path[1].(int); ok && i < len(indexes) {
+						err.Path[1] = indexes[i]
+					}
+				}
+				if len(err.Path) > 0 {
+					err.Path[0] = key
+				}
+				errs = append(errs, err)
+			}
+		}(typeName, indexes)
+	}
+	wg.Wait()
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errorPathIndex(errs[i]) < errorPathIndex(errs[j])
+	})
+	var finishErr error
+	if len(errs) > 0 {
+		finishErr = errs[0]
+	}
+	trace.Finish(tracer.FinishWithError(finishErr))
+
+	var data bytes.Buffer
+	data.WriteByte('[')
+	for i, item := range results {
+		if i > 0 {
+			data.WriteByte(',')
+		}
+		if len(item) == 0 {
+			item = []byte("null")
+		}
+		data.Write(item)
+	}
+	data.WriteByte(']')
+	return data.Bytes(), errs
+}
+
+func (f *federation) resolveEntityType(ctx context.Context, doc *ast.QueryDocument, op *ast.OperationDefinition, field *ast.Field,
+	typeName string, indexes []int, representations []map[string]any, variables map[string]any) ([]json.RawMessage, []*gqlerrors.QueryError) {
+
+	indexErrors := func(message string, err error) (errs []*gqlerrors.QueryError) {
+		for i := range indexes {
+			errs = append(errs, &gqlerrors.QueryError{Err: err, Message: message, Path: []any{federationEntitiesField, i}})
+		}
+		return errs
+	}
+
+	entity, ok := f.entities[typeName]
+	if !ok {
+		return nil, indexErrors(fmt.Sprintf("entity type %q is not resolvable in this subgraph", typeName), nil)
+	}
+
+	typeRepresentations := make([]map[string]any, len(indexes))
+	for i, idx := range indexes {
+		typeRepresentations[i] = representations[idx]
+	}
+	entities, err := entity.resolver.ResolveEntities(ctx, typeRepresentations)
+	if err != nil {
+		return nil, indexErrors(err.Error(), err)
+	}
+
+	ctx = candishared.SetToContext(ctx, federationEntitiesContextKey, entities)
+	response := entity.schema.Exec(ctx, buildEntityQuery(doc, op, field, typeName), "", variables)
+	for _, err := range response.Errors {
+		err.Locations = nil
+	}
+
+	var data struct {
+		Entities []json.RawMessage `json:"_entities"`
+	}
+	json.Unmarshal(response.Data, &data)
+	return data.Entities, response.Errors
+}
+
+// buildEntityQuery build query for entity schema from _entities selection, only include selections applicable to entity type
+func buildEntityQuery(doc *ast.QueryDocument, op *ast.OperationDefinition, field *ast.Field, typeName string) string {
+	var selections ast.SelectionSet
+	for _, sel := range field.SelectionSet {
+		switch s := sel.(type) {
+		case *ast.FragmentSpread:
+			if fragment := doc.Fragments.ForName(s.Name); fragment == nil || fragment.TypeCondition != typeName {
+				continue
+			}
+		case *ast.InlineFragment:
+			if s.TypeCondition != "" && s.TypeCondition != typeName {
+				continue
+			}
+		}
+		selections = append(selections, sel)
+	}
+	if len(selections) == 0 {
+		selections = append(selections, &ast.Field{Alias: "__typename", Name: "__typename"})
+	}
+
+	// collect fragment definitions (transitive) and variables used in selections
+	entityQuery := &ast.QueryDocument{}
+	usedFragments := make(map[string]bool)
+	usedVariables := make(map[string]bool)
+	var collect func(selections ast.SelectionSet)
+	collect = func(selections ast.SelectionSet) {
+		for _, sel := range selections {
+			switch s := sel.(type) {
+			case *ast.Field:
+				collectVariables(usedVariables, s.Arguments, s.Directives)
+				collect(s.SelectionSet)
+			case *ast.InlineFragment:
+				collectVariables(usedVariables, nil, s.Directives)
+				collect(s.SelectionSet)
+			case *ast.FragmentSpread:
+				collectVariables(usedVariables, nil, s.Directives)
+				if fragment := doc.Fragments.ForName(s.Name); fragment != nil && !usedFragments[s.Name] {
+					usedFragments[s.Name] = true
+					entityQuery.Fragments = append(entityQuery.Fragments, fragment)
+					collectVariables(usedVariables, nil, fragment.Directives)
+					collect(fragment.SelectionSet)
+				}
+			}
+		}
+	}
+	collect(selections)
+
+	entityOperation := &ast.OperationDefinition{
+		Operation:    ast.Query,
+		SelectionSet: ast.SelectionSet{&ast.Field{Alias: federationEntitiesField, Name: federationEntitiesField, SelectionSet: selections}},
+	}
+	for _, v := range op.VariableDefinitions {
+		if usedVariables[v.Variable] {
+			entityOperation.VariableDefinitions = append(entityOperation.VariableDefinitions, v)
+		}
+	}
+	entityQuery.Operations = ast.OperationList{entityOperation}
+
+	var query strings.Builder
+	formatter.NewFormatter(&query, formatter.WithCompacted()).FormatQueryDocument(entityQuery)
+	return query.String()
+}
+
+// collectVariables collect variable name used in arguments and directive arguments
+func collectVariables(usedVariables map[string]bool, arguments ast.ArgumentList, directives ast.DirectiveList) {
+	var collectValue func(value *ast.Value)
+	collectValue = func(value *ast.Value) {
+		if value == nil {
+			return
+		}
+		if value.Kind == ast.Variable {
+			usedVariables[value.Raw] = true
+		}
+		for _, child := range value.Children {
+			collectValue(child.Value)
+		}
+	}
+	for _, arg := range arguments {
+		collectValue(arg.Value)
+	}
+	for _, directive := range directives {
+		for _, arg := range directive.Arguments {
+			collectValue(arg.Value)
+		}
+	}
+}
+
+func errorPathIndex(err *gqlerrors.QueryError) int {
+	if len(err.Path) > 1 {
+		if i, ok := err.Path[1].(int); ok {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,186 @@
+package graphqlserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golangid/candi/codebase/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const federationTestSchema = `
+schema { query: Query }
+type Query { me: User }
+type User @key(fields: "id") {
+	id: String!
+	name: String!
+}
+type Product @key(fields: "upc") {
+	upc: String!
+	price(currency: String): String!
+}
+`
+
+type federationTestUser struct{ id string }
+
+func (u *federationTestUser) ID() string   { return u.id }
+func (u *federationTestUser) Name() string { return "user " + u.id }
+
+type federationTestProduct struct{ upc string }
+
+func (p *federationTestProduct) Upc() string { return p.upc }
+func (p *federationTestProduct) Price(args struct{ Currency *string }) string {
+	if args.Currency != nil {
+		return "10 " + *args.Currency
+	}
+	return "10"
+}
+
+type federationTestCalls struct {
+	mu    sync.Mutex
+	calls map[string][]int
+}
+
+func (c *federationTestCalls) add(typeName string, n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls[typeName] = append(c.calls[typeName], n)
+}
+
+func newTestFederation(t *testing.T) (*federation, *federationTestCalls) {
+	calls := &federationTestCalls{calls: make(map[string][]int)}
+	resolvers := []interfaces.GraphQLEntityResolver{
+		NewEntityResolver("User", func(ctx context.Context, representations []map[string]any) ([]*federationTestUser, error) {
+			calls.add("User", len(representations))
+			users := make([]*federationTestUser, len(representations))
+			for i, rep := range representations {
+				if id, _ := rep["id"].(string); id != "0" {
+					users[i] = &federationTestUser{id: id}
+				}
+			}
+			return users, nil
+		}),
+		NewEntityResolver("Product", func(ctx context.Context, representations []map[string]any) ([]*federationTestProduct, error) {
+			calls.add("Product", len(representations))
+			products := make([]*federationTestProduct, len(representations))
+			for i, rep := range representations {
+				products[i] = &federationTestProduct{upc: rep["upc"].(string)}
+			}
+			return products, nil
+		}),
+	}
+	f, err := newFederation(federationTestSchema+federationDefinitions, federationTestSchema, resolvers)
+	require.NoError(t, err)
+	return f, calls
+}
+
+func TestSchemaDefinitionRegex(t *testing.T) {
+	tests := []struct {
+		name, schema, want string
+	}{
+		{name: "schema definition", schema: "schema { query: Query }\ntype Query { a: Int }", want: "\ntype Query { a: Int }"},
+		{name: "schema with directive", schema: "schema @a(b: \"c\") { query: Query }\ntype Query { a: Int }", want: "\ntype Query { a: Int }"},
+		{name: "extend schema", schema: "schema { query: Query }\nextend schema { mutation: Mutation }\ntype Query { a: Int }",
+			want: "\n\ntype Query { a: Int }"},
+		{name: "extend schema without operation types", schema: "extend schema @link(url: \"x\", import: [\"@key\"])\ntype User @key(fields: \"id\") { id: ID! }",
+			want: "\ntype User @key(fields: \"id\") { id: ID! }"},
+		{name: "schema field", schema: "type Query { schema: String }", want: "type Query { schema: String }"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, schemaDefinitionRegex.ReplaceAllString(tt.schema, "$1"))
+		})
+	}
+}
+
+func TestFederationService(t *testing.T) {
+	f, _ := newTestFederation(t)
+
+	response := f.exec(context.Background(), `query { __typename s: _service { sdl __typename } }`, "", nil)
+	require.NotNil(t, response)
+	assert.Empty(t, response.Errors)
+
+	var data struct {
+		Typename string `json:"__typename"`
+		Service  struct {
+			SDL      string `json:"sdl"`
+			Typename string `json:"__typename"`
+		} `json:"s"`
+	}
+	require.NoError(t, json.Unmarshal(response.Data, &data))
+	assert.Equal(t, "Query", data.Typename)
+	assert.Equal(t, "_Service", data.Service.Typename)
+	assert.True(t, strings.HasPrefix(data.Service.SDL, strings.TrimSpace(federationTestSchema)))
+	assert.Contains(t, data.Service.SDL, `extend schema @link(url: "`+federationSpecURL+`", import: ["@key"`)
+	assert.NotContains(t, data.Service.SDL, "directive @key")
+}
+
+func TestFederationEntities(t *testing.T) {
+	f, calls := newTestFederation(t)
+
+	query := `query ($representations: [_Any!]!, $currency: String) {
+		_entities(representations: $representations) {
+			__typename
+			... on User { id name }
+			...ProductFields
+		}
+	}
+	fragment ProductFields on Product { upc price(currency: $currency) }`
+	variables := map[string]any{
+		"currency": "IDR",
+		"representations": []any{
+			map[string]any{"__typename": "User", "id": "1"},
+			map[string]any{"__typename": "Product", "upc": "p1"},
+			map[string]any{"__typename": "User", "id": "2"},
+			map[string]any{"__typename": "Unknown", "id": "3"},
+			map[string]any{"__typename": "User", "id": "0"},
+		},
+	}
+
+	response := f.exec(context.Background(), query, "", variables)
+	require.NotNil(t, response)
+	assert.JSONEq(t, `{"_entities": [
+		{"__typename": "User", "id": "1", "name": "user 1"},
+		{"__typename": "Product", "upc": "p1", "price": "10 IDR"},
+		{"__typename": "User", "id": "2", "name": "user 2"},
+		null,
+		null
+	]}`, string(response.Data))
+
+	// representations is batched per entity type
+	assert.Equal(t, map[string][]int{"User": {3}, "Product": {1}}, calls.calls)
+
+	require.Len(t, response.Errors, 1)
+	assert.Equal(t, []any{"_entities", 3}, response.Errors[0].Path)
+	assert.Contains(t, response.Errors[0].Message, `"Unknown"`)
+}
+
+func TestFederationEntitiesResolverError(t *testing.T) {
+	f, err := newFederation(federationTestSchema+federationDefinitions, federationTestSchema, []interfaces.GraphQLEntityResolver{
+		NewEntityResolver("User", func(ctx context.Context, representations []map[string]any) ([]*federationTestUser, error) {
+			return nil, errors.New("database error")
+		}),
+	})
+	require.NoError(t, err)
+
+	response := f.exec(context.Background(), `query ($r: [_Any!]!) { _entities(representations: $r) { ... on User { id } } }`, "",
+		map[string]any{"r": []any{map[string]any{"__typename": "User", "id": "1"}, map[string]any{"__typename": "User", "id": "2"}}})
+	require.NotNil(t, response)
+	assert.JSONEq(t, `{"_entities": [null, null]}`, string(response.Data))
+	require.Len(t, response.Errors, 2)
+	assert.Equal(t, []any{"_entities", 0}, response.Errors[0].Path)
+	assert.Equal(t, []any{"_entities", 1}, response.Errors[1].Path)
+	assert.Equal(t, "database error", response.Errors[0].Message)
+}
+
+func TestFederationExecSkipNonFederationQuery(t *testing.T) {
+	f, _ := newTestFederation(t)
+	assert.Nil(t, f.exec(context.Background(), `{ me { id } }`, "", nil))
+	assert.Nil(t, f.exec(context.Background(), `{ me { id } _service { sdl } }`, "", nil))
+	assert.Nil(t, f.exec(context.Background(), `mutation { _service { sdl } }`, "", nil))
+}
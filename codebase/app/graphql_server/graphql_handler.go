@@ -16,11 +16,15 @@ import (
 
 	"github.com/golangid/candi/candihelper"
 	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/candiutils"
 	"github.com/golangid/candi/codebase/app/graphql_server/ws"
 	"github.com/golangid/candi/codebase/factory"
+	"github.com/golangid/candi/codebase/interfaces"
 	"github.com/golangid/candi/logger"
 	"github.com/golangid/candi/tracer"
+	"github.com/golangid/candi/wrapper"
 	"github.com/golangid/graphql-go"
+	gqlerrors "github.com/golangid/graphql-go/errors"
 	gqltypes "github.com/golangid/graphql-go/types"
 )
 
@@ -31,12 +35,18 @@ type Handler interface {
 	ServeVoyager(resp http.ResponseWriter, req *http.Request)
 }
 
+// PersistedQueryHandler optional interface for Handler, serve endpoint for register persisted query manifest
+type PersistedQueryHandler interface {
+	ServePersistedQueries(resp http.ResponseWriter, req *http.Request)
+}
+
 // ConstructHandlerFromService for create public graphql handler (maybe inject to rest handler)
 func ConstructHandlerFromService(service factory.ServiceFactory, opt Option) Handler {
 	if len(opt.schemaSource) == 0 {
 		opt.schemaSource = candihelper.LoadAllFile(os.Getenv(candihelper.WORKDIR)+"api/graphql", ".graphql")
 	}
 	var resolver rootResolver
+	var entityResolvers []interfaces.GraphQLEntityResolver
 
 	if opt.rootResolver == nil {
 		// create dynamic struct
@@ -60,6 +70,9 @@ func ConstructHandlerFromService(service factory.ServiceFactory, opt Option) Han
 				if schema := resolverModule.Schema(); schema != "" {
 					opt.schemaSource = append(opt.schemaSource, schema+"\n"...)
 				}
+				if entityHandler, ok := resolverModule.(interfaces.GraphQLEntityHandler); ok {
+					entityResolvers = append(entityResolvers, entityHandler.EntityResolvers()...)
+				}
 			}
 		}
 		resolver = rootResolver{
@@ -74,6 +87,18 @@ func ConstructHandlerFromService(service factory.ServiceFactory, opt Option) Han
 			rootMutation:     opt.rootResolver.Mutation(),
 			rootSubscription: opt.rootResolver.Subscription(),
 		}
+		if entityHandler, ok := opt.rootResolver.(interfaces.GraphQLEntityHandler); ok {
+			entityResolvers = entityHandler.EntityResolvers()
+		}
+	}
+
+	if !strings.Contains(string(opt.schemaSource), "directive @"+CostDirective) {
+		opt.schemaSource = append(opt.schemaSource, costDirectiveDefinition...)
+	}
+	federationSDL := string(opt.schemaSource)
+	opt.enableFederation = opt.enableFederation || len(entityResolvers) > 0
+	if opt.enableFederation {
+		opt.schemaSource = append(opt.schemaSource, federationDefinitions...)
 	}
 
 	// default directive
@@ -96,50 +121,104 @@ func ConstructHandlerFromService(service factory.ServiceFactory, opt Option) Han
 		// handling vulnerabilities exploit schema
 		schemaOpts = append(schemaOpts, graphql.DisableIntrospection())
 	}
+	if opt.queryLimit.MaxDepth > 0 {
+		schemaOpts = append(schemaOpts, graphql.MaxDepth(opt.queryLimit.MaxDepth))
+	}
 
 	logger.LogYellow(fmt.Sprintf("[GraphQL] endpoint\t\t\t: http://127.0.0.1:%d%s", opt.httpPort, opt.RootPath))
 	logger.LogYellow(fmt.Sprintf("[GraphQL] playground\t\t\t: http://127.0.0.1:%d%s/playground", opt.httpPort, opt.RootPath))
 	logger.LogYellow(fmt.Sprintf("[GraphQL] voyager\t\t\t: http://127.0.0.1:%d%s/voyager", opt.httpPort, opt.RootPath))
 
-	return &handlerImpl{
-		schema: graphql.MustParseSchema(string(opt.schemaSource), &resolver, schemaOpts...),
-		option: opt,
+	handler := newHandler(graphql.MustParseSchema(string(opt.schemaSource), &resolver, schemaOpts...), opt)
+	if opt.enableFederation {
+		fed, err := newFederation(string(opt.schemaSource), federationSDL, entityResolvers, schemaOpts...)
+		if err != nil {
+			panic(err)
+		}
+		handler.federation = fed
+		logger.LogYellow(fmt.Sprintf("[GraphQL] federation			: enabled with %d entity resolver(s)", len(entityResolvers)))
 	}
+	return handler
 }
 
 type handlerImpl struct {
-	schema *graphql.Schema
-	option Option
+	schema       *graphql.Schema
+	option       Option
+	queryLimiter *queryLimiter
+	federation   *federation
 }
 
 // NewHandler init new graphql http handler
 func NewHandler(schema *graphql.Schema, opt Option) Handler {
+	return newHandler(schema, opt)
+}
+
+func newHandler(schema *graphql.Schema, opt Option) *handlerImpl {
+	registered, err := initPersistedQuery(context.Background(), &opt)
+	if err != nil {
+		panic(err)
+	}
+	if registered > 0 {
+		logger.LogYellow(fmt.Sprintf("[GraphQL] persisted query\t\t: %d operation(s) registered from manifest", registered))
+	}
+
 	return &handlerImpl{
-		schema: schema,
-		option: opt,
+		schema:       schema,
+		option:       opt,
+		queryLimiter: newQueryLimiter(opt.queryLimit, schema),
 	}
 }
 
 func (s *handlerImpl) ServeGraphQL() http.HandlerFunc {
-	return ws.NewHandlerFunc(s.schema, http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+	return ws.NewHandlerFunc(s, http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 		var params struct {
 			Query         string         `json:"query"`
 			OperationName string         `json:"operationName"`
 			Variables     map[string]any `json:"variables"`
+			Extensions    struct {
+				PersistedQuery *PersistedQueryExtension `json:"persistedQuery"`
+			} `json:"extensions"`
 		}
-		body, err := io.ReadAll(req.Body)
-		if err != nil {
-			http.Error(resp, err.Error(), http.StatusBadRequest)
-			return
-		}
-		if err := json.Unmarshal(body, &params); err != nil {
-			params.Query = string(body)
+		if req.Method == http.MethodGet {
+			urlQuery := req.URL.Query()
+			params.Query, params.OperationName = urlQuery.Get("query"), urlQuery.Get("operationName")
+			if variables := urlQuery.Get("variables"); variables != "" {
+				json.Unmarshal([]byte(variables), &params.Variables)
+			}
+			if extensions := urlQuery.Get("extensions"); extensions != "" {
+				json.Unmarshal([]byte(extensions), &params.Extensions)
+			}
+		} else {
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				http.Error(resp, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := json.Unmarshal(body, &params); err != nil {
+				params.Query = string(body)
+			}
 		}
 
 		req.Header.Set(candihelper.HeaderXRealIP, extractRealIPHeader(req))
 
 		ctx := context.WithValue(req.Context(), candishared.ContextKeyHTTPHeader, req.Header)
-		response := s.schema.Exec(ctx, params.Query, params.OperationName, params.Variables)
+		ctx = candiutils.WithDataLoaderRegistry(ctx)
+		var response *graphql.Response
+		query, queryErr := s.resolvePersistedQuery(ctx, params.Query, params.Extensions.PersistedQuery)
+		switch {
+		case queryErr != nil:
+			response = &graphql.Response{Errors: []*gqlerrors.QueryError{queryErr}}
+		case req.Method == http.MethodGet && isMutationQuery(query, params.OperationName):
+			response = &graphql.Response{Errors: []*gqlerrors.QueryError{{Message: "mutation operation is not allowed in GET request"}}}
+		default:
+			response = s.checkQueryLimit(ctx, query, params.OperationName, params.Variables)
+		}
+		if response == nil && s.federation != nil {
+			response = s.federation.exec(ctx, query, params.OperationName, params.Variables)
+		}
+		if response == nil {
+			response = s.schema.Exec(ctx, query, params.OperationName, params.Variables)
+		}
 		responseJSON, err := json.Marshal(response)
 		if err != nil {
 			http.Error(resp, err.Error(), http.StatusInternalServerError)
@@ -148,7 +227,65 @@ func (s *handlerImpl) ServeGraphQL() http.HandlerFunc {
 
 		resp.Header().Set(candihelper.HeaderContentType, candihelper.HeaderMIMEApplicationJSON)
 		resp.Write(responseJSON)
-	}))
+	}), ws.MaxSubscriptions(s.option.maxSubscriptionsPerConnection))
+}
+
+// Subscribe implement ws.GraphQLService, check persisted query allow-list and query limit before subscribe
+func (s *handlerImpl) Subscribe(ctx context.Context, document string, operationName string, variableValues map[string]any) (<-chan any, error) {
+	response := s.checkQueryLimit(ctx, document, operationName, variableValues)
+	if _, queryErr := s.resolvePersistedQuery(ctx, document, nil); queryErr != nil {
+		response = &graphql.Response{Errors: []*gqlerrors.QueryError{queryErr}}
+	}
+	if response != nil {
+		c := make(chan any, 1)
+		c <- response
+		close(c)
+		return c, nil
+	}
+	return s.schema.Subscribe(ctx, document, operationName, variableValues)
+}
+
+// checkQueryLimit analyze query before execution, return response with errors if query exceed the limit
+func (s *handlerImpl) checkQueryLimit(ctx context.Context, query, operationName string, variables map[string]any) *graphql.Response {
+	metrics, errs := s.queryLimiter.check(query, operationName, variables)
+	if len(errs) == 0 {
+		return nil
+	}
+
+	gqlTracer := &graphqlTracer{opt: &s.option}
+	ctx, finish := gqlTracer.TraceQuery(ctx, query, operationName, variables, nil)
+	tracer.Log(ctx, "graphql.query_metrics", metrics)
+	finish(nil, errs)
+	return &graphql.Response{Errors: errs}
+}
+
+// ServePersistedQueries register persisted query manifest to persisted query store, used for allow-list
+func (s *handlerImpl) ServePersistedQueries(resp http.ResponseWriter, req *http.Request) {
+	if s.option.persistedQueryStore == nil {
+		wrapper.NewHTTPResponse(http.StatusNotFound, "Persisted query is not enabled").JSON(resp)
+		return
+	}
+	if req.Method != http.MethodPost {
+		wrapper.NewHTTPResponse(http.StatusMethodNotAllowed, "Method not allowed").JSON(resp)
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		wrapper.NewHTTPResponse(http.StatusBadRequest, err.Error()).JSON(resp)
+		return
+	}
+	manifest, err := ParsePersistedQueryManifest(body)
+	if err != nil {
+		wrapper.NewHTTPResponse(http.StatusBadRequest, err.Error()).JSON(resp)
+		return
+	}
+	registered, err := RegisterPersistedQueries(req.Context(), s.option.persistedQueryStore, manifest)
+	if err != nil {
+		wrapper.NewHTTPResponse(http.StatusBadRequest, err.Error()).JSON(resp)
+		return
+	}
+	wrapper.NewHTTPResponse(http.StatusOK, "Success", map[string]int{"registered": registered}).JSON(resp)
 }
 
 func (s *handlerImpl) ServePlayground(resp http.ResponseWriter, req *http.Request) {
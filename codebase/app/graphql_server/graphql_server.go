@@ -40,6 +40,9 @@ func NewServer(service factory.ServiceFactory, opts ...OptionFunc) factory.AppSe
 	mux.HandleFunc(server.opt.RootPath, httpHandler.ServeGraphQL())
 	mux.HandleFunc(server.opt.RootPath+"/playground", httpHandler.ServePlayground)
 	mux.HandleFunc(server.opt.RootPath+"/voyager", httpHandler.ServeVoyager)
+	if pqHandler, ok := httpHandler.(PersistedQueryHandler); ok {
+		mux.Handle(server.opt.RootPath+"/persisted-queries", service.GetDependency().GetMiddleware().HTTPBasicAuth(http.HandlerFunc(pqHandler.ServePersistedQueries)))
+	}
 
 	httpEngine.Addr = fmt.Sprintf(":%d", server.opt.httpPort)
 	httpEngine.Handler = mux
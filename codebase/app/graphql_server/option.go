@@ -28,6 +28,14 @@ type (
 		tlsConfig      *tls.Config
 		schemaSource   []byte
 		onErrorWrapper func(context.Context, error) error
+		queryLimit     QueryLimit
+
+		persistedQueryStore     PersistedQueryStore
+		persistedQueryAllowList bool
+		persistedQueryManifest  []byte
+
+		maxSubscriptionsPerConnection int
+		enableFederation              bool
 	}
 
 	// OptionFunc type
@@ -139,3 +147,72 @@ func SetOnErrorWrapper(errFunc func(context.Context, error) error) OptionFunc {
 		o.onErrorWrapper = errFunc
 	}
 }
+
+// SetMaxQueryDepth option func, reject query with field nesting deeper than maxDepth before execution
+func SetMaxQueryDepth(maxDepth int) OptionFunc {
+	return func(o *Option) {
+		o.queryLimit.MaxDepth = maxDepth
+	}
+}
+
+// SetMaxQueryComplexity option func, reject query with total field complexity greater than maxComplexity before execution,
+// default field complexity is 1 and can be changed with @cost(complexity: Int!, multipliers: [String!]) directive in schema field
+func SetMaxQueryComplexity(maxComplexity int) OptionFunc {
+	return func(o *Option) {
+		o.queryLimit.MaxComplexity = maxComplexity
+	}
+}
+
+// SetMaxQueryAliases option func, reject query with number of field aliases greater than maxAliases before execution
+func SetMaxQueryAliases(maxAliases int) OptionFunc {
+	return func(o *Option) {
+		o.queryLimit.MaxAliases = maxAliases
+	}
+}
+
+// SetMaxQueryTokens option func, reject query document with number of lexical tokens greater than maxTokens before execution
+func SetMaxQueryTokens(maxTokens int) OptionFunc {
+	return func(o *Option) {
+		o.queryLimit.MaxTokens = maxTokens
+	}
+}
+
+// SetPersistedQueryStore option func, enable automatic persisted queries (APQ) with query stored in store,
+// use NewCachePersistedQueryStore or NewInMemoryPersistedQueryStore
+func SetPersistedQueryStore(store PersistedQueryStore) OptionFunc {
+	return func(o *Option) {
+		o.persistedQueryStore = store
+	}
+}
+
+// SetPersistedQueryAllowList option func, only execute operations registered in persisted query store
+// (from manifest option or "{root_path}/persisted-queries" endpoint), store must be set with SetPersistedQueryStore
+// and must not evict query (NewInMemoryPersistedQueryStore with negative maxSize or NewCachePersistedQueryStore without expiration)
+func SetPersistedQueryAllowList(allowList bool) OptionFunc {
+	return func(o *Option) {
+		o.persistedQueryAllowList = allowList
+	}
+}
+
+// SetPersistedQueryManifest option func, register operations in manifest to persisted query store at startup,
+// manifest format is same with "{root_path}/persisted-queries" endpoint (see ParsePersistedQueryManifest)
+func SetPersistedQueryManifest(manifest []byte) OptionFunc {
+	return func(o *Option) {
+		o.persistedQueryManifest = manifest
+	}
+}
+
+// SetMaxSubscriptionsPerConnection option func, limit concurrent subscriptions in one websocket connection
+func SetMaxSubscriptionsPerConnection(max int) OptionFunc {
+	return func(o *Option) {
+		o.maxSubscriptionsPerConnection = max
+	}
+}
+
+// SetEnableFederation option func, serve schema as apollo federation v2 subgraph (_service and _entities query),
+// federation is enabled automatically if any graphql module register entity resolver
+func SetEnableFederation(enable bool) OptionFunc {
+	return func(o *Option) {
+		o.enableFederation = enable
+	}
+}
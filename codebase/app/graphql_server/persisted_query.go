@@ -0,0 +1,276 @@
+package graphqlserver
+
+/*
+	Persisted query, support Automatic Persisted Queries (APQ) and allow-list only registered operations
+*/
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golangid/candi/codebase/interfaces"
+	gqlerrors "github.com/golangid/graphql-go/errors"
+)
+
+const (
+	// ErrCodePersistedQueryNotFound error code in graphql error extensions, client must resend request with full query
+	ErrCodePersistedQueryNotFound = "PERSISTED_QUERY_NOT_FOUND"
+	// ErrCodePersistedQueryNotSupported error code in graphql error extensions
+	ErrCodePersistedQueryNotSupported = "PERSISTED_QUERY_NOT_SUPPORTED"
+	// ErrCodePersistedQueryNotAllowed error code in graphql error extensions, query is not registered in allow-list
+	ErrCodePersistedQueryNotAllowed = "PERSISTED_QUERY_NOT_ALLOWED"
+	// ErrCodePersistedQueryHashMismatch error code in graphql error extensions
+	ErrCodePersistedQueryHashMismatch = "PERSISTED_QUERY_HASH_MISMATCH"
+)
+
+var (
+	// ErrPersistedQueryNotFound error
+	ErrPersistedQueryNotFound = errors.New("persisted query not found")
+)
+
+// PersistedQueryStore abstraction for store query document by sha256 hash
+type PersistedQueryStore interface {
+	Get(ctx context.Context, hash string) (query string, err error)
+	Set(ctx context.Context, hash, query string) error
+}
+
+// DefaultPersistedQueryStoreSize default max stored query in memory persisted query store
+const DefaultPersistedQueryStoreSize = 1000
+
+type inMemoryPersistedQueryStore struct {
+	mu      sync.Mutex
+	maxSize int
+	queries map[string]*list.Element
+	order   *list.List
+}
+
+type inMemoryPersistedQuery struct {
+	hash, query string
+}
+
+// NewInMemoryPersistedQueryStore persisted query store in memory, least recently used query will be evicted
+// when store is full, maxSize 0 will use DefaultPersistedQueryStoreSize and negative maxSize is unlimited (no eviction)
+func NewInMemoryPersistedQueryStore(maxSize int) PersistedQueryStore {
+	if maxSize == 0 {
+		maxSize = DefaultPersistedQueryStoreSize
+	}
+	return &inMemoryPersistedQueryStore{maxSize: maxSize, queries: make(map[string]*list.Element), order: list.New()}
+}
+
+func (s *inMemoryPersistedQueryStore) Get(ctx context.Context, hash string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.queries[hash]
+	if !ok {
+		return "", ErrPersistedQueryNotFound
+	}
+	s.order.MoveToFront(elem)
+	return elem.Value.(*inMemoryPersistedQuery).query, nil
+}
+
+func (s *inMemoryPersistedQueryStore) Set(ctx context.Context, hash, query string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.queries[hash]; ok {
+		elem.Value.(*inMemoryPersistedQuery).query = query
+		s.order.MoveToFront(elem)
+		return nil
+	}
+	for s.maxSize > 0 && s.order.Len() >= s.maxSize {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.queries, oldest.Value.(*inMemoryPersistedQuery).hash)
+	}
+	s.queries[hash] = s.order.PushFront(&inMemoryPersistedQuery{hash: hash, query: query})
+	return nil
+}
+
+type cachePersistedQueryStore struct {
+	cache     interfaces.Cache
+	keyPrefix string
+	expire    time.Duration
+}
+
+// NewCachePersistedQueryStore persisted query store using cache (example: redis), zero expire means no expiration
+func NewCachePersistedQueryStore(cache interfaces.Cache, keyPrefix string, expire time.Duration) PersistedQueryStore {
+	if keyPrefix == "" {
+		keyPrefix = "graphql:persisted_query"
+	}
+	return &cachePersistedQueryStore{cache: cache, keyPrefix: keyPrefix, expire: expire}
+}
+
+func (s *cachePersistedQueryStore) Get(ctx context.Context, hash string) (string, error) {
+	query, err := s.cache.Get(ctx, s.keyPrefix+":"+hash)
+	if err != nil || len(query) == 0 {
+		return "", ErrPersistedQueryNotFound
+	}
+	return string(query), nil
+}
+
+func (s *cachePersistedQueryStore) Set(ctx context.Context, hash, query string) error {
+	return s.cache.Set(ctx, s.keyPrefix+":"+hash, query, s.expire)
+}
+
+// PersistedQueryExtension request extension for persisted query
+type PersistedQueryExtension struct {
+	Version    int    `json:"version"`
+	Sha256Hash string `json:"sha256Hash"`
+}
+
+// QueryHash get sha256 hash (hex) of query document, used as persisted query id
+func QueryHash(query string) string {
+	h := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(h[:])
+}
+
+// resolvePersistedQuery get query document from persisted query store and check allow-list
+func (s *handlerImpl) resolvePersistedQuery(ctx context.Context, query string, ext *PersistedQueryExtension) (string, *gqlerrors.QueryError) {
+	store := s.option.persistedQueryStore
+	if store == nil {
+		if ext != nil && ext.Sha256Hash != "" {
+			return "", newPersistedQueryError(ErrCodePersistedQueryNotSupported, "PersistedQueryNotSupported")
+		}
+		return query, nil
+	}
+
+	if ext == nil || ext.Sha256Hash == "" {
+		if !s.option.persistedQueryAllowList {
+			return query, nil
+		}
+		if _, err := store.Get(ctx, QueryHash(query)); err != nil {
+			return "", newPersistedQueryError(ErrCodePersistedQueryNotAllowed, "PersistedQueryNotAllowed")
+		}
+		return query, nil
+	}
+
+	if ext.Version != 0 && ext.Version != 1 {
+		return "", newPersistedQueryError(ErrCodePersistedQueryNotSupported, fmt.Sprintf("Unsupported persisted query version %d", ext.Version))
+	}
+
+	if query == "" {
+		stored, err := store.Get(ctx, ext.Sha256Hash)
+		if err != nil {
+			return "", newPersistedQueryError(ErrCodePersistedQueryNotFound, "PersistedQueryNotFound")
+		}
+		return stored, nil
+	}
+
+	if QueryHash(query) != ext.Sha256Hash {
+		return "", newPersistedQueryError(ErrCodePersistedQueryHashMismatch, "provided sha does not match query")
+	}
+	if s.option.persistedQueryAllowList {
+		if _, err := store.Get(ctx, ext.Sha256Hash); err != nil {
+			return "", newPersistedQueryError(ErrCodePersistedQueryNotAllowed, "PersistedQueryNotAllowed")
+		}
+		return query, nil
+	}
+	if err := store.Set(ctx, ext.Sha256Hash, query); err != nil {
+		return "", &gqlerrors.QueryError{Err: err, Message: err.Error()}
+	}
+	return query, nil
+}
+
+func newPersistedQueryError(code, message string) *gqlerrors.QueryError {
+	return &gqlerrors.QueryError{
+		Message:    message,
+		Rule:       code,
+		Extensions: map[string]any{"code": code},
+	}
+}
+
+// PersistedQueryManifest operation list for register to persisted query store, compatible with
+// apollo persisted query manifest ({"operations": [{"id": "<sha256>", "body": "<query>"}]})
+type PersistedQueryManifest struct {
+	Operations []PersistedQueryOperation `json:"operations"`
+}
+
+// PersistedQueryOperation registered operation
+type PersistedQueryOperation struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	Type string `json:"type,omitempty"`
+	Body string `json:"body"`
+}
+
+// ParsePersistedQueryManifest parse manifest from apollo persisted query manifest format,
+// key-value format ({"<sha256>": "<query>"}) or list of query document
+func ParsePersistedQueryManifest(data []byte) (manifest PersistedQueryManifest, err error) {
+	if err = json.Unmarshal(data, &manifest); err == nil && len(manifest.Operations) > 0 {
+		return manifest, nil
+	}
+
+	var keyValue map[string]string
+	if err = json.Unmarshal(data, &keyValue); err == nil {
+		for id, body := range keyValue {
+			manifest.Operations = append(manifest.Operations, PersistedQueryOperation{ID: id, Body: body})
+		}
+		return manifest, nil
+	}
+
+	var queries []string
+	if err = json.Unmarshal(data, &queries); err == nil {
+		for _, body := range queries {
+			manifest.Operations = append(manifest.Operations, PersistedQueryOperation{Body: body})
+		}
+		return manifest, nil
+	}
+	return manifest, errors.New("invalid persisted query manifest format")
+}
+
+// RegisterPersistedQueries validate and store all operations in manifest to persisted query store
+func RegisterPersistedQueries(ctx context.Context, store PersistedQueryStore, manifest PersistedQueryManifest) (registered int, err error) {
+	for _, op := range manifest.Operations {
+		hash := QueryHash(op.Body)
+		if op.ID != "" && op.ID != hash {
+			return registered, fmt.Errorf("operation %q: id %s does not match sha256 hash of body", op.Name, op.ID)
+		}
+		if err := store.Set(ctx, hash, op.Body); err != nil {
+			return registered, err
+		}
+		registered++
+	}
+	return registered, nil
+}
+
+// initPersistedQuery check persisted query store for allow-list and register operations from manifest option
+func initPersistedQuery(ctx context.Context, opt *Option) (registered int, err error) {
+	if opt.persistedQueryAllowList {
+		switch store := opt.persistedQueryStore.(type) {
+		case nil:
+			return 0, errors.New("persisted query allow-list: persisted query store is not set")
+		case *inMemoryPersistedQueryStore:
+			if store.maxSize > 0 {
+				return 0, errors.New("persisted query allow-list: in memory store must be unlimited (negative maxSize)")
+			}
+		case *cachePersistedQueryStore:
+			if store.expire > 0 {
+				return 0, errors.New("persisted query allow-list: cache store must not have expiration")
+			}
+		}
+	}
+
+	if len(opt.persistedQueryManifest) == 0 {
+		return 0, nil
+	}
+	if opt.persistedQueryStore == nil {
+		return 0, errors.New("persisted query manifest: persisted query store is not set")
+	}
+	manifest, err := ParsePersistedQueryManifest(opt.persistedQueryManifest)
+	if err != nil {
+		return 0, fmt.Errorf("persisted query manifest: %w", err)
+	}
+	registered, err = RegisterPersistedQueries(ctx, opt.persistedQueryStore, manifest)
+	if err != nil {
+		return registered, fmt.Errorf("persisted query manifest: %w", err)
+	}
+	return registered, nil
+}
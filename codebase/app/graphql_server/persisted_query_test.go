@@ -0,0 +1,218 @@
+package graphqlserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golangid/graphql-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type persistedQueryTestResolver struct{}
+
+func (*persistedQueryTestResolver) Hello() string { return "hello" }
+
+func (*persistedQueryTestResolver) SetHello(args struct{ Value string }) string { return args.Value }
+
+const persistedQueryTestSchema = `
+schema { query: Query mutation: Mutation }
+type Query { hello: String! }
+type Mutation { setHello(value: String!): String! }
+`
+
+func newPersistedQueryTestHandler(opts ...OptionFunc) *handlerImpl {
+	var opt Option
+	for _, o := range opts {
+		o(&opt)
+	}
+	return newHandler(graphql.MustParseSchema(persistedQueryTestSchema, &persistedQueryTestResolver{}), opt)
+}
+
+func TestInMemoryPersistedQueryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryPersistedQueryStore(2)
+	store.Set(ctx, "a", "query a")
+	store.Set(ctx, "b", "query b")
+	// "b" is least recently used
+	store.Get(ctx, "a")
+	store.Set(ctx, "c", "query c")
+
+	_, err := store.Get(ctx, "b")
+	assert.ErrorIs(t, err, ErrPersistedQueryNotFound)
+	query, err := store.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "query a", query)
+
+	assert.Equal(t, DefaultPersistedQueryStoreSize, NewInMemoryPersistedQueryStore(0).(*inMemoryPersistedQueryStore).maxSize)
+
+	t.Run("unlimited", func(t *testing.T) {
+		store := NewInMemoryPersistedQueryStore(-1)
+		for i := range DefaultPersistedQueryStoreSize + 1 {
+			store.Set(ctx, strconv.Itoa(i), "query")
+		}
+		_, err := store.Get(ctx, "0")
+		assert.NoError(t, err)
+	})
+}
+
+func TestResolvePersistedQuery(t *testing.T) {
+	ctx := context.Background()
+	query := `{ hello }`
+	hash := QueryHash(query)
+
+	t.Run("not supported without store", func(t *testing.T) {
+		h := newPersistedQueryTestHandler()
+		_, err := h.resolvePersistedQuery(ctx, "", &PersistedQueryExtension{Version: 1, Sha256Hash: hash})
+		require.NotNil(t, err)
+		assert.Equal(t, ErrCodePersistedQueryNotSupported, err.Extensions["code"])
+
+		resolved, err := h.resolvePersistedQuery(ctx, query, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, query, resolved)
+	})
+
+	t.Run("automatic persisted query", func(t *testing.T) {
+		h := newPersistedQueryTestHandler(SetPersistedQueryStore(NewInMemoryPersistedQueryStore(0)))
+		_, err := h.resolvePersistedQuery(ctx, "", &PersistedQueryExtension{Version: 1, Sha256Hash: hash})
+		require.NotNil(t, err)
+		assert.Equal(t, ErrCodePersistedQueryNotFound, err.Extensions["code"])
+
+		_, err = h.resolvePersistedQuery(ctx, query, &PersistedQueryExtension{Version: 1, Sha256Hash: QueryHash("{ other }")})
+		require.NotNil(t, err)
+		assert.Equal(t, ErrCodePersistedQueryHashMismatch, err.Extensions["code"])
+
+		_, err = h.resolvePersistedQuery(ctx, query, &PersistedQueryExtension{Version: 2, Sha256Hash: hash})
+		require.NotNil(t, err)
+		assert.Equal(t, ErrCodePersistedQueryNotSupported, err.Extensions["code"])
+
+		resolved, err := h.resolvePersistedQuery(ctx, query, &PersistedQueryExtension{Version: 1, Sha256Hash: hash})
+		assert.Nil(t, err)
+		assert.Equal(t, query, resolved)
+
+		resolved, err = h.resolvePersistedQuery(ctx, "", &PersistedQueryExtension{Version: 1, Sha256Hash: hash})
+		assert.Nil(t, err)
+		assert.Equal(t, query, resolved)
+	})
+
+	t.Run("allow-list", func(t *testing.T) {
+		h := newPersistedQueryTestHandler(SetPersistedQueryStore(NewInMemoryPersistedQueryStore(-1)), SetPersistedQueryAllowList(true))
+		_, err := h.resolvePersistedQuery(ctx, query, nil)
+		require.NotNil(t, err)
+		assert.Equal(t, ErrCodePersistedQueryNotAllowed, err.Extensions["code"])
+
+		// unregistered query is not stored from client request
+		_, err = h.resolvePersistedQuery(ctx, query, &PersistedQueryExtension{Version: 1, Sha256Hash: hash})
+		require.NotNil(t, err)
+		assert.Equal(t, ErrCodePersistedQueryNotAllowed, err.Extensions["code"])
+
+		registered, regErr := RegisterPersistedQueries(ctx, h.option.persistedQueryStore, PersistedQueryManifest{
+			Operations: []PersistedQueryOperation{{ID: hash, Body: query}},
+		})
+		require.NoError(t, regErr)
+		assert.Equal(t, 1, registered)
+
+		resolved, err := h.resolvePersistedQuery(ctx, query, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, query, resolved)
+	})
+}
+
+func TestRegisterPersistedQueries(t *testing.T) {
+	manifest, err := ParsePersistedQueryManifest([]byte(`{"` + QueryHash(`{ hello }`) + `": "{ hello }"}`))
+	require.NoError(t, err)
+	_, err = RegisterPersistedQueries(context.Background(), NewInMemoryPersistedQueryStore(0), manifest)
+	assert.NoError(t, err)
+
+	manifest, err = ParsePersistedQueryManifest([]byte(`{"operations": [{"id": "invalid", "body": "{ hello }"}]}`))
+	require.NoError(t, err)
+	_, err = RegisterPersistedQueries(context.Background(), NewInMemoryPersistedQueryStore(0), manifest)
+	assert.Error(t, err)
+
+	_, err = ParsePersistedQueryManifest([]byte(`"invalid"`))
+	assert.Error(t, err)
+}
+
+func TestInitPersistedQuery(t *testing.T) {
+	query := `{ hello }`
+	manifest := []byte(`{"operations": [{"id": "` + QueryHash(query) + `", "body": "` + query + `"}]}`)
+
+	tests := []struct {
+		name           string
+		opts           []OptionFunc
+		wantRegistered int
+		wantErr        bool
+	}{
+		{name: "allow-list without store", opts: []OptionFunc{SetPersistedQueryAllowList(true)}, wantErr: true},
+		{name: "allow-list with evicting in memory store", wantErr: true,
+			opts: []OptionFunc{SetPersistedQueryStore(NewInMemoryPersistedQueryStore(0)), SetPersistedQueryAllowList(true)}},
+		{name: "allow-list with expiring cache store", wantErr: true,
+			opts: []OptionFunc{SetPersistedQueryStore(NewCachePersistedQueryStore(nil, "", time.Hour)), SetPersistedQueryAllowList(true)}},
+		{name: "allow-list with cache store", opts: []OptionFunc{SetPersistedQueryStore(NewCachePersistedQueryStore(nil, "", 0)), SetPersistedQueryAllowList(true)}},
+		{name: "manifest without store", opts: []OptionFunc{SetPersistedQueryManifest(manifest)}, wantErr: true},
+		{name: "invalid manifest", wantErr: true,
+			opts: []OptionFunc{SetPersistedQueryStore(NewInMemoryPersistedQueryStore(-1)), SetPersistedQueryManifest([]byte(`"invalid"`))}},
+		{name: "manifest with allow-list", wantRegistered: 1,
+			opts: []OptionFunc{SetPersistedQueryStore(NewInMemoryPersistedQueryStore(-1)), SetPersistedQueryAllowList(true), SetPersistedQueryManifest(manifest)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opt Option
+			for _, o := range tt.opts {
+				o(&opt)
+			}
+			registered, err := initPersistedQuery(context.Background(), &opt)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantRegistered, registered)
+		})
+	}
+
+	t.Run("registered operation from manifest is allowed", func(t *testing.T) {
+		h := newPersistedQueryTestHandler(SetPersistedQueryStore(NewInMemoryPersistedQueryStore(-1)),
+			SetPersistedQueryAllowList(true), SetPersistedQueryManifest(manifest))
+		resolved, err := h.resolvePersistedQuery(context.Background(), query, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, query, resolved)
+
+		_, err = h.resolvePersistedQuery(context.Background(), `{ __typename }`, nil)
+		require.NotNil(t, err)
+		assert.Equal(t, ErrCodePersistedQueryNotAllowed, err.Extensions["code"])
+	})
+
+	assert.Panics(t, func() { newPersistedQueryTestHandler(SetPersistedQueryAllowList(true)) })
+}
+
+func TestServeGraphQLMutationInGETRequest(t *testing.T) {
+	handler := newPersistedQueryTestHandler().ServeGraphQL()
+
+	tests := []struct {
+		name, query, operationName, want string
+	}{
+		{name: "query", query: `{ hello }`, want: `"hello":"hello"`},
+		{name: "mutation", query: `mutation { setHello(value: "x") }`, want: "mutation operation is not allowed in GET request"},
+		{name: "selected mutation", query: `query A { hello } mutation B { setHello(value: "x") }`, operationName: "B",
+			want: "mutation operation is not allowed in GET request"},
+		{name: "selected query", query: `query A { hello } mutation B { setHello(value: "x") }`, operationName: "A",
+			want: `"hello":"hello"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := url.Values{"query": {tt.query}, "operationName": {tt.operationName}}
+			req := httptest.NewRequest(http.MethodGet, "/graphql?"+params.Encode(), nil)
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			assert.Contains(t, rec.Body.String(), tt.want)
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "mutation { setHello(value: \"x\") }"}`))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	assert.Contains(t, rec.Body.String(), `"setHello":"x"`)
+}
@@ -0,0 +1,442 @@
+package graphqlserver
+
+/*
+	Query limit, analyze incoming query document before execution (depth, complexity, aliases and tokens)
+*/
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/golangid/graphql-go"
+	gqlerrors "github.com/golangid/graphql-go/errors"
+	gqltypes "github.com/golangid/graphql-go/types"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/lexer"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+const (
+	// CostDirective schema directive for set field complexity, multipliers is list of argument name
+	// which value multiply the complexity of field selection (example: limit argument in list field)
+	CostDirective = "cost"
+
+	costDirectiveDefinition = "\ndirective @" + CostDirective + "(complexity: Int!, multipliers: [String!]) on FIELD_DEFINITION\n"
+	defaultFieldComplexity  = 1
+
+	// ErrCodeMaxDepthExceeded error code in graphql error extensions
+	ErrCodeMaxDepthExceeded = "MAX_DEPTH_EXCEEDED"
+	// ErrCodeMaxComplexityExceeded error code in graphql error extensions
+	ErrCodeMaxComplexityExceeded = "MAX_COMPLEXITY_EXCEEDED"
+	// ErrCodeMaxAliasesExceeded error code in graphql error extensions
+	ErrCodeMaxAliasesExceeded = "MAX_ALIASES_EXCEEDED"
+	// ErrCodeMaxTokensExceeded error code in graphql error extensions
+	ErrCodeMaxTokensExceeded = "MAX_TOKENS_EXCEEDED"
+	// ErrCodeInvalidQuery error code in graphql error extensions, query cannot be analyzed
+	ErrCodeInvalidQuery = "INVALID_QUERY"
+)
+
+// QueryLimit config, zero value means unlimited
+type QueryLimit struct {
+	MaxDepth      int
+	MaxComplexity int
+	MaxAliases    int
+	MaxTokens     int
+}
+
+// IsActive check if one of limit is set
+func (l QueryLimit) IsActive() bool {
+	return l.MaxDepth > 0 || l.MaxComplexity > 0 || l.MaxAliases > 0 || l.MaxTokens > 0
+}
+
+// QueryMetrics result from analyze query
+type QueryMetrics struct {
+	Depth      int `json:"depth"`
+	Complexity int `json:"complexity"`
+	Aliases    int `json:"aliases"`
+	Tokens     int `json:"tokens"`
+}
+
+type fieldCost struct {
+	complexity  int
+	multipliers []string
+}
+
+type queryLimiter struct {
+	limit      QueryLimit
+	execSchema *graphql.Schema
+	schema     *gqltypes.Schema
+	costs      map[string]fieldCost
+}
+
+// newQueryLimiter query is validated with execSchema before analyze, set graphql.MaxDepth option in execSchema
+// for reject deep query in validation step
+func newQueryLimiter(limit QueryLimit, execSchema *graphql.Schema) *queryLimiter {
+	l := &queryLimiter{limit: limit, execSchema: execSchema, costs: make(map[string]fieldCost)}
+	if execSchema == nil {
+		return l
+	}
+
+	schema := execSchema.ASTSchema()
+	l.schema = schema
+
+	for typeName, namedType := range schema.Types {
+		var fields gqltypes.FieldsDefinition
+		switch t := namedType.(type) {
+		case *gqltypes.ObjectTypeDefinition:
+			fields = t.Fields
+		case *gqltypes.InterfaceTypeDefinition:
+			fields = t.Fields
+		default:
+			continue
+		}
+		for _, field := range fields {
+			directive := field.Directives.Get(CostDirective)
+			if directive == nil {
+				continue
+			}
+			var cost fieldCost
+			if val, ok := directive.Arguments.Get("complexity"); ok {
+				cost.complexity = toInt(val.Deserialize(nil))
+			}
+			if val, ok := directive.Arguments.Get("multipliers"); ok {
+				multipliers, _ := val.Deserialize(nil).([]any)
+				for _, m := range multipliers {
+					if s, ok := m.(string); ok {
+						cost.multipliers = append(cost.multipliers, s)
+					}
+				}
+			}
+			l.costs[typeName+"."+field.Name] = cost
+		}
+	}
+	return l
+}
+
+// check validate and analyze query, return graphql errors when query is invalid or exceed the limit
+func (l *queryLimiter) check(query, operationName string, variables map[string]any) (QueryMetrics, []*gqlerrors.QueryError) {
+	var metrics QueryMetrics
+	if l == nil || !l.limit.IsActive() {
+		return metrics, nil
+	}
+
+	if l.limit.MaxTokens > 0 {
+		metrics.Tokens = countTokens(query, l.limit.MaxTokens+1)
+		if metrics.Tokens > l.limit.MaxTokens {
+			return metrics, []*gqlerrors.QueryError{
+				newQueryLimitError(ErrCodeMaxTokensExceeded, "tokens", l.limit.MaxTokens, metrics.Tokens),
+			}
+		}
+	}
+
+	doc, err := parseQuery(query)
+	if err != nil {
+		return metrics, []*gqlerrors.QueryError{newInvalidQueryError(err.Error())}
+	}
+
+	// federation query is validated with entity schema when executed
+	if op := doc.Operations.ForName(operationName); l.execSchema != nil && (op == nil || !isFederationOperation(op)) {
+		var errs []*gqlerrors.QueryError
+		for _, err := range l.execSchema.ValidateWithVariables(query, variables) {
+			// depth is reported by analyzer with query limit error code
+			if err.Rule != "MaxDepthExceeded" {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			return metrics, errs
+		}
+	}
+
+	a := &queryAnalyzer{
+		limiter: l, variables: variables, fragments: make(map[string]*ast.FragmentDefinition, len(doc.Fragments)),
+		fragmentMetrics: make(map[string]selectionMetrics), visiting: make(map[string]bool), maxComplexity: math.MaxInt,
+	}
+	if l.limit.MaxComplexity > 0 {
+		// saturate complexity, query is rejected if complexity exceed the limit
+		a.maxComplexity = l.limit.MaxComplexity + 1
+	}
+	for _, fragment := range doc.Fragments {
+		a.fragments[fragment.Name] = fragment
+	}
+	for _, op := range doc.Operations {
+		if a.exceeded {
+			break
+		}
+		if operationName != "" && op.Name != operationName {
+			continue
+		}
+		m := a.analyzeSelectionSet(op.SelectionSet, l.rootTypeName(string(op.Operation)), 0)
+		metrics.Depth = max(metrics.Depth, m.depth)
+		metrics.Complexity = max(metrics.Complexity, m.complexity)
+	}
+	metrics.Aliases = countAliases(doc)
+
+	var errs []*gqlerrors.QueryError
+	if l.limit.MaxDepth > 0 && metrics.Depth > l.limit.MaxDepth {
+		errs = append(errs, newQueryLimitError(ErrCodeMaxDepthExceeded, "depth", l.limit.MaxDepth, metrics.Depth))
+	}
+	if l.limit.MaxComplexity > 0 && metrics.Complexity > l.limit.MaxComplexity {
+		errs = append(errs, newQueryLimitError(ErrCodeMaxComplexityExceeded, "complexity", l.limit.MaxComplexity, metrics.Complexity))
+	}
+	if l.limit.MaxAliases > 0 && metrics.Aliases > l.limit.MaxAliases {
+		errs = append(errs, newQueryLimitError(ErrCodeMaxAliasesExceeded, "aliases", l.limit.MaxAliases, metrics.Aliases))
+	}
+	return metrics, errs
+}
+
+// isMutationQuery check if selected operation in query document is mutation
+func isMutationQuery(query, operationName string) bool {
+	doc, err := parseQuery(query)
+	if err != nil {
+		return false
+	}
+	for _, op := range doc.Operations {
+		if (operationName == "" || op.Name == operationName) && op.Operation == ast.Mutation {
+			return true
+		}
+	}
+	return false
+}
+
+func parseQuery(query string) (*ast.QueryDocument, error) {
+	return parser.ParseQuery(&ast.Source{Input: query})
+}
+
+// countTokens count lexical tokens in query (comment is skipped), stop counting when reach the limit
+func countTokens(query string, limit int) (count int) {
+	lex := lexer.New(&ast.Source{Input: query})
+	for count < limit {
+		token, err := lex.ReadToken()
+		if err != nil || token.Kind == lexer.EOF {
+			break
+		}
+		if token.Kind != lexer.Comment {
+			count++
+		}
+	}
+	return count
+}
+
+// countAliases count aliased fields in all operations and fragment definitions
+func countAliases(doc *ast.QueryDocument) (aliases int) {
+	var walk func(selections ast.SelectionSet)
+	walk = func(selections ast.SelectionSet) {
+		for _, sel := range selections {
+			switch s := sel.(type) {
+			case *ast.Field:
+				if s.Alias != s.Name {
+					aliases++
+				}
+				walk(s.SelectionSet)
+			case *ast.InlineFragment:
+				walk(s.SelectionSet)
+			}
+		}
+	}
+	for _, op := range doc.Operations {
+		walk(op.SelectionSet)
+	}
+	for _, fragment := range doc.Fragments {
+		walk(fragment.SelectionSet)
+	}
+	return aliases
+}
+
+func (l *queryLimiter) rootTypeName(operationType string) string {
+	if l.schema == nil {
+		return ""
+	}
+	if entryPoint, ok := l.schema.EntryPoints[operationType]; ok {
+		return entryPoint.TypeName()
+	}
+	return ""
+}
+
+// fieldInfo get field cost and named type of field result
+func (l *queryLimiter) fieldInfo(parentType, fieldName string) (cost fieldCost, typeName string) {
+	cost.complexity = defaultFieldComplexity
+	if c, ok := l.costs[parentType+"."+fieldName]; ok {
+		cost = c
+	}
+	if l.schema == nil {
+		return cost, ""
+	}
+
+	var fields gqltypes.FieldsDefinition
+	switch t := l.schema.Types[parentType].(type) {
+	case *gqltypes.ObjectTypeDefinition:
+		fields = t.Fields
+	case *gqltypes.InterfaceTypeDefinition:
+		fields = t.Fields
+	}
+	if field := fields.Get(fieldName); field != nil {
+		typeName = unwrapTypeName(field.Type)
+	}
+	return cost, typeName
+}
+
+func newQueryLimitError(code, name string, limit, actual int) *gqlerrors.QueryError {
+	return &gqlerrors.QueryError{
+		Message: fmt.Sprintf("query %s %d exceeds maximum allowed %s %d", name, actual, name, limit),
+		Rule:    code,
+		Extensions: map[string]any{
+			"code":   code,
+			"limit":  limit,
+			"actual": actual,
+		},
+	}
+}
+
+func newInvalidQueryError(reason string) *gqlerrors.QueryError {
+	return &gqlerrors.QueryError{
+		Message:    "query cannot be analyzed: " + reason,
+		Rule:       ErrCodeInvalidQuery,
+		Extensions: map[string]any{"code": ErrCodeInvalidQuery},
+	}
+}
+
+func unwrapTypeName(t gqltypes.Type) string {
+	for {
+		switch v := t.(type) {
+		case *gqltypes.List:
+			t = v.OfType
+		case *gqltypes.NonNull:
+			t = v.OfType
+		case gqltypes.NamedType:
+			return v.TypeName()
+		default:
+			return ""
+		}
+	}
+}
+
+type queryAnalyzer struct {
+	limiter   *queryLimiter
+	variables map[string]any
+	fragments map[string]*ast.FragmentDefinition
+	// fragmentMetrics analyzed fragment, each fragment is analyzed once even if spread many times
+	fragmentMetrics map[string]selectionMetrics
+	visiting        map[string]bool
+	// maxComplexity saturation value of complexity for prevent integer overflow
+	maxComplexity int
+	// exceeded one of limit is exceeded, the rest of query is not analyzed
+	exceeded bool
+}
+
+type selectionMetrics struct {
+	depth, complexity int
+}
+
+// analyzeSelectionSet level is depth of parent selection from operation root
+func (a *queryAnalyzer) analyzeSelectionSet(selections ast.SelectionSet, parentType string, level int) (m selectionMetrics) {
+	limit := a.limiter.limit
+	for _, sel := range selections {
+		var selMetrics selectionMetrics
+		switch s := sel.(type) {
+		case *ast.FragmentSpread:
+			selMetrics = a.analyzeFragment(s.Name, level)
+
+		case *ast.InlineFragment:
+			typeName := parentType
+			if s.TypeCondition != "" {
+				typeName = s.TypeCondition
+			}
+			selMetrics = a.analyzeSelectionSet(s.SelectionSet, typeName, level)
+
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") {
+				// skip introspection and meta fields
+				continue
+			}
+			cost, typeName := a.limiter.fieldInfo(parentType, s.Name)
+			var child selectionMetrics
+			if limit.MaxDepth <= 0 || level < limit.MaxDepth {
+				child = a.analyzeSelectionSet(s.SelectionSet, typeName, level+1)
+			}
+			multiplier := 1
+			for _, argName := range cost.multipliers {
+				if n := a.argumentInt(s.Arguments.ForName(argName)); n > 0 {
+					multiplier = a.saturatedMul(multiplier, n)
+				}
+			}
+			selMetrics.depth = child.depth + 1
+			selMetrics.complexity = a.saturatedAdd(max(cost.complexity, 0), a.saturatedMul(multiplier, child.complexity))
+		}
+		m.depth = max(m.depth, selMetrics.depth)
+		m.complexity = a.saturatedAdd(m.complexity, selMetrics.complexity)
+
+		if (limit.MaxDepth > 0 && level+m.depth > limit.MaxDepth) || (limit.MaxComplexity > 0 && m.complexity == a.maxComplexity) {
+			a.exceeded = true
+		}
+		if a.exceeded {
+			// limit is exceeded, no need to analyze the rest
+			return m
+		}
+	}
+	return m
+}
+
+// analyzeFragment analyze fragment once and reuse the result for next spread, depth and complexity
+// of fragment is not depend on where the fragment is spread
+func (a *queryAnalyzer) analyzeFragment(name string, level int) selectionMetrics {
+	if m, ok := a.fragmentMetrics[name]; ok {
+		return m
+	}
+	fragment, ok := a.fragments[name]
+	if !ok || a.visiting[name] {
+		return selectionMetrics{}
+	}
+
+	a.visiting[name] = true
+	m := a.analyzeSelectionSet(fragment.SelectionSet, fragment.TypeCondition, level)
+	delete(a.visiting, name)
+	a.fragmentMetrics[name] = m
+	return m
+}
+
+func (a *queryAnalyzer) saturatedAdd(x, y int) int {
+	if x > a.maxComplexity-y {
+		return a.maxComplexity
+	}
+	return x + y
+}
+
+func (a *queryAnalyzer) saturatedMul(x, y int) int {
+	if x != 0 && y > a.maxComplexity/x {
+		return a.maxComplexity
+	}
+	return min(x*y, a.maxComplexity)
+}
+
+func (a *queryAnalyzer) argumentInt(arg *ast.Argument) int {
+	if arg == nil || arg.Value == nil {
+		return 0
+	}
+	switch arg.Value.Kind {
+	case ast.Variable:
+		return toInt(a.variables[arg.Value.Raw])
+	case ast.IntValue:
+		return toInt(arg.Value.Raw)
+	}
+	return 0
+}
+
+func toInt(val any) int {
+	switch v := val.(type) {
+	case int:
+		return v
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case float64:
+		return int(min(v, math.MaxInt32))
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
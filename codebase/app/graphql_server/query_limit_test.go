@@ -0,0 +1,196 @@
+package graphqlserver
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golangid/graphql-go"
+	"github.com/stretchr/testify/assert"
+)
+
+const queryLimitTestSchema = `
+schema { query: Query }
+type Query {
+	user(id: ID!): User
+	users(limit: Int): [User!]! @cost(complexity: 2, multipliers: ["limit"])
+}
+type User {
+	id: ID!
+	name: String
+	friends(first: Int): [User!]! @cost(complexity: 1, multipliers: ["first"])
+}
+` + costDirectiveDefinition
+
+func TestQueryLimiterCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     QueryLimit
+		query     string
+		operation string
+		variables map[string]any
+		want      QueryMetrics
+		wantRule  string
+	}{
+		{
+			name:  "depth",
+			limit: QueryLimit{MaxDepth: 3},
+			query: `{ user(id: 1) { friends { friends { id } } } }`,
+			want:  QueryMetrics{Depth: 4, Complexity: 4}, wantRule: ErrCodeMaxDepthExceeded,
+		},
+		{
+			name:  "complexity with multiplier",
+			limit: QueryLimit{MaxComplexity: 100},
+			query: `{ users(limit: 10) { id name } }`,
+			want:  QueryMetrics{Depth: 2, Complexity: 22},
+		},
+		{
+			name:      "complexity with variable multiplier",
+			limit:     QueryLimit{MaxComplexity: 100},
+			query:     `query Q($n: Int) { users(limit: $n) { friends(first: $n) { id } } }`,
+			variables: map[string]any{"n": float64(10)},
+			want:      QueryMetrics{Depth: 3, Complexity: 101}, wantRule: ErrCodeMaxComplexityExceeded,
+		},
+		{
+			name:  "aliases",
+			limit: QueryLimit{MaxAliases: 1},
+			query: `{ a: user(id: 1) { id } b: user(id: 2) { id } }`,
+			want:  QueryMetrics{Depth: 2, Complexity: 4, Aliases: 2}, wantRule: ErrCodeMaxAliasesExceeded,
+		},
+		{
+			name:  "tokens",
+			limit: QueryLimit{MaxTokens: 5},
+			query: `{ user(id: 1) { id } }`,
+			want:  QueryMetrics{Tokens: 6}, wantRule: ErrCodeMaxTokensExceeded,
+		},
+		{
+			name:  "fragment and inline fragment",
+			limit: QueryLimit{MaxDepth: 10},
+			query: `{ user(id: 1) { ...F } } fragment F on User { friends { ... on User { id } } }`,
+			want:  QueryMetrics{Depth: 3, Complexity: 3},
+		},
+		{
+			name:     "recursive fragment",
+			limit:    QueryLimit{MaxDepth: 10},
+			query:    `{ user(id: 1) { ...F } } fragment F on User { friends { ...F } }`,
+			wantRule: "NoFragmentCycles",
+		},
+		{
+			name:     "invalid field",
+			limit:    QueryLimit{MaxDepth: 10},
+			query:    `{ user(id: 1) { email } }`,
+			wantRule: "FieldsOnCorrectType",
+		},
+		{
+			name:      "selected operation",
+			limit:     QueryLimit{MaxDepth: 2},
+			query:     `query A { user(id: 1) { id } } query B { user(id: 1) { friends { id } } }`,
+			operation: "A",
+			want:      QueryMetrics{Depth: 2, Complexity: 2},
+		},
+		{
+			name:      "overflow is saturated",
+			limit:     QueryLimit{MaxComplexity: 1000},
+			query:     `query Q($n: Int) { users(limit: $n) { friends(first: $n) { friends(first: $n) { friends(first: $n) { id } } } } }`,
+			variables: map[string]any{"n": 1 << 30},
+			want:      QueryMetrics{Depth: 5, Complexity: 1001}, wantRule: ErrCodeMaxComplexityExceeded,
+		},
+		{
+			name:      "overflow is saturated without complexity limit",
+			limit:     QueryLimit{MaxDepth: 10},
+			query:     `query Q($n: Int) { users(limit: $n) { friends(first: $n) { friends(first: $n) { friends(first: $n) { id } } } } }`,
+			variables: map[string]any{"n": 1 << 30},
+			want:      QueryMetrics{Depth: 5, Complexity: math.MaxInt},
+		},
+		{
+			name:     "invalid query",
+			limit:    QueryLimit{MaxDepth: 10},
+			query:    `{ user(id: 1) { id }`,
+			wantRule: ErrCodeInvalidQuery,
+		},
+		{
+			name:     "invalid token",
+			limit:    QueryLimit{MaxDepth: 10},
+			query:    `{ user(id: "1) { id } }`,
+			wantRule: ErrCodeInvalidQuery,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := graphql.MustParseSchema(queryLimitTestSchema, nil, graphql.MaxDepth(tt.limit.MaxDepth))
+			metrics, errs := newQueryLimiter(tt.limit, schema).check(tt.query, tt.operation, tt.variables)
+			if tt.want.Tokens == 0 {
+				metrics.Tokens = 0
+			}
+			assert.Equal(t, tt.want, metrics)
+			if tt.wantRule == "" {
+				assert.Empty(t, errs)
+				return
+			}
+			if assert.Len(t, errs, 1) {
+				assert.Equal(t, tt.wantRule, errs[0].Rule)
+			}
+		})
+	}
+}
+
+func TestQueryLimiterNestedFragments(t *testing.T) {
+	// each fragment spread the next fragment twice, the query is expanded to 2^n fields
+	const n = 30
+	query := `{ user(id: 1) { ...F0 } } fragment F30 on User { id }`
+	for i := range n {
+		query += fmt.Sprintf(" fragment F%d on User { a: friends { ...F%d } b: friends { ...F%d } }", i, i+1, i+1)
+	}
+	wantComplexity := 1
+	for range n {
+		wantComplexity = 2 * (1 + wantComplexity)
+	}
+
+	tests := []struct {
+		name     string
+		limit    QueryLimit
+		want     QueryMetrics
+		wantRule string
+	}{
+		{
+			name:  "analyzed without limit exceeded",
+			limit: QueryLimit{MaxDepth: n + 2},
+			want:  QueryMetrics{Depth: n + 2, Complexity: wantComplexity + 1, Aliases: 2 * n},
+		},
+		{
+			name:     "complexity exceeded",
+			limit:    QueryLimit{MaxComplexity: 1000},
+			want:     QueryMetrics{Depth: n + 2, Complexity: 1001, Aliases: 2 * n},
+			wantRule: ErrCodeMaxComplexityExceeded,
+		},
+		{
+			name:     "depth exceeded",
+			limit:    QueryLimit{MaxDepth: 5},
+			want:     QueryMetrics{Depth: 6, Complexity: 6, Aliases: 2 * n},
+			wantRule: ErrCodeMaxDepthExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := graphql.MustParseSchema(queryLimitTestSchema, nil, graphql.MaxDepth(tt.limit.MaxDepth))
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				metrics, errs := newQueryLimiter(tt.limit, schema).check(query, "", nil)
+				assert.Equal(t, tt.want, metrics)
+				if tt.wantRule == "" {
+					assert.Empty(t, errs)
+				} else if assert.Len(t, errs, 1) {
+					assert.Equal(t, tt.wantRule, errs[0].Rule)
+				}
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("query analysis is not finished, fragment is analyzed for each spread")
+			}
+		})
+	}
+}
@@ -5,7 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"sync"
 	"time"
+
+	"github.com/golangid/candi/candihelper"
+	"github.com/golangid/candi/candishared"
 )
 
 type operationMessageType string
@@ -30,6 +35,7 @@ type wsConnection interface {
 	SetReadLimit(limit int64)
 	SetWriteDeadline(t time.Time) error
 	WriteJSON(v any) error
+	WriteControl(messageType int, data []byte, deadline time.Time) error
 }
 
 type sendFunc func(id string, omType operationMessageType, payload json.RawMessage)
@@ -42,12 +48,12 @@ type operationMessage struct {
 }
 
 type startMessagePayload struct {
-	OperationName string                 `json:"operationName"`
-	Query         string                 `json:"query"`
+	OperationName string         `json:"operationName"`
+	Query         string         `json:"query"`
 	Variables     map[string]any `json:"variables"`
 }
 
-type initMessagePayload struct{}
+type initMessagePayload map[string]any
 
 // GraphQLService interface
 type GraphQLService interface {
@@ -55,12 +61,27 @@ type GraphQLService interface {
 }
 
 type connection struct {
-	cancel       func()
-	service      GraphQLService
-	writeTimeout time.Duration
-	ws           wsConnection
+	cancel             func()
+	service            GraphQLService
+	writeTimeout       time.Duration
+	initTimeout        time.Duration
+	maxSubscriptions   int
+	initPayloadHeaders []string
+	ws                 wsConnection
+
+	mu         sync.Mutex
+	operations map[string]*operation
+}
+
+type operation struct {
+	cancel func()
 }
 
+var (
+	errDuplicateOperation = errors.New("operation with the same id already exists")
+	errTooManyOperations  = errors.New("too many subscriptions in connection")
+)
+
 // ReadLimit limits the maximum size of incoming messages
 func ReadLimit(limit int64) func(conn *connection) {
 	return func(conn *connection) {
@@ -75,22 +96,52 @@ func WriteTimeout(d time.Duration) func(conn *connection) {
 	}
 }
 
-// Connect implements the apollographql subscriptions-transport-ws protocol@v0.9.4
-// https://github.com/apollographql/subscriptions-transport-ws/blob/v0.9.4/PROTOCOL.md
-func Connect(ctx context.Context, ws wsConnection, service GraphQLService, options ...func(conn *connection)) func() {
+// ConnectionInitTimeout sets max duration waiting connection_init message from client (graphql-transport-ws protocol)
+func ConnectionInitTimeout(d time.Duration) func(conn *connection) {
+	return func(conn *connection) {
+		conn.initTimeout = d
+	}
+}
+
+// MaxSubscriptions limits the number of concurrent subscriptions per connection, zero means unlimited
+func MaxSubscriptions(max int) func(conn *connection) {
+	return func(conn *connection) {
+		conn.maxSubscriptions = max
+	}
+}
+
+// InitPayloadHeaders sets header names which value can be sent in connection_init payload (graphql-transport-ws protocol),
+// header already sent in websocket upgrade request is not overridden, default is Authorization
+func InitPayloadHeaders(headers ...string) func(conn *connection) {
+	return func(conn *connection) {
+		conn.initPayloadHeaders = headers
+	}
+}
+
+func newConnection(ws wsConnection, service GraphQLService, options ...func(conn *connection)) *connection {
 	conn := &connection{
-		service: service,
-		ws:      ws,
+		service:    service,
+		ws:         ws,
+		operations: make(map[string]*operation),
 	}
 
 	defaultOpts := []func(conn *connection){
 		ReadLimit(4096),
 		WriteTimeout(time.Second),
+		ConnectionInitTimeout(10 * time.Second),
+		InitPayloadHeaders(candihelper.HeaderAuthorization),
 	}
 
 	for _, opt := range append(defaultOpts, options...) {
 		opt(conn)
 	}
+	return conn
+}
+
+// Connect implements the apollographql subscriptions-transport-ws protocol@v0.9.4
+// https://github.com/apollographql/subscriptions-transport-ws/blob/v0.9.4/PROTOCOL.md
+func Connect(ctx context.Context, ws wsConnection, service GraphQLService, options ...func(conn *connection)) func() {
+	conn := newConnection(ws, service, options...)
 
 	ctx, cancel := context.WithCancel(ctx)
 	conn.cancel = cancel
@@ -146,10 +197,71 @@ func (conn *connection) close() {
 	conn.ws.Close()
 }
 
+// addOperation register running operation, check duplicate id and max subscriptions in connection
+func (conn *connection) addOperation(id string, cancel func()) (*operation, error) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if _, ok := conn.operations[id]; ok {
+		return nil, errDuplicateOperation
+	}
+	if conn.maxSubscriptions > 0 && len(conn.operations) >= conn.maxSubscriptions {
+		return nil, errTooManyOperations
+	}
+	op := &operation{cancel: cancel}
+	conn.operations[id] = op
+	return op, nil
+}
+
+// removeOperation cancel and remove operation, if op is not nil only remove when registered operation is op
+func (conn *connection) removeOperation(id string, op *operation) bool {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	registered, ok := conn.operations[id]
+	if !ok || (op != nil && registered != op) {
+		return false
+	}
+	delete(conn.operations, id)
+	registered.cancel()
+	return true
+}
+
+// withInitPayload set connection_init payload to context, allowed header values in payload (or in "headers" field)
+// are merged to http header in context so can be used by auth middleware (example: {"Authorization": "Bearer <token>"}),
+// header from websocket upgrade request is not overridden
+func (conn *connection) withInitPayload(ctx context.Context, payload initMessagePayload) context.Context {
+	if len(payload) == 0 {
+		return ctx
+	}
+
+	ctx = candishared.SetToContext(ctx, candishared.ContextKeyWebSocketInitPayload, map[string]any(payload))
+	headers, _ := candishared.GetValueFromContext(ctx, candishared.ContextKeyHTTPHeader).(http.Header)
+	headers = headers.Clone()
+	if headers == nil {
+		headers = make(http.Header)
+	}
+	values := map[string]any(payload)
+	if nested, ok := payload["headers"].(map[string]any); ok {
+		values = nested
+	}
+	for _, name := range conn.initPayloadHeaders {
+		name = http.CanonicalHeaderKey(name)
+		if headers.Get(name) != "" {
+			continue
+		}
+		for key, value := range values {
+			if str, ok := value.(string); ok && http.CanonicalHeaderKey(key) == name {
+				headers.Set(name, str)
+			}
+		}
+	}
+	return candishared.SetToContext(ctx, candishared.ContextKeyHTTPHeader, headers)
+}
+
 func (conn *connection) readLoop(ctx context.Context, send sendFunc) {
 	defer conn.close()
 
-	opDone := map[string]func(){}
 	for {
 		var msg operationMessage
 		err := conn.ws.ReadJSON(&msg)
@@ -168,7 +280,6 @@ func (conn *connection) readLoop(ctx context.Context, send sendFunc) {
 			send("", typeConnectionAck, nil)
 
 		case typeStart:
-			// TODO: check an operation with the same ID hasn't been started already
 			if msg.ID == "" {
 				ep := errPayload(errors.New("missing ID for start operation"))
 				send("", typeConnectionError, ep)
@@ -183,8 +294,7 @@ func (conn *connection) readLoop(ctx context.Context, send sendFunc) {
 			}
 
 			opCtx, cancel := context.WithCancel(ctx)
-			// TODO: timeout this call, to guard against poor clients
-			c, err := conn.service.Subscribe(opCtx, osp.Query, osp.OperationName, osp.Variables)
+			op, err := conn.addOperation(msg.ID, cancel)
 			if err != nil {
 				cancel()
 				send(msg.ID, typeError, errPayload(err))
@@ -192,36 +302,39 @@ func (conn *connection) readLoop(ctx context.Context, send sendFunc) {
 				continue
 			}
 
-			opDone[msg.ID] = cancel
+			// TODO: timeout this call, to guard against poor clients
+			c, err := conn.service.Subscribe(opCtx, osp.Query, osp.OperationName, osp.Variables)
+			if err != nil {
+				conn.removeOperation(msg.ID, op)
+				send(msg.ID, typeError, errPayload(err))
+				send(msg.ID, typeComplete, nil)
+				continue
+			}
 
-			go func() {
-				defer cancel()
+			go func(id string) {
+				defer conn.removeOperation(id, op)
 				for {
 					select {
 					case <-opCtx.Done():
 						return
 					case payload, more := <-c:
 						if !more {
-							send(msg.ID, typeComplete, nil)
+							send(id, typeComplete, nil)
 							return
 						}
 
 						jsonPayload, err := json.Marshal(payload)
 						if err != nil {
-							send(msg.ID, typeError, errPayload(err))
+							send(id, typeError, errPayload(err))
 							continue
 						}
-						send(msg.ID, typeData, jsonPayload)
+						send(id, typeData, jsonPayload)
 					}
 				}
-			}()
+			}(msg.ID)
 
 		case typeStop:
-			onDone, ok := opDone[msg.ID]
-			if ok {
-				delete(opDone, msg.ID)
-				onDone()
-			}
+			conn.removeOperation(msg.ID, nil)
 			send(msg.ID, typeComplete, nil)
 
 		case typeConnectionTerminate:
@@ -8,7 +8,10 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-const protocolGraphQLWS = "graphql-ws"
+const (
+	protocolGraphQLWS          = "graphql-ws"
+	protocolGraphQLTransportWS = "graphql-transport-ws"
+)
 
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
@@ -16,11 +19,12 @@ var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
-	Subprotocols: []string{protocolGraphQLWS},
+	Subprotocols: []string{protocolGraphQLTransportWS, protocolGraphQLWS},
 }
 
-// NewHandlerFunc returns an http.HandlerFunc that supports GraphQL over websockets
-func NewHandlerFunc(svc GraphQLService, httpHandler http.Handler) http.HandlerFunc {
+// NewHandlerFunc returns an http.HandlerFunc that supports GraphQL over websockets,
+// protocol negotiated from subprotocol (graphql-transport-ws or legacy graphql-ws)
+func NewHandlerFunc(svc GraphQLService, httpHandler http.Handler, options ...func(conn *connection)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		// handle cors
@@ -33,19 +37,21 @@ func NewHandlerFunc(svc GraphQLService, httpHandler http.Handler) http.HandlerFu
 		}
 
 		for _, subprotocol := range websocket.Subprotocols(r) {
-			if subprotocol == protocolGraphQLWS {
+			if subprotocol == protocolGraphQLWS || subprotocol == protocolGraphQLTransportWS {
 				ws, err := upgrader.Upgrade(w, r, nil)
 				if err != nil {
 					return
 				}
 
-				if ws.Subprotocol() != protocolGraphQLWS {
+				ctx := candishared.SetToContext(context.Background(), candishared.ContextKeyHTTPHeader, r.Header)
+				switch ws.Subprotocol() {
+				case protocolGraphQLTransportWS:
+					go ConnectTransportWS(ctx, ws, svc, options...)
+				case protocolGraphQLWS:
+					go Connect(ctx, ws, svc, options...)
+				default:
 					ws.Close()
-					return
 				}
-
-				ctx := candishared.SetToContext(context.Background(), candishared.ContextKeyHTTPHeader, r.Header)
-				go Connect(ctx, ws, svc)
 				return
 			}
 		}
@@ -0,0 +1,181 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+const (
+	typePing      operationMessageType = "ping"
+	typePong      operationMessageType = "pong"
+	typeSubscribe operationMessageType = "subscribe"
+	typeNext      operationMessageType = "next"
+
+	closeCodeBadRequest            = 4400
+	closeCodeUnauthorized          = 4401
+	closeCodeInitTimeout           = 4408
+	closeCodeSubscriberExists      = 4409
+	closeCodeTooManyInitialisation = 4429
+)
+
+// ConnectTransportWS implements the graphql-transport-ws protocol
+// https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+func ConnectTransportWS(ctx context.Context, ws wsConnection, service GraphQLService, options ...func(conn *connection)) func() {
+	conn := newConnection(ws, service, options...)
+
+	ctx, cancel := context.WithCancel(ctx)
+	conn.cancel = cancel
+	conn.transportReadLoop(ctx, conn.writeLoop(ctx))
+
+	return cancel
+}
+
+// closeWithCode close websocket connection with close code and reason
+func (conn *connection) closeWithCode(code int, reason string) {
+	conn.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(conn.writeTimeout))
+	conn.close()
+}
+
+func (conn *connection) transportReadLoop(ctx context.Context, send sendFunc) {
+	defer conn.close()
+
+	var initialised, acknowledged atomic.Bool
+	initTimer := time.AfterFunc(conn.initTimeout, func() {
+		if !acknowledged.Load() {
+			conn.closeWithCode(closeCodeInitTimeout, "Connection initialisation timeout")
+		}
+	})
+	defer initTimer.Stop()
+
+	for {
+		var msg operationMessage
+		if err := conn.ws.ReadJSON(&msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				conn.closeWithCode(closeCodeBadRequest, "Invalid message received")
+			}
+			return
+		}
+
+		switch msg.Type {
+		case typeConnectionInit:
+			if initialised.Swap(true) {
+				conn.closeWithCode(closeCodeTooManyInitialisation, "Too many initialisation requests")
+				return
+			}
+			var initMsg initMessagePayload
+			if len(msg.Payload) > 0 {
+				if err := json.Unmarshal(msg.Payload, &initMsg); err != nil {
+					conn.closeWithCode(closeCodeBadRequest, "Invalid connection_init payload")
+					return
+				}
+			}
+			ctx = conn.withInitPayload(ctx, initMsg)
+			acknowledged.Store(true)
+			send("", typeConnectionAck, nil)
+
+		case typePing:
+			send("", typePong, msg.Payload)
+
+		case typePong:
+
+		case typeSubscribe:
+			if !acknowledged.Load() {
+				conn.closeWithCode(closeCodeUnauthorized, "Unauthorized")
+				return
+			}
+
+			var payload startMessagePayload
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &payload) != nil {
+				conn.closeWithCode(closeCodeBadRequest, "Invalid subscribe message")
+				return
+			}
+
+			opCtx, cancel := context.WithCancel(ctx)
+			op, err := conn.addOperation(msg.ID, cancel)
+			if errors.Is(err, errDuplicateOperation) {
+				cancel()
+				conn.closeWithCode(closeCodeSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", msg.ID))
+				return
+			}
+			if err != nil {
+				cancel()
+				send(msg.ID, typeError, errListPayload(err))
+				continue
+			}
+
+			c, err := conn.service.Subscribe(opCtx, payload.Query, payload.OperationName, payload.Variables)
+			if err != nil {
+				conn.removeOperation(msg.ID, op)
+				send(msg.ID, typeError, errListPayload(err))
+				continue
+			}
+
+			go func(id string) {
+				defer conn.removeOperation(id, op)
+				for first := true; ; first = false {
+					select {
+					case <-opCtx.Done():
+						return
+					case result, more := <-c:
+						if !more {
+							send(id, typeComplete, nil)
+							return
+						}
+
+						jsonPayload, err := json.Marshal(result)
+						if err != nil {
+							send(id, typeError, errListPayload(err))
+							return
+						}
+						// request error (example: validation error) before subscription started
+						if errs := requestErrors(jsonPayload); first && errs != nil {
+							send(id, typeError, errs)
+							return
+						}
+						send(id, typeNext, jsonPayload)
+					}
+				}
+			}(msg.ID)
+
+		case typeComplete:
+			conn.removeOperation(msg.ID, nil)
+
+		default:
+			conn.closeWithCode(closeCodeBadRequest, fmt.Sprintf("Invalid message type: %s", msg.Type))
+			return
+		}
+	}
+}
+
+// requestErrors get errors from execution result without data
+func requestErrors(result json.RawMessage) json.RawMessage {
+	var res struct {
+		Data   json.RawMessage `json:"data"`
+		Errors json.RawMessage `json:"errors"`
+	}
+	if err := json.Unmarshal(result, &res); err != nil || len(res.Errors) == 0 {
+		return nil
+	}
+	if len(res.Data) > 0 && string(res.Data) != "null" {
+		return nil
+	}
+	return res.Errors
+}
+
+func errListPayload(err error) json.RawMessage {
+	b, _ := json.Marshal([]struct {
+		Message string `json:"message"`
+	}{
+		{Message: err.Error()},
+	})
+	return b
+}
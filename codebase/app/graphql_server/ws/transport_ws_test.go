@@ -0,0 +1,155 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golangid/candi/candishared"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeService send results to each subscription and record http header from subscription context
+type fakeService struct {
+	results []any
+	headers chan http.Header
+}
+
+func newFakeService(results ...any) *fakeService {
+	return &fakeService{results: results, headers: make(chan http.Header, 10)}
+}
+
+func (s *fakeService) Subscribe(ctx context.Context, document string, operationName string, variableValues map[string]any) (<-chan any, error) {
+	headers, _ := candishared.GetValueFromContext(ctx, candishared.ContextKeyHTTPHeader).(http.Header)
+	s.headers <- headers
+
+	c := make(chan any, len(s.results))
+	for _, result := range s.results {
+		c <- result
+	}
+	close(c)
+	return c, nil
+}
+
+func dialTestServer(t *testing.T, service GraphQLService, subprotocol string, header http.Header, options ...func(conn *connection)) *websocket.Conn {
+	server := httptest.NewServer(NewHandlerFunc(service, http.NotFoundHandler(), options...))
+	t.Cleanup(server.Close)
+
+	dialer := websocket.Dialer{Subprotocols: []string{subprotocol}}
+	ws, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+	require.NoError(t, err)
+	t.Cleanup(func() { ws.Close() })
+	require.Equal(t, subprotocol, ws.Subprotocol())
+	return ws
+}
+
+func sendMessage(t *testing.T, ws *websocket.Conn, msg string) {
+	require.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte(msg)))
+}
+
+func readMessage(t *testing.T, ws *websocket.Conn) (msg operationMessage) {
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	require.NoError(t, ws.ReadJSON(&msg))
+	return msg
+}
+
+func readCloseCode(t *testing.T, ws *websocket.Conn) int {
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := ws.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	return closeErr.Code
+}
+
+func TestConnectTransportWS(t *testing.T) {
+	result := map[string]any{"data": map[string]any{"hello": "world"}}
+
+	t.Run("connection init, subscribe and complete", func(t *testing.T) {
+		service := newFakeService(result, result)
+		ws := dialTestServer(t, service, protocolGraphQLTransportWS, http.Header{"X-Real-Ip": {"10.0.0.1"}})
+
+		sendMessage(t, ws, `{"type": "connection_init", "payload": {"authorization": "Bearer token", "X-Real-IP": "1.1.1.1"}}`)
+		assert.Equal(t, typeConnectionAck, readMessage(t, ws).Type)
+
+		sendMessage(t, ws, `{"id": "1", "type": "subscribe", "payload": {"query": "subscription { hello }"}}`)
+		for range 2 {
+			msg := readMessage(t, ws)
+			assert.Equal(t, "1", msg.ID)
+			assert.Equal(t, typeNext, msg.Type)
+			assert.JSONEq(t, `{"data": {"hello": "world"}}`, string(msg.Payload))
+		}
+		msg := readMessage(t, ws)
+		assert.Equal(t, "1", msg.ID)
+		assert.Equal(t, typeComplete, msg.Type)
+
+		// only allowed header is merged, header from upgrade request is not overridden
+		headers := <-service.headers
+		assert.Equal(t, "Bearer token", headers.Get("Authorization"))
+		assert.Equal(t, "10.0.0.1", headers.Get("X-Real-IP"))
+	})
+
+	t.Run("authorization from upgrade request is not overridden", func(t *testing.T) {
+		service := newFakeService(result)
+		ws := dialTestServer(t, service, protocolGraphQLTransportWS, http.Header{"Authorization": {"Bearer upgrade"}})
+
+		sendMessage(t, ws, `{"type": "connection_init", "payload": {"headers": {"Authorization": "Bearer init"}}}`)
+		assert.Equal(t, typeConnectionAck, readMessage(t, ws).Type)
+		sendMessage(t, ws, `{"id": "1", "type": "subscribe", "payload": {"query": "subscription { hello }"}}`)
+		assert.Equal(t, typeNext, readMessage(t, ws).Type)
+
+		assert.Equal(t, "Bearer upgrade", (<-service.headers).Get("Authorization"))
+	})
+
+	t.Run("ping pong", func(t *testing.T) {
+		ws := dialTestServer(t, newFakeService(), protocolGraphQLTransportWS, nil)
+
+		sendMessage(t, ws, `{"type": "ping", "payload": {"key": "value"}}`)
+		msg := readMessage(t, ws)
+		assert.Equal(t, typePong, msg.Type)
+		assert.JSONEq(t, `{"key": "value"}`, string(msg.Payload))
+	})
+
+	t.Run("subscribe before connection init", func(t *testing.T) {
+		ws := dialTestServer(t, newFakeService(), protocolGraphQLTransportWS, nil)
+
+		sendMessage(t, ws, `{"id": "1", "type": "subscribe", "payload": {"query": "subscription { hello }"}}`)
+		assert.Equal(t, closeCodeUnauthorized, readCloseCode(t, ws))
+	})
+
+	t.Run("too many connection init", func(t *testing.T) {
+		ws := dialTestServer(t, newFakeService(), protocolGraphQLTransportWS, nil)
+
+		sendMessage(t, ws, `{"type": "connection_init"}`)
+		assert.Equal(t, typeConnectionAck, readMessage(t, ws).Type)
+		sendMessage(t, ws, `{"type": "connection_init"}`)
+		assert.Equal(t, closeCodeTooManyInitialisation, readCloseCode(t, ws))
+	})
+
+	t.Run("connection init timeout", func(t *testing.T) {
+		ws := dialTestServer(t, newFakeService(), protocolGraphQLTransportWS, nil, ConnectionInitTimeout(10*time.Millisecond))
+
+		assert.Equal(t, closeCodeInitTimeout, readCloseCode(t, ws))
+	})
+}
+
+func TestConnectLegacy(t *testing.T) {
+	service := newFakeService(map[string]any{"data": map[string]any{"hello": "world"}})
+	ws := dialTestServer(t, service, protocolGraphQLWS, nil)
+
+	sendMessage(t, ws, `{"type": "connection_init", "payload": {"Authorization": "Bearer token"}}`)
+	assert.Equal(t, typeConnectionAck, readMessage(t, ws).Type)
+
+	sendMessage(t, ws, `{"id": "1", "type": "start", "payload": {"query": "subscription { hello }"}}`)
+	msg := readMessage(t, ws)
+	assert.Equal(t, typeData, msg.Type)
+	assert.JSONEq(t, `{"data": {"hello": "world"}}`, string(msg.Payload))
+	assert.Equal(t, typeComplete, readMessage(t, ws).Type)
+
+	// connection_init payload is not merged to header in legacy protocol
+	assert.Empty(t, (<-service.headers).Get("Authorization"))
+}
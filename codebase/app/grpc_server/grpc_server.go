@@ -12,37 +12,51 @@ import (
 	"github.com/golangid/candi/logger"
 	"github.com/soheilhy/cmux"
 	"google.golang.org/grpc"
+	channelzsvc "google.golang.org/grpc/channelz/service"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 type grpcServer struct {
-	opt          option
-	serverEngine *grpc.Server
-	listener     net.Listener
-	service      factory.ServiceFactory
+	opt           option
+	serverEngine  *grpc.Server
+	listener      net.Listener
+	service       factory.ServiceFactory
+	healthChecker *healthChecker
 }
 
 // NewServer create new GRPC server
 func NewServer(service factory.ServiceFactory, opts ...OptionFunc) factory.AppServerFactory {
-	intercept := new(interceptor)
 	serverOpt := getDefaultOption()
 	for _, opt := range opts {
 		opt(&serverOpt)
 	}
+	intercept := new(interceptor)
+	if serverOpt.enableValidation && service.GetDependency() != nil {
+		intercept.validator = service.GetDependency().GetValidator()
+	}
 	serverOpt.serverOptions = append(serverOpt.serverOptions,
-		grpc.UnaryInterceptor(chainUnaryServer(
+		grpc.UnaryInterceptor(chainUnaryServer(append([]grpc.UnaryServerInterceptor{
 			intercept.unaryTracerInterceptor,
+			intercept.unaryRecoveryInterceptor,
+			intercept.unaryRateLimitInterceptor,
 			intercept.unaryMiddlewareInterceptor,
-		)),
-		grpc.StreamInterceptor(chainStreamServer(
+			intercept.unaryValidationInterceptor,
+		}, serverOpt.unaryInterceptors...)...)),
+		grpc.StreamInterceptor(chainStreamServer(append([]grpc.StreamServerInterceptor{
 			intercept.streamTracerInterceptor,
+			intercept.streamRecoveryInterceptor,
+			intercept.streamRateLimitInterceptor,
 			intercept.streamMiddlewareInterceptor,
-		)))
+			intercept.streamValidationInterceptor,
+		}, serverOpt.streamInterceptors...)...)))
 
 	server := &grpcServer{
 		serverEngine: grpc.NewServer(serverOpt.serverOptions...),
 		service:      service,
 		opt:          serverOpt,
 	}
+	intercept.opt = &server.opt
 
 	grpcPort := server.opt.tcpPort
 	if server.opt.sharedListener == nil {
@@ -65,13 +79,27 @@ func NewServer(service factory.ServiceFactory, opts ...OptionFunc) factory.AppSe
 
 	// register all module
 	intercept.middleware = make(types.MiddlewareGroup)
-	intercept.opt = &server.opt
 	for _, m := range service.GetModules() {
 		if h := m.GRPCHandler(); h != nil {
 			h.Register(server.serverEngine, &intercept.middleware)
 		}
 	}
 
+	moduleServices := server.serverEngine.GetServiceInfo()
+	if server.opt.enableHealthService {
+		server.healthChecker = newHealthChecker(service.GetDependency(), server.opt.healthCheckInterval)
+		for name := range moduleServices {
+			server.healthChecker.services = append(server.healthChecker.services, name)
+		}
+		healthpb.RegisterHealthServer(server.serverEngine, server.healthChecker.server)
+	}
+	if server.opt.enableReflection {
+		reflection.Register(server.serverEngine)
+	}
+	if server.opt.enableChannelz {
+		channelzsvc.RegisterChannelzServiceToServer(server.serverEngine)
+	}
+
 	for root, info := range server.serverEngine.GetServiceInfo() {
 		for _, method := range info.Methods {
 			logger.LogGreen(fmt.Sprintf("[GRPC-METHOD] /%s/%s \t\t[metadata]--> %v", root, method.Name, info.Metadata))
@@ -83,6 +111,9 @@ func NewServer(service factory.ServiceFactory, opts ...OptionFunc) factory.AppSe
 }
 
 func (s *grpcServer) Serve() {
+	if s.healthChecker != nil {
+		s.healthChecker.run()
+	}
 	if err := s.serverEngine.Serve(s.listener); err != nil {
 		log.Println("GRPC: Unexpected Error", err)
 	}
@@ -91,10 +122,18 @@ func (s *grpcServer) Serve() {
 func (s *grpcServer) Shutdown(ctx context.Context) {
 	defer log.Println("\x1b[33;1mStopping GRPC server:\x1b[0m \x1b[32;1mSUCCESS\x1b[0m")
 
+	if s.healthChecker != nil {
+		s.healthChecker.shutdown()
+	}
 	s.serverEngine.GracefulStop()
 	s.listener.Close()
 }
 
+// ServerEngine return grpc server engine, used by REST server for gRPC transcoding
+func (s *grpcServer) ServerEngine() *grpc.Server {
+	return s.serverEngine
+}
+
 func (s *grpcServer) Name() string {
 	return string(types.GRPC)
 }
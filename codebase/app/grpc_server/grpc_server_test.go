@@ -0,0 +1,237 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golangid/candi/codebase/factory"
+	"github.com/golangid/candi/codebase/factory/dependency"
+	"github.com/golangid/candi/codebase/factory/types"
+	"github.com/golangid/candi/codebase/interfaces"
+	mockfactory "github.com/golangid/candi/mocks/codebase/factory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const testServiceName = "candi.test.EchoService"
+
+type (
+	// fakeDependency dependency with sql database only, health of database can be changed with setHealth
+	fakeDependency struct {
+		dependency.Dependency
+		db        *fakeDatabase
+		validator interfaces.Validator
+	}
+	fakeDatabase struct {
+		interfaces.SQLDatabase
+		mu  sync.Mutex
+		err error
+	}
+
+	// echoServer handler of test echo service, unary and bidirectional stream reply the received value
+	echoServer struct {
+		unary  func(ctx context.Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error)
+		stream func(stream grpc.ServerStream) error
+	}
+	echoHandler struct {
+		server      *echoServer
+		middlewares types.MiddlewareGroup
+	}
+)
+
+func (f *fakeDependency) GetSQLDatabase() interfaces.SQLDatabase     { return f.db }
+func (f *fakeDependency) GetMongoDatabase() interfaces.MongoDatabase { return nil }
+func (f *fakeDependency) GetRedisPool() interfaces.RedisPool         { return nil }
+func (f *fakeDependency) GetValidator() interfaces.Validator         { return f.validator }
+func (f *fakeDependency) FetchBroker(func(types.Worker, interfaces.Broker)) {
+}
+
+func (f *fakeDatabase) Health() map[string]error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return map[string]error{"sql": f.err}
+}
+
+func (f *fakeDatabase) setHealth(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+var echoServiceDesc = grpc.ServiceDesc{
+	ServiceName: testServiceName,
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Echo",
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			in := new(wrapperspb.StringValue)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req any) (any, error) {
+				return srv.(*echoServer).echo(ctx, req.(*wrapperspb.StringValue))
+			}
+			return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + testServiceName + "/Echo"}, handler)
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "EchoStream",
+		ServerStreams: true,
+		ClientStreams: true,
+		Handler: func(srv any, stream grpc.ServerStream) error {
+			return srv.(*echoServer).echoStream(stream)
+		},
+	}},
+}
+
+func (s *echoServer) echo(ctx context.Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	if s.unary != nil {
+		return s.unary(ctx, in)
+	}
+	return wrapperspb.String(in.GetValue()), nil
+}
+
+func (s *echoServer) echoStream(stream grpc.ServerStream) error {
+	if s.stream != nil {
+		return s.stream(stream)
+	}
+	for {
+		in := new(wrapperspb.StringValue)
+		if err := stream.RecvMsg(in); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := stream.SendMsg(wrapperspb.String(in.GetValue())); err != nil {
+			return err
+		}
+	}
+}
+
+func (h *echoHandler) Register(server *grpc.Server, middlewareGroup *types.MiddlewareGroup) {
+	server.RegisterService(&echoServiceDesc, h.server)
+	for fullMethod, middlewares := range h.middlewares {
+		middlewareGroup.Add(fullMethod, middlewares...)
+	}
+}
+
+// newTestService service with echo service module
+func newTestService(deps dependency.Dependency, handler *echoHandler) *mockfactory.ServiceFactory {
+	module := &mockfactory.ModuleFactory{}
+	module.On("GRPCHandler").Return(handler)
+	service := &mockfactory.ServiceFactory{}
+	service.On("GetDependency").Return(deps)
+	service.On("GetModules").Return([]factory.ModuleFactory{module})
+	return service
+}
+
+// newTestServer serve grpc server with in memory listener
+func newTestServer(t *testing.T, service factory.ServiceFactory, opts ...OptionFunc) (*grpcServer, *grpc.ClientConn) {
+	server := NewServer(service, append([]OptionFunc{SetTCPPort(0), SetDebugMode(false)}, opts...)...).(*grpcServer)
+	server.listener.Close()
+	listener := bufconn.Listen(1 << 20)
+	server.listener = listener
+	go server.Serve()
+	t.Cleanup(func() { server.Shutdown(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return server, conn
+}
+
+func TestHealthService(t *testing.T) {
+	deps := &fakeDependency{db: &fakeDatabase{}}
+	service := newTestService(deps, &echoHandler{server: &echoServer{}})
+	_, conn := newTestServer(t, service, SetEnableHealthService(true), SetHealthCheckInterval(10*time.Millisecond))
+	client := healthpb.NewHealthClient(conn)
+
+	servingStatus := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return healthpb.HealthCheckResponse_UNKNOWN
+		}
+		return resp.GetStatus()
+	}
+	hasStatus := func(want healthpb.HealthCheckResponse_ServingStatus) func() bool {
+		return func() bool { return servingStatus("") == want && servingStatus(testServiceName) == want }
+	}
+
+	require.Eventually(t, hasStatus(healthpb.HealthCheckResponse_SERVING), time.Second, 5*time.Millisecond)
+
+	t.Run("dependency is not healthy", func(t *testing.T) {
+		watch, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{Service: testServiceName})
+		require.NoError(t, err)
+		resp, err := watch.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+
+		deps.db.setHealth(errors.New("connection refused"))
+		resp, err = watch.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.GetStatus())
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(""))
+
+		deps.db.setHealth(nil)
+		resp, err = watch.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(""))
+	})
+
+	t.Run("unknown service", func(t *testing.T) {
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestNewServerServices(t *testing.T) {
+	const (
+		healthService     = "grpc.health.v1.Health"
+		reflectionService = "grpc.reflection.v1.ServerReflection"
+		channelzService   = "grpc.channelz.v1.Channelz"
+	)
+	tests := []struct {
+		name         string
+		opts         []OptionFunc
+		wantServices []string
+	}{
+		{name: "default", wantServices: []string{testServiceName}},
+		{name: "health service", opts: []OptionFunc{SetEnableHealthService(true)}, wantServices: []string{testServiceName, healthService}},
+		{name: "reflection", opts: []OptionFunc{SetEnableReflection(true)}, wantServices: []string{testServiceName, reflectionService}},
+		{name: "channelz", opts: []OptionFunc{SetEnableChannelz(true)}, wantServices: []string{testServiceName, channelzService}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(&fakeDependency{db: &fakeDatabase{}}, &echoHandler{server: &echoServer{}})
+			server, _ := newTestServer(t, service, tt.opts...)
+
+			services := server.ServerEngine().GetServiceInfo()
+			for _, name := range tt.wantServices {
+				assert.Contains(t, services, name)
+			}
+			for _, name := range []string{healthService, reflectionService, channelzService} {
+				if !slices.Contains(tt.wantServices, name) {
+					assert.NotContains(t, services, name)
+				}
+			}
+			assert.Equal(t, slices.Contains(tt.wantServices, healthService), server.healthChecker != nil)
+		})
+	}
+}
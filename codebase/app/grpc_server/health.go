@@ -0,0 +1,73 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golangid/candi/codebase/factory/dependency"
+	"github.com/golangid/candi/logger"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthChecker update grpc.health.v1 serving status from dependency health
+type healthChecker struct {
+	server   *health.Server
+	deps     dependency.Dependency
+	services []string
+	interval time.Duration
+	cancel   func()
+}
+
+func newHealthChecker(deps dependency.Dependency, interval time.Duration) *healthChecker {
+	return &healthChecker{
+		server:   health.NewServer(),
+		deps:     deps,
+		interval: interval,
+	}
+}
+
+func (h *healthChecker) run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+
+	h.check()
+	ticker := time.NewTicker(h.interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				h.check()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (h *healthChecker) check() {
+	status := healthpb.HealthCheckResponse_SERVING
+	if h.deps != nil {
+		for name, err := range dependency.GetHealth(h.deps) {
+			if err != nil {
+				status = healthpb.HealthCheckResponse_NOT_SERVING
+				logger.LogRed(fmt.Sprintf("GRPC health check: dependency %s is not healthy: %s", name, err.Error()))
+			}
+		}
+	}
+
+	// empty service name is overall server status
+	h.server.SetServingStatus("", status)
+	for _, service := range h.services {
+		h.server.SetServingStatus(service, status)
+	}
+}
+
+func (h *healthChecker) shutdown() {
+	if h.cancel != nil {
+		h.cancel()
+	}
+	h.server.Shutdown()
+}
@@ -3,13 +3,17 @@ package grpcserver
 import (
 	"context"
 	"fmt"
+	"math"
 	"os"
+	"runtime"
 	"strconv"
 	"time"
 
 	"github.com/golangid/candi/candihelper"
 	"github.com/golangid/candi/candishared"
 	"github.com/golangid/candi/codebase/factory/types"
+	"github.com/golangid/candi/codebase/interfaces"
+	"github.com/golangid/candi/logger"
 	"github.com/golangid/candi/tracer"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -20,6 +24,7 @@ import (
 type interceptor struct {
 	middleware types.MiddlewareGroup
 	opt        *option
+	validator  interfaces.Validator
 }
 
 // for unary server
@@ -70,7 +75,7 @@ func (i *interceptor) unaryTracerInterceptor(ctx context.Context, req any, info
 	defer func() {
 		if rec := recover(); rec != nil {
 			trace.SetTag("panic", true)
-			err = status.Errorf(codes.Aborted, "%v", rec)
+			err = status.Errorf(codes.Internal, "%v", rec)
 		}
 		i.logInterceptor(start, err, info.FullMethod, "GRPC")
 		trace.Finish(tracer.FinishWithError(err))
@@ -93,6 +98,33 @@ func (i *interceptor) unaryTracerInterceptor(ctx context.Context, req any, info
 	return
 }
 
+// unaryRecoveryInterceptor recover panic in handler to codes.Internal error with stack trace in span
+func (i *interceptor) unaryRecoveryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = i.recoverPanic(ctx, info.FullMethod, rec)
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+func (i *interceptor) unaryRateLimitInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	if err := i.rateLimitInterceptor(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (i *interceptor) unaryValidationInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	if err := i.validateRequest(ctx, req); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
 func (i *interceptor) unaryMiddlewareInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
 	ctx, err = i.middlewareInterceptor(ctx, info.FullMethod)
 	if err != nil {
@@ -153,7 +185,7 @@ func (i *interceptor) streamTracerInterceptor(srv any, stream grpc.ServerStream,
 	defer func() {
 		if rec := recover(); rec != nil {
 			trace.SetTag("panic", true)
-			err = status.Errorf(codes.Aborted, "%v", rec)
+			err = status.Errorf(codes.Internal, "%v", rec)
 		}
 		i.logInterceptor(start, err, info.FullMethod, "GRPC:STREAM")
 		trace.Finish(tracer.FinishWithError(err))
@@ -165,6 +197,33 @@ func (i *interceptor) streamTracerInterceptor(srv any, stream grpc.ServerStream,
 	return
 }
 
+// streamRecoveryInterceptor recover panic in handler to codes.Internal error with stack trace in span
+func (i *interceptor) streamRecoveryInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = i.recoverPanic(stream.Context(), info.FullMethod, rec)
+		}
+	}()
+
+	return handler(srv, stream)
+}
+
+func (i *interceptor) streamRateLimitInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	if err := i.rateLimitInterceptor(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+
+	return handler(srv, stream)
+}
+
+func (i *interceptor) streamValidationInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	if !i.opt.enableValidation {
+		return handler(srv, stream)
+	}
+
+	return handler(srv, &validatedServerStream{ServerStream: stream, interceptor: i})
+}
+
 func (i *interceptor) streamMiddlewareInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
 	ctx, err := i.middlewareInterceptor(stream.Context(), info.FullMethod)
 	if err != nil {
@@ -189,6 +248,71 @@ func (i *interceptor) middlewareInterceptor(ctx context.Context, fullMethod stri
 	return ctx, nil
 }
 
+func (i *interceptor) recoverPanic(ctx context.Context, fullMethod string, rec any) error {
+	const size = 4 << 10
+	stackTrace := make([]byte, size)
+	stackTrace = stackTrace[:runtime.Stack(stackTrace, false)]
+
+	tracer.Log(ctx, "panic", rec)
+	tracer.Log(ctx, "stacktrace", stackTrace)
+	logger.LogE(fmt.Sprintf("GRPC: panic in %s: %v\n%s", fullMethod, rec, stackTrace))
+	return status.Errorf(codes.Internal, "panic: %v", rec)
+}
+
+func (i *interceptor) rateLimitInterceptor(ctx context.Context, fullMethod string) error {
+	limiter, ok := i.opt.rateLimiters[fullMethod]
+	if !ok {
+		if limiter, ok = i.opt.rateLimiters["*"]; !ok {
+			return nil
+		}
+	}
+
+	key := fullMethod
+	if i.opt.rateLimitKeyFunc != nil {
+		key = i.opt.rateLimitKeyFunc(ctx, fullMethod)
+	}
+	res, err := limiter.Allow(ctx, key)
+	if err != nil {
+		// fail open when rate limiter backend is unavailable
+		tracer.Log(ctx, "rate_limiter_error", err)
+		return nil
+	}
+
+	grpc.SetHeader(ctx, metadata.Pairs(
+		"ratelimit-limit", strconv.Itoa(res.Limit),
+		"ratelimit-remaining", strconv.Itoa(res.Remaining),
+	))
+	if !res.Allowed {
+		retryAfter := strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds())))
+		grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter))
+		return status.Errorf(codes.ResourceExhausted, "rate limit exceeded for %s, retry after %ss", fullMethod, retryAfter)
+	}
+	return nil
+}
+
+// validateRequest validate with Validate() method (ex: generated from protoc-gen-validate), or with dependency validator
+func (i *interceptor) validateRequest(ctx context.Context, req any) (err error) {
+	if !i.opt.enableValidation {
+		return nil
+	}
+
+	switch v := req.(type) {
+	case interface{ ValidateAll() error }:
+		err = v.ValidateAll()
+	case interface{ Validate() error }:
+		err = v.Validate()
+	default:
+		if i.validator != nil {
+			err = i.validator.ValidateStruct(req)
+		}
+	}
+	if err != nil {
+		tracer.Log(ctx, "validation_error", err)
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
 // Log incoming grpc request
 func (i *interceptor) logInterceptor(startTime time.Time, err error, fullMethod string, reqType string) {
 	if !i.opt.debugMode {
@@ -226,3 +350,17 @@ type wrappedServerStream struct {
 func (w *wrappedServerStream) Context() context.Context {
 	return w.wrappedContext
 }
+
+// validatedServerStream for validate every received message in stream
+type validatedServerStream struct {
+	grpc.ServerStream
+	interceptor *interceptor
+}
+
+// RecvMsg receive and validate message
+func (v *validatedServerStream) RecvMsg(m any) error {
+	if err := v.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return v.interceptor.validateRequest(v.Context(), m)
+}
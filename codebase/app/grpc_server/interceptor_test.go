@@ -0,0 +1,325 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/codebase/factory/types"
+	"github.com/golangid/candi/codebase/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type (
+	// recorder record called interceptor and handler in order
+	recorder struct {
+		mu    sync.Mutex
+		calls []string
+	}
+
+	// fakeRateLimiter allow first n request for every key, return err if set
+	fakeRateLimiter struct {
+		recorder *recorder
+		limit    int
+		err      error
+		mu       sync.Mutex
+		counts   map[string]int
+	}
+
+	// fakeValidator reject value "invalid"
+	fakeValidator struct {
+		interfaces.Validator
+		recorder *recorder
+	}
+
+	validateAllMessage struct{}
+	validateMessage    struct{}
+)
+
+func (r *recorder) record(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.calls...)
+}
+
+func (f *fakeRateLimiter) Allow(ctx context.Context, key string) (candishared.RateLimitResult, error) {
+	f.recorder.record("ratelimit")
+	if f.err != nil {
+		return candishared.RateLimitResult{}, f.err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.counts == nil {
+		f.counts = map[string]int{}
+	}
+	f.counts[key]++
+	if f.counts[key] > f.limit {
+		return candishared.RateLimitResult{Limit: f.limit, RetryAfter: 1500 * time.Millisecond}, nil
+	}
+	return candishared.RateLimitResult{Allowed: true, Limit: f.limit, Remaining: f.limit - f.counts[key]}, nil
+}
+
+func (f *fakeValidator) ValidateStruct(data any) error {
+	f.recorder.record("validation")
+	if data.(*wrapperspb.StringValue).GetValue() == "invalid" {
+		return errors.New("value is invalid")
+	}
+	return nil
+}
+
+func (validateAllMessage) ValidateAll() error { return errors.New("all errors") }
+func (validateAllMessage) Validate() error    { return errors.New("first error") }
+func (validateMessage) Validate() error       { return errors.New("first error") }
+
+// newInterceptorTestServer serve echo service with rate limiter, middleware and validator which is recorded in rec
+func newInterceptorTestServer(t *testing.T, rec *recorder, server *echoServer, opts ...OptionFunc) *grpc.ClientConn {
+	middleware := func(ctx context.Context) (context.Context, error) {
+		rec.record("middleware")
+		if md, _ := metadata.FromIncomingContext(ctx); len(md.Get("authorization")) == 0 {
+			return ctx, status.Error(codes.Unauthenticated, "missing authorization")
+		}
+		return ctx, nil
+	}
+	handler := &echoHandler{server: server, middlewares: types.MiddlewareGroup{
+		"/" + testServiceName + "/Echo":       {middleware},
+		"/" + testServiceName + "/EchoStream": {middleware},
+	}}
+	deps := &fakeDependency{db: &fakeDatabase{}, validator: &fakeValidator{recorder: rec}}
+	opts = append([]OptionFunc{SetEnableRequestValidation(true)}, opts...)
+	_, conn := newTestServer(t, newTestService(deps, handler), opts...)
+	return conn
+}
+
+func authorizedContext() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer token")
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	echo := func(conn *grpc.ClientConn, ctx context.Context, value string) (string, metadata.MD, error) {
+		var header metadata.MD
+		out := new(wrapperspb.StringValue)
+		err := conn.Invoke(ctx, "/"+testServiceName+"/Echo", wrapperspb.String(value), out, grpc.Header(&header))
+		return out.GetValue(), header, err
+	}
+
+	t.Run("interceptor order", func(t *testing.T) {
+		rec := &recorder{}
+		server := &echoServer{unary: func(ctx context.Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+			rec.record("handler")
+			return in, nil
+		}}
+		custom := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			rec.record("custom")
+			return handler(ctx, req)
+		}
+		conn := newInterceptorTestServer(t, rec, server,
+			AddRateLimiter("*", &fakeRateLimiter{recorder: rec, limit: 10}), AddUnaryInterceptors(custom))
+
+		value, _, err := echo(conn, authorizedContext(), "candi")
+		assert.NoError(t, err)
+		assert.Equal(t, "candi", value)
+		assert.Equal(t, []string{"ratelimit", "middleware", "validation", "custom", "handler"}, rec.get())
+
+		_, _, err = echo(conn, context.Background(), "candi")
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Equal(t, []string{"ratelimit", "middleware"}, rec.get()[5:])
+
+		_, _, err = echo(conn, authorizedContext(), "invalid")
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, []string{"ratelimit", "middleware", "validation"}, rec.get()[7:])
+	})
+
+	t.Run("recover panic", func(t *testing.T) {
+		server := &echoServer{unary: func(ctx context.Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+			panic("something went wrong")
+		}}
+		panicInterceptor := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if req.(*wrapperspb.StringValue).GetValue() == "interceptor" {
+				panic("panic in interceptor")
+			}
+			return handler(ctx, req)
+		}
+		conn := newInterceptorTestServer(t, &recorder{}, server, AddUnaryInterceptors(panicInterceptor))
+
+		_, _, err := echo(conn, authorizedContext(), "handler")
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.Equal(t, "panic: something went wrong", status.Convert(err).Message())
+
+		_, _, err = echo(conn, authorizedContext(), "interceptor")
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.Equal(t, "panic: panic in interceptor", status.Convert(err).Message())
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		conn := newInterceptorTestServer(t, &recorder{}, &echoServer{},
+			AddRateLimiter("/"+testServiceName+"/Echo", &fakeRateLimiter{recorder: &recorder{}, limit: 1}))
+
+		_, header, err := echo(conn, authorizedContext(), "candi")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1"}, header.Get("ratelimit-limit"))
+		assert.Equal(t, []string{"0"}, header.Get("ratelimit-remaining"))
+		assert.Empty(t, header.Get("retry-after"))
+
+		_, header, err = echo(conn, authorizedContext(), "candi")
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, []string{"1"}, header.Get("ratelimit-limit"))
+		assert.Equal(t, []string{"0"}, header.Get("ratelimit-remaining"))
+		assert.Equal(t, []string{"2"}, header.Get("retry-after"))
+	})
+
+	t.Run("rate limit with key func", func(t *testing.T) {
+		keyFunc := func(ctx context.Context, fullMethod string) string {
+			md, _ := metadata.FromIncomingContext(ctx)
+			return fullMethod + ":" + md.Get("x-client-id")[0]
+		}
+		conn := newInterceptorTestServer(t, &recorder{}, &echoServer{},
+			AddRateLimiter("*", &fakeRateLimiter{recorder: &recorder{}, limit: 1}), SetRateLimitKeyFunc(keyFunc))
+
+		for _, clientID := range []string{"a", "b"} {
+			_, _, err := echo(conn, metadata.AppendToOutgoingContext(authorizedContext(), "x-client-id", clientID), "candi")
+			assert.NoError(t, err)
+		}
+		_, _, err := echo(conn, metadata.AppendToOutgoingContext(authorizedContext(), "x-client-id", "a"), "candi")
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("rate limiter is unavailable", func(t *testing.T) {
+		rec := &recorder{}
+		conn := newInterceptorTestServer(t, rec, &echoServer{},
+			AddRateLimiter("*", &fakeRateLimiter{recorder: rec, err: errors.New("connection refused")}))
+
+		value, header, err := echo(conn, authorizedContext(), "candi")
+		assert.NoError(t, err)
+		assert.Equal(t, "candi", value)
+		assert.Empty(t, header.Get("ratelimit-limit"))
+		assert.Equal(t, []string{"ratelimit", "middleware", "validation"}, rec.get())
+	})
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	const fullMethod = "/" + testServiceName + "/EchoStream"
+	newStream := func(t *testing.T, conn *grpc.ClientConn, ctx context.Context) grpc.ClientStream {
+		stream, err := conn.NewStream(ctx, &echoServiceDesc.Streams[0], fullMethod)
+		require.NoError(t, err)
+		return stream
+	}
+	echo := func(stream grpc.ClientStream, value string) (string, error) {
+		if err := stream.SendMsg(wrapperspb.String(value)); err != nil && err != io.EOF {
+			return "", err
+		}
+		out := new(wrapperspb.StringValue)
+		err := stream.RecvMsg(out)
+		return out.GetValue(), err
+	}
+
+	t.Run("interceptor order", func(t *testing.T) {
+		rec := &recorder{}
+		custom := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			rec.record("custom")
+			return handler(srv, ss)
+		}
+		conn := newInterceptorTestServer(t, rec, &echoServer{},
+			AddRateLimiter("*", &fakeRateLimiter{recorder: rec, limit: 10}), AddStreamInterceptors(custom))
+
+		stream := newStream(t, conn, authorizedContext())
+		value, err := echo(stream, "candi")
+		assert.NoError(t, err)
+		assert.Equal(t, "candi", value)
+		assert.Equal(t, []string{"ratelimit", "middleware", "custom", "validation"}, rec.get())
+
+		// every received message is validated
+		value, err = echo(stream, "stream")
+		assert.NoError(t, err)
+		assert.Equal(t, "stream", value)
+		_, err = echo(stream, "invalid")
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, []string{"validation", "validation"}, rec.get()[4:])
+
+		_, err = echo(newStream(t, conn, context.Background()), "candi")
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("recover panic", func(t *testing.T) {
+		server := &echoServer{stream: func(stream grpc.ServerStream) error {
+			panic("something went wrong")
+		}}
+		conn := newInterceptorTestServer(t, &recorder{}, server)
+
+		_, err := echo(newStream(t, conn, authorizedContext()), "candi")
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.Equal(t, "panic: something went wrong", status.Convert(err).Message())
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		conn := newInterceptorTestServer(t, &recorder{}, &echoServer{},
+			AddRateLimiter(fullMethod, &fakeRateLimiter{recorder: &recorder{}, limit: 1}))
+
+		stream := newStream(t, conn, authorizedContext())
+		_, err := echo(stream, "candi")
+		assert.NoError(t, err)
+		header, _ := stream.Header()
+		assert.Equal(t, []string{"1"}, header.Get("ratelimit-limit"))
+		assert.Equal(t, []string{"0"}, header.Get("ratelimit-remaining"))
+
+		stream = newStream(t, conn, authorizedContext())
+		_, err = echo(stream, "candi")
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		header, _ = stream.Header()
+		assert.Equal(t, []string{"2"}, header.Get("retry-after"))
+	})
+
+	t.Run("rate limiter is unavailable", func(t *testing.T) {
+		conn := newInterceptorTestServer(t, &recorder{}, &echoServer{},
+			AddRateLimiter("*", &fakeRateLimiter{recorder: &recorder{}, err: errors.New("connection refused")}))
+
+		value, err := echo(newStream(t, conn, authorizedContext()), "candi")
+		assert.NoError(t, err)
+		assert.Equal(t, "candi", value)
+	})
+}
+
+func TestValidateRequest(t *testing.T) {
+	rec := &recorder{}
+	tests := []struct {
+		name             string
+		enableValidation bool
+		validator        interfaces.Validator
+		req              any
+		wantErr          string
+	}{
+		{name: "validate all", enableValidation: true, req: validateAllMessage{}, wantErr: "all errors"},
+		{name: "validate", enableValidation: true, req: validateMessage{}, wantErr: "first error"},
+		{name: "validator", enableValidation: true, validator: &fakeValidator{recorder: rec}, req: wrapperspb.String("invalid"), wantErr: "value is invalid"},
+		{name: "valid struct", enableValidation: true, validator: &fakeValidator{recorder: rec}, req: wrapperspb.String("candi")},
+		{name: "without validator", enableValidation: true, req: wrapperspb.String("invalid")},
+		{name: "validation is disabled", req: validateAllMessage{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &interceptor{opt: &option{enableValidation: tt.enableValidation}, validator: tt.validator}
+			err := i.validateRequest(context.Background(), tt.req)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			assert.Equal(t, tt.wantErr, status.Convert(err).Message())
+		})
+	}
+}
@@ -1,11 +1,13 @@
 package grpcserver
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"time"
 
 	"github.com/golangid/candi/candihelper"
+	"github.com/golangid/candi/codebase/interfaces"
 	"github.com/soheilhy/cmux"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/keepalive"
@@ -19,6 +21,17 @@ type (
 		sharedListener cmux.CMux
 		serverOptions  []grpc.ServerOption
 		tlsConfig      *tls.Config
+
+		enableHealthService bool
+		healthCheckInterval time.Duration
+		enableReflection    bool
+		enableChannelz      bool
+
+		rateLimiters       map[string]interfaces.RateLimiter
+		rateLimitKeyFunc   func(ctx context.Context, fullMethod string) string
+		enableValidation   bool
+		unaryInterceptors  []grpc.UnaryServerInterceptor
+		streamInterceptors []grpc.StreamServerInterceptor
 	}
 
 	// OptionFunc type
@@ -27,8 +40,9 @@ type (
 
 func getDefaultOption() option {
 	return option{
-		tcpPort:   ":8002",
-		debugMode: true,
+		tcpPort:             ":8002",
+		debugMode:           true,
+		healthCheckInterval: 10 * time.Second,
 		serverOptions: []grpc.ServerOption{
 			grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
 				MinTime:             5 * time.Second, // If a client pings more than once every 5 seconds, terminate the connection
@@ -86,3 +100,72 @@ func SetTLSConfig(tlsConfig *tls.Config) OptionFunc {
 		o.tlsConfig = tlsConfig
 	}
 }
+
+// SetEnableHealthService option func, register grpc.health.v1 service with serving status derived from dependency health
+func SetEnableHealthService(enable bool) OptionFunc {
+	return func(o *option) {
+		o.enableHealthService = enable
+	}
+}
+
+// SetHealthCheckInterval option func, interval for update serving status in grpc.health.v1 service
+func SetHealthCheckInterval(interval time.Duration) OptionFunc {
+	return func(o *option) {
+		if interval > 0 {
+			o.healthCheckInterval = interval
+		}
+	}
+}
+
+// SetEnableReflection option func, register grpc server reflection service
+func SetEnableReflection(enable bool) OptionFunc {
+	return func(o *option) {
+		o.enableReflection = enable
+	}
+}
+
+// SetEnableChannelz option func, register grpc channelz service
+func SetEnableChannelz(enable bool) OptionFunc {
+	return func(o *option) {
+		o.enableChannelz = enable
+	}
+}
+
+// AddRateLimiter option func, add rate limiter for grpc full method (ex: "/package.Service/Method"), use "*" for all method
+func AddRateLimiter(fullMethod string, limiter interfaces.RateLimiter) OptionFunc {
+	return func(o *option) {
+		if o.rateLimiters == nil {
+			o.rateLimiters = make(map[string]interfaces.RateLimiter)
+		}
+		o.rateLimiters[fullMethod] = limiter
+	}
+}
+
+// SetRateLimitKeyFunc option func, set rate limiter key (ex: by client IP or user ID), default key is full method
+func SetRateLimitKeyFunc(keyFunc func(ctx context.Context, fullMethod string) string) OptionFunc {
+	return func(o *option) {
+		o.rateLimitKeyFunc = keyFunc
+	}
+}
+
+// SetEnableRequestValidation option func, validate incoming request with Validate() method in protobuf message
+// (ex: generated from protoc-gen-validate), or with validator from dependency
+func SetEnableRequestValidation(enable bool) OptionFunc {
+	return func(o *option) {
+		o.enableValidation = enable
+	}
+}
+
+// AddUnaryInterceptors option func, add custom unary interceptors after default interceptors in chain
+func AddUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) OptionFunc {
+	return func(o *option) {
+		o.unaryInterceptors = append(o.unaryInterceptors, interceptors...)
+	}
+}
+
+// AddStreamInterceptors option func, add custom stream interceptors after default interceptors in chain
+func AddStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) OptionFunc {
+	return func(o *option) {
+		o.streamInterceptors = append(o.streamInterceptors, interceptors...)
+	}
+}
@@ -0,0 +1,162 @@
+package kafkaworker
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/golangid/candi/broker"
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/codebase/factory/types"
+	"github.com/golangid/candi/tracer"
+)
+
+// consumeBatchClaim collect messages from claim until reach batch max size or max wait time, then dispatch to batch handler
+func (c *consumerHandler) consumeBatchClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim, handler types.WorkerHandler) error {
+	batch := make([]*sarama.ConsumerMessage, 0, handler.BatchMaxSize)
+	timer := time.NewTimer(handler.BatchMaxWait)
+	defer timer.Stop()
+
+	flush := func() {
+		if len(batch) > 0 {
+			c.processBatchMessage(session, handler, batch)
+			batch = make([]*sarama.ConsumerMessage, 0, handler.BatchMaxSize)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(handler.BatchMaxWait)
+	}
+
+	for {
+		select {
+		case message, ok := <-claim.Messages():
+			if !ok {
+				flush()
+				return nil
+			}
+			if message == nil {
+				continue
+			}
+			batch = append(batch, message)
+			if len(batch) >= handler.BatchMaxSize {
+				flush()
+			}
+
+		case <-timer.C:
+			flush()
+
+		case <-session.Context().Done():
+			flush()
+			return nil
+
+		}
+	}
+}
+
+func (c *consumerHandler) processBatchMessage(session sarama.ConsumerGroupSession, handler types.WorkerHandler, messages []*sarama.ConsumerMessage) {
+	ctx := session.Context()
+	if handler.DisableTrace {
+		ctx = tracer.SkipTraceContext(ctx)
+	}
+
+	var err error
+	trace, ctx := tracer.StartTraceFromHeader(ctx, "KafkaConsumerBatch", map[string]string{})
+	batchTraceID := tracer.GetTraceID(ctx)
+
+	events := make([]*candishared.EventContext, len(messages))
+	messageTraces := make([]tracer.Tracer, len(messages))
+	messageTraceIDs := make([]string, len(messages))
+	defer func() {
+		if r := recover(); r != nil {
+			trace.SetTag("panic", true)
+			err = fmt.Errorf("%v", r)
+		}
+
+		failed := 0
+		for i, eventContext := range events {
+			// event context is nil if panic while building events
+			msgErr := err
+			if eventContext != nil {
+				if eventContext.Err() == nil && err != nil {
+					eventContext.SetError(err)
+				}
+				msgErr = eventContext.Err()
+			}
+			if msgErr != nil {
+				failed++
+			}
+			if handler.AutoACK {
+				session.MarkMessage(messages[i], "")
+			}
+			if messageTraces[i] != nil {
+				messageTraces[i].Finish(tracer.FinishWithError(msgErr))
+			}
+		}
+		trace.SetTag("failed_count", failed)
+		trace.Finish(tracer.FinishWithError(err))
+	}()
+
+	trace.SetTag("brokers", strings.Join(c.bk.BrokerHost, ","))
+	trace.SetTag("topic", handler.Pattern)
+	trace.SetTag("partition", int(messages[0].Partition))
+	trace.SetTag("consumer_group", c.opt.consumerGroup)
+	trace.SetTag("batch_size", len(messages))
+	trace.SetTag("first_offset", int(messages[0].Offset))
+	trace.SetTag("last_offset", int(messages[len(messages)-1].Offset))
+	if c.bk.WorkerType != types.Kafka {
+		trace.SetTag("worker_type", string(c.bk.WorkerType))
+	}
+
+	for i, message := range messages {
+		header := map[string]string{
+			"offset":    strconv.Itoa(int(message.Offset)),
+			"partition": strconv.Itoa(int(message.Partition)),
+			"timestamp": message.Timestamp.Format(time.RFC3339),
+		}
+		for _, val := range message.Headers {
+			header[string(val.Key)] = string(val.Value)
+		}
+
+		// each message continue trace from producer and linked to batch trace id
+		msgTrace, msgCtx := tracer.StartTraceFromHeader(session.Context(), "KafkaConsumerBatchMessage", header)
+		if handler.DisableTrace {
+			msgCtx = tracer.SkipTraceContext(msgCtx)
+		}
+		if c.bk.Serde != nil {
+			msgCtx = broker.SetKafkaSerdeToContext(msgCtx, c.bk.Serde)
+		}
+		msgTrace.SetTag("topic", message.Topic)
+		msgTrace.SetTag("key", message.Key)
+		msgTrace.SetTag("batch_trace_id", batchTraceID)
+		msgTrace.Log("header", header)
+		msgTrace.Log("message", message.Value)
+		messageTraces[i] = msgTrace
+		messageTraceIDs[i] = tracer.GetTraceID(msgCtx)
+
+		eventContext := candishared.NewEventContext(bytes.NewBuffer(make([]byte, 0, len(message.Value))))
+		eventContext.SetContext(msgCtx)
+		eventContext.SetWorkerType(string(c.bk.WorkerType))
+		eventContext.SetHandlerRoute(message.Topic)
+		eventContext.SetHeader(header)
+		eventContext.SetKey(string(message.Key))
+		eventContext.Write(message.Value)
+		events[i] = eventContext
+	}
+	trace.Log("message_trace_ids", messageTraceIDs)
+
+	if c.opt.debugMode {
+		log.Printf("\x1b[35;3mKafka Consumer%s: batch consumed, topic = %s, partition = %d, offset = %d-%d, size = %d\x1b[0m",
+			getWorkerTypeLog(c.bk.WorkerType), handler.Pattern, messages[0].Partition,
+			messages[0].Offset, messages[len(messages)-1].Offset, len(messages))
+	}
+
+	err = handler.BatchHandlerFunc(ctx, events)
+}
@@ -0,0 +1,125 @@
+package kafkaworker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/golangid/candi/broker"
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/codebase/factory/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+	ctx    context.Context
+	mu     sync.Mutex
+	marked []int64
+}
+
+func (f *fakeSession) Context() context.Context { return f.ctx }
+
+func (f *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	offset := int64(-1)
+	if msg != nil {
+		offset = msg.Offset
+	}
+	f.marked = append(f.marked, offset)
+}
+
+type fakeClaim struct {
+	sarama.ConsumerGroupClaim
+	messages chan *sarama.ConsumerMessage
+}
+
+func (f *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return f.messages }
+
+func newTestConsumerHandler() *consumerHandler {
+	return &consumerHandler{bk: &broker.KafkaBroker{WorkerType: types.Kafka}, opt: &option{}}
+}
+
+func newTestMessages(n int) []*sarama.ConsumerMessage {
+	messages := make([]*sarama.ConsumerMessage, n)
+	for i := range messages {
+		messages[i] = &sarama.ConsumerMessage{Topic: "test", Offset: int64(i), Value: []byte{byte('a' + i)}}
+	}
+	return messages
+}
+
+func TestConsumeBatchClaim(t *testing.T) {
+	var batchSizes []int
+	handler := types.WorkerHandler{
+		Pattern: "test", BatchMaxSize: 3, BatchMaxWait: 50 * time.Millisecond,
+		BatchHandlerFunc: func(ctx context.Context, events []*candishared.EventContext) error {
+			batchSizes = append(batchSizes, len(events))
+			return nil
+		},
+	}
+
+	claim := &fakeClaim{messages: make(chan *sarama.ConsumerMessage, 10)}
+	session := &fakeSession{ctx: context.Background()}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		newTestConsumerHandler().consumeBatchClaim(session, claim, handler)
+	}()
+
+	for _, message := range newTestMessages(4) {
+		claim.messages <- message
+	}
+	// first batch is full, second batch is dispatched after max wait
+	time.Sleep(100 * time.Millisecond)
+	claim.messages <- newTestMessages(1)[0]
+	// remaining batch is dispatched when claim is closed
+	close(claim.messages)
+	<-done
+
+	assert.Equal(t, []int{3, 1, 1}, batchSizes)
+}
+
+func TestProcessBatchMessage(t *testing.T) {
+	t.Run("mark all message and report failed message", func(t *testing.T) {
+		session := &fakeSession{ctx: context.Background()}
+		var failed []error
+		newTestConsumerHandler().processBatchMessage(session, types.WorkerHandler{
+			Pattern: "test", AutoACK: true,
+			BatchHandlerFunc: func(ctx context.Context, events []*candishared.EventContext) error {
+				events[1].SetError(errors.New("failed"))
+				for _, event := range events {
+					failed = append(failed, event.Err())
+				}
+				return nil
+			},
+		}, newTestMessages(3))
+
+		assert.Equal(t, []int64{0, 1, 2}, session.marked)
+		assert.Nil(t, failed[0])
+		assert.Error(t, failed[1])
+	})
+
+	t.Run("panic while building events", func(t *testing.T) {
+		session := &fakeSession{ctx: context.Background()}
+		messages := newTestMessages(3)
+		messages[1] = nil
+
+		called := false
+		assert.NotPanics(t, func() {
+			newTestConsumerHandler().processBatchMessage(session, types.WorkerHandler{
+				Pattern: "test", AutoACK: true,
+				BatchHandlerFunc: func(ctx context.Context, events []*candishared.EventContext) error {
+					called = true
+					return nil
+				},
+			}, messages)
+		})
+
+		assert.False(t, called)
+		assert.Len(t, session.marked, 3)
+	})
+}
@@ -38,6 +38,10 @@ func (c *consumerHandler) Cleanup(sarama.ConsumerGroupSession) error {
 
 // ConsumeClaim must start a consumer loop of ConsumerGroupClaim's Messages().
 func (c *consumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	if handler, ok := c.handlerFuncs[claim.Topic()]; ok && handler.IsBatch() {
+		return c.consumeBatchClaim(session, claim, handler)
+	}
+
 	for {
 		select {
 		case message := <-claim.Messages():
@@ -63,6 +67,9 @@ func (c *consumerHandler) processMessage(session sarama.ConsumerGroupSession, me
 	if handler.DisableTrace {
 		ctx = tracer.SkipTraceContext(ctx)
 	}
+	if c.bk.Serde != nil {
+		ctx = broker.SetKafkaSerdeToContext(ctx, c.bk.Serde)
+	}
 
 	header := map[string]string{
 		"offset":    strconv.Itoa(int(message.Offset)),
@@ -90,6 +97,9 @@ func (c *consumerHandler) processMessage(session sarama.ConsumerGroupSession, me
 	trace.SetTag("topic", message.Topic)
 	trace.SetTag("key", message.Key)
 	trace.SetTag("consumer_group", c.opt.consumerGroup)
+	if schemaID, err := broker.GetSchemaIDFromPayload(message.Value); c.bk.Serde != nil && err == nil {
+		trace.SetTag("schema_id", schemaID)
+	}
 	if c.bk.WorkerType != types.Kafka {
 		trace.SetTag("worker_type", string(c.bk.WorkerType))
 	}
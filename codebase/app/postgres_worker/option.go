@@ -37,7 +37,9 @@ func getDefaultOption(service factory.ServiceFactory) option {
 		minReconnectInterval: 500 * time.Millisecond,
 		maxReconnectInterval: time.Second,
 	}
-	if redisPool := service.GetDependency().GetRedisPool(); redisPool != nil {
+	if locker := service.GetDependency().GetLocker(); locker != nil {
+		opt.locker = locker
+	} else if redisPool := service.GetDependency().GetRedisPool(); redisPool != nil {
 		opt.locker = candiutils.NewRedisLocker(redisPool.WritePool())
 	} else {
 		opt.locker = &candiutils.NoopLocker{}
@@ -0,0 +1,179 @@
+package rabbitmqworker
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/golangid/candi/broker"
+	"github.com/golangid/candi/candihelper"
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/codebase/factory/types"
+	"github.com/golangid/candi/logger"
+	"github.com/golangid/candi/tracer"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type batchReceiver struct {
+	handler    types.WorkerHandler
+	channel    *amqp.Channel
+	deliveries <-chan amqp.Delivery
+}
+
+// setupBatchReceiver consume queue in dedicated channel with prefetch count of batch max size,
+// default channel prefetch count is too small for filling the batch
+func setupBatchReceiver(bk *broker.RabbitMQBroker, consumerGroup string, handler types.WorkerHandler) (receiver batchReceiver, err error) {
+	receiver.handler = handler
+	receiver.channel, err = bk.Conn.Channel()
+	if err != nil {
+		return receiver, fmt.Errorf("RabbitMQ batch channel: %w", err)
+	}
+	if err := receiver.channel.Qos(handler.BatchMaxSize, 0, false); err != nil {
+		return receiver, fmt.Errorf("RabbitMQ batch Qos: %w", err)
+	}
+	receiver.deliveries, err = setupQueueConfig(receiver.channel, consumerGroup, bk.Exchange, handler.Pattern)
+	return receiver, err
+}
+
+// consumeBatch collect deliveries until reach batch max size or max wait time, then dispatch to batch handler
+func (r *rabbitmqWorker) consumeBatch(receiver batchReceiver) {
+	handler := receiver.handler
+	batch := make([]amqp.Delivery, 0, handler.BatchMaxSize)
+	timer := time.NewTimer(handler.BatchMaxWait)
+	defer timer.Stop()
+
+	flush := func() {
+		if len(batch) > 0 {
+			r.processBatchMessage(handler, batch)
+			batch = make([]amqp.Delivery, 0, handler.BatchMaxSize)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(handler.BatchMaxWait)
+	}
+
+	for {
+		select {
+		case message, ok := <-receiver.deliveries:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, message)
+			if len(batch) >= handler.BatchMaxSize {
+				flush()
+			}
+
+		case <-timer.C:
+			flush()
+
+		case <-r.batchShutdown:
+			flush()
+			return
+
+		}
+	}
+}
+
+func (r *rabbitmqWorker) processBatchMessage(handler types.WorkerHandler, messages []amqp.Delivery) {
+	if r.ctx.Err() != nil {
+		logger.LogRed("rabbitmq_consumer > ctx root err: " + r.ctx.Err().Error())
+		return
+	}
+
+	ctx := r.ctx
+	if handler.DisableTrace {
+		ctx = tracer.SkipTraceContext(ctx)
+	}
+
+	var err error
+	trace, ctx := tracer.StartTraceFromHeader(ctx, "RabbitMQConsumerBatch", map[string]string{})
+	batchTraceID := tracer.GetTraceID(ctx)
+
+	events := make([]*candishared.EventContext, len(messages))
+	messageTraces := make([]tracer.Tracer, len(messages))
+	messageTraceIDs := make([]string, len(messages))
+	defer func() {
+		if rec := recover(); rec != nil {
+			trace.SetTag("panic", true)
+			err = fmt.Errorf("%v", rec)
+		}
+
+		failed := 0
+		for i, eventContext := range events {
+			// event context is nil if panic while building events
+			msgErr := err
+			if eventContext != nil {
+				if eventContext.Err() == nil && err != nil {
+					eventContext.SetError(err)
+				}
+				msgErr = eventContext.Err()
+			}
+			if msgErr != nil {
+				failed++
+			}
+			if handler.AutoACK {
+				if msgErr != nil {
+					// reject failed message without requeue, can be routed to dead letter exchange
+					messages[i].Nack(false, false)
+				} else {
+					messages[i].Ack(false)
+				}
+			}
+			if messageTraces[i] != nil {
+				messageTraces[i].Finish(tracer.FinishWithError(msgErr))
+			}
+		}
+		trace.SetTag("failed_count", failed)
+		trace.Finish(tracer.FinishWithError(err))
+	}()
+
+	trace.SetTag("broker", candihelper.MaskingPasswordURL(r.bk.BrokerHost))
+	trace.SetTag("exchange", messages[0].Exchange)
+	trace.SetTag("routing_key", handler.Pattern)
+	trace.SetTag("batch_size", len(messages))
+	if r.bk.WorkerType != types.RabbitMQ {
+		trace.SetTag("worker_type", string(r.bk.WorkerType))
+	}
+
+	for i, message := range messages {
+		header := make(map[string]string, len(message.Headers))
+		for key, val := range message.Headers {
+			header[key] = string(candihelper.ToBytes(val))
+		}
+
+		// each message continue trace from publisher and linked to batch trace id
+		msgTrace, msgCtx := tracer.StartTraceFromHeader(r.ctx, "RabbitMQConsumerBatchMessage", header)
+		if handler.DisableTrace {
+			msgCtx = tracer.SkipTraceContext(msgCtx)
+		}
+		msgTrace.SetTag("routing_key", message.RoutingKey)
+		msgTrace.SetTag("batch_trace_id", batchTraceID)
+		msgTrace.Log("header", message.Headers)
+		msgTrace.Log("body", message.Body)
+		messageTraces[i] = msgTrace
+		messageTraceIDs[i] = tracer.GetTraceID(msgCtx)
+
+		eventContext := candishared.NewEventContext(bytes.NewBuffer(make([]byte, 0, len(message.Body))))
+		eventContext.SetContext(msgCtx)
+		eventContext.SetWorkerType(string(r.bk.WorkerType))
+		eventContext.SetHandlerRoute(message.RoutingKey)
+		eventContext.SetHeader(header)
+		eventContext.SetKey(message.Exchange)
+		eventContext.Write(message.Body)
+		events[i] = eventContext
+	}
+	trace.Log("message_trace_ids", messageTraceIDs)
+
+	if r.opt.debugMode {
+		log.Printf("\x1b[35;3mRabbitMQ Consumer%s: batch consumed, topic = %s, size = %d\x1b[0m",
+			getWorkerTypeLog(r.bk.WorkerType), handler.Pattern, len(messages))
+	}
+
+	err = handler.BatchHandlerFunc(ctx, events)
+}
@@ -0,0 +1,145 @@
+package rabbitmqworker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golangid/candi/broker"
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/codebase/factory/types"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAcknowledger struct {
+	mu          sync.Mutex
+	acks, nacks []uint64
+}
+
+func (f *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.acks = append(f.acks, tag)
+	return nil
+}
+
+func (f *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nacks = append(f.nacks, tag)
+	return nil
+}
+
+func (f *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return f.Nack(tag, false, requeue)
+}
+
+type panicHeader struct{}
+
+func (panicHeader) MarshalJSON() ([]byte, error) { panic("invalid header") }
+
+func newTestWorker() *rabbitmqWorker {
+	r := &rabbitmqWorker{
+		bk:            &broker.RabbitMQBroker{WorkerType: types.RabbitMQ},
+		batchShutdown: make(chan struct{}),
+	}
+	r.ctx, r.ctxCancelFunc = context.WithCancel(context.Background())
+	return r
+}
+
+func newTestDeliveries(ack amqp.Acknowledger, n int) []amqp.Delivery {
+	messages := make([]amqp.Delivery, n)
+	for i := range messages {
+		messages[i] = amqp.Delivery{Acknowledger: ack, DeliveryTag: uint64(i + 1), RoutingKey: "test", Body: []byte{byte('a' + i)}}
+	}
+	return messages
+}
+
+func TestConsumeBatch(t *testing.T) {
+	var mu sync.Mutex
+	var batchSizes []int
+	handler := types.WorkerHandler{
+		Pattern: "test", BatchMaxSize: 3, BatchMaxWait: 50 * time.Millisecond,
+		BatchHandlerFunc: func(ctx context.Context, events []*candishared.EventContext) error {
+			mu.Lock()
+			defer mu.Unlock()
+			batchSizes = append(batchSizes, len(events))
+			return nil
+		},
+	}
+
+	r := newTestWorker()
+	deliveries := make(chan amqp.Delivery, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.consumeBatch(batchReceiver{handler: handler, deliveries: deliveries})
+	}()
+
+	for _, message := range newTestDeliveries(&fakeAcknowledger{}, 4) {
+		deliveries <- message
+	}
+	// first batch is full, second batch is dispatched after max wait
+	time.Sleep(100 * time.Millisecond)
+	deliveries <- newTestDeliveries(&fakeAcknowledger{}, 1)[0]
+	// remaining batch is dispatched when deliveries channel is closed
+	close(deliveries)
+	<-done
+
+	assert.Equal(t, []int{3, 1, 1}, batchSizes)
+}
+
+func TestProcessBatchMessage(t *testing.T) {
+	t.Run("ack success and nack failed message", func(t *testing.T) {
+		ack := &fakeAcknowledger{}
+		r := newTestWorker()
+		r.processBatchMessage(types.WorkerHandler{
+			Pattern: "test", AutoACK: true,
+			BatchHandlerFunc: func(ctx context.Context, events []*candishared.EventContext) error {
+				events[1].SetError(errors.New("failed"))
+				return nil
+			},
+		}, newTestDeliveries(ack, 3))
+
+		assert.Equal(t, []uint64{1, 3}, ack.acks)
+		assert.Equal(t, []uint64{2}, ack.nacks)
+	})
+
+	t.Run("nack all message if handler return error", func(t *testing.T) {
+		ack := &fakeAcknowledger{}
+		r := newTestWorker()
+		r.processBatchMessage(types.WorkerHandler{
+			Pattern: "test", AutoACK: true,
+			BatchHandlerFunc: func(ctx context.Context, events []*candishared.EventContext) error {
+				return errors.New("failed")
+			},
+		}, newTestDeliveries(ack, 2))
+
+		assert.Empty(t, ack.acks)
+		assert.Equal(t, []uint64{1, 2}, ack.nacks)
+	})
+
+	t.Run("panic while building events", func(t *testing.T) {
+		ack := &fakeAcknowledger{}
+		messages := newTestDeliveries(ack, 3)
+		messages[1].Headers = amqp.Table{"invalid": panicHeader{}}
+
+		called := false
+		r := newTestWorker()
+		assert.NotPanics(t, func() {
+			r.processBatchMessage(types.WorkerHandler{
+				Pattern: "test", AutoACK: true,
+				BatchHandlerFunc: func(ctx context.Context, events []*candishared.EventContext) error {
+					called = true
+					return nil
+				},
+			}, messages)
+		})
+
+		assert.False(t, called)
+		assert.Equal(t, []uint64{1, 2, 3}, ack.nacks)
+	})
+}
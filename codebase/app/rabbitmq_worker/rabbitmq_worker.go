@@ -34,6 +34,9 @@ type rabbitmqWorker struct {
 	wg         sync.WaitGroup
 	receiver   []reflect.SelectCase
 	handlers   map[string]types.WorkerHandler
+
+	batchReceivers []batchReceiver
+	batchShutdown  chan struct{}
 }
 
 // NewWorker create new rabbitmq consumer
@@ -54,6 +57,7 @@ func NewWorker(service factory.ServiceFactory, bk interfaces.Broker, opts ...Opt
 	worker.ctx, worker.ctxCancelFunc = context.WithCancel(context.Background())
 
 	worker.shutdown = make(chan struct{}, 1)
+	worker.batchShutdown = make(chan struct{})
 	worker.handlers = make(map[string]types.WorkerHandler)
 
 	for _, m := range service.GetModules() {
@@ -62,6 +66,16 @@ func NewWorker(service factory.ServiceFactory, bk interfaces.Broker, opts ...Opt
 			h.MountHandlers(&handlerGroup)
 			for _, handler := range handlerGroup.Handlers {
 				logger.LogYellow(fmt.Sprintf(`[RABBITMQ-CONSUMER]%s (queue): %-15s  --> (module): "%s"`, getWorkerTypeLog(rabbitMQBroker.WorkerType), `"`+handler.Pattern+`"`, m.Name()))
+				worker.handlers[handler.Pattern] = handler
+				if handler.IsBatch() {
+					receiver, err := setupBatchReceiver(worker.bk, worker.opt.consumerGroup, handler)
+					if err != nil {
+						panic(err)
+					}
+					worker.batchReceivers = append(worker.batchReceivers, receiver)
+					continue
+				}
+
 				queueChan, err := setupQueueConfig(worker.bk.Channel, worker.opt.consumerGroup, rabbitMQBroker.Exchange, handler.Pattern)
 				if err != nil {
 					panic(err)
@@ -70,19 +84,26 @@ func NewWorker(service factory.ServiceFactory, bk interfaces.Broker, opts ...Opt
 				worker.receiver = append(worker.receiver, reflect.SelectCase{
 					Dir: reflect.SelectRecv, Chan: reflect.ValueOf(queueChan),
 				})
-				worker.handlers[handler.Pattern] = handler
 				worker.semaphore = append(worker.semaphore, make(chan struct{}, 1))
 			}
 		}
 	}
 
-	fmt.Printf("\x1b[34;1m⇨ RabbitMQ consumer%s running with %d queue. Broker: %s\x1b[0m\n\n", getWorkerTypeLog(rabbitMQBroker.WorkerType), len(worker.receiver),
-		candihelper.MaskingPasswordURL(rabbitMQBroker.BrokerHost))
+	fmt.Printf("\x1b[34;1m⇨ RabbitMQ consumer%s running with %d queue and %d batch queue. Broker: %s\x1b[0m\n\n", getWorkerTypeLog(rabbitMQBroker.WorkerType), len(worker.receiver),
+		len(worker.batchReceivers), candihelper.MaskingPasswordURL(rabbitMQBroker.BrokerHost))
 
 	return worker
 }
 
 func (r *rabbitmqWorker) Serve() {
+	for _, receiver := range r.batchReceivers {
+		r.wg.Add(1)
+		go func(receiver batchReceiver) {
+			defer r.wg.Done()
+			r.consumeBatch(receiver)
+		}(receiver)
+	}
+
 	for {
 		select {
 		case <-r.shutdown:
@@ -123,6 +144,7 @@ func (r *rabbitmqWorker) Shutdown(ctx context.Context) {
 
 	r.shutdown <- struct{}{}
 	r.isShutdown = true
+	close(r.batchShutdown)
 	runningJob := 0
 	for _, sem := range r.semaphore {
 		runningJob += len(sem)
@@ -135,6 +157,9 @@ func (r *rabbitmqWorker) Shutdown(ctx context.Context) {
 		time.Now().Format(candihelper.TimeFormatLogger), getWorkerTypeLog(r.bk.WorkerType), waitingJob)
 
 	r.wg.Wait()
+	for _, receiver := range r.batchReceivers {
+		receiver.channel.Close()
+	}
 	r.bk.Channel.Close()
 	r.ctxCancelFunc()
 }
@@ -0,0 +1,204 @@
+package restserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type (
+	// AccessLogEntry data of access log for each request
+	AccessLogEntry struct {
+		Time      time.Time
+		RequestID string
+		TraceID   string
+		RemoteIP  string
+		User      string
+		Method    string
+		Host      string
+		Path      string
+		Query     string
+		Proto     string
+		UserAgent string
+		Referer   string
+		Status    int
+		Latency   time.Duration
+		BytesIn   int64
+		BytesOut  int64
+	}
+
+	// AccessLogFormatter write access log entry (one line) to buffer
+	AccessLogFormatter func(buff *bytes.Buffer, entry *AccessLogEntry)
+
+	// zapAccessLogger route access log to zap logger with sampling
+	zapAccessLogger struct {
+		once              sync.Once
+		logger            *zap.Logger
+		first, thereafter int
+	}
+)
+
+// AccessLogFormatJSON access log in JSON format (default)
+func AccessLogFormatJSON(buff *bytes.Buffer, entry *AccessLogEntry) {
+	buff.WriteString(`{"time":`)
+	writeJSONString(buff, entry.Time.Format(time.RFC3339Nano))
+	if entry.RequestID != "" {
+		buff.WriteString(`,"id":`)
+		writeJSONString(buff, entry.RequestID)
+	}
+	if entry.RemoteIP != "" {
+		buff.WriteString(`,"remote_ip":`)
+		writeJSONString(buff, entry.RemoteIP)
+	}
+	buff.WriteString(`,"method":`)
+	writeJSONString(buff, entry.Method)
+	buff.WriteString(`,"host":`)
+	writeJSONString(buff, entry.Host)
+	buff.WriteString(`,"path":`)
+	writeJSONString(buff, entry.Path)
+	buff.WriteString(`,"query":`)
+	writeJSONString(buff, entry.Query)
+	buff.WriteString(`,"user_agent":`)
+	writeJSONString(buff, entry.UserAgent)
+	buff.WriteString(`,"status":`)
+	buff.WriteString(strconv.Itoa(entry.Status))
+	buff.WriteString(`,"latency":`)
+	writeJSONString(buff, entry.Latency.String())
+	if entry.TraceID != "" {
+		buff.WriteString(`,"trace_id":`)
+		writeJSONString(buff, entry.TraceID)
+	}
+	buff.WriteString(`,"bytes_in":`)
+	buff.WriteString(strconv.FormatInt(entry.BytesIn, 10))
+	buff.WriteString(`,"bytes_out":`)
+	buff.WriteString(strconv.FormatInt(entry.BytesOut, 10))
+	buff.WriteString("}\n")
+}
+
+// AccessLogFormatCombined access log in Apache combined log format
+func AccessLogFormatCombined(buff *bytes.Buffer, entry *AccessLogEntry) {
+	buff.WriteString(orDash(entry.RemoteIP))
+	buff.WriteString(" - ")
+	buff.WriteString(orDash(entry.User))
+	buff.WriteString(" [")
+	buff.WriteString(entry.Time.Format("02/Jan/2006:15:04:05 -0700"))
+	buff.WriteString(`] "`)
+	buff.WriteString(entry.Method)
+	buff.WriteByte(' ')
+	buff.WriteString(entry.Path)
+	if entry.Query != "" {
+		buff.WriteByte('?')
+		buff.WriteString(entry.Query)
+	}
+	buff.WriteByte(' ')
+	buff.WriteString(entry.Proto)
+	buff.WriteString(`" `)
+	buff.WriteString(strconv.Itoa(entry.Status))
+	buff.WriteByte(' ')
+	if entry.BytesOut > 0 {
+		buff.WriteString(strconv.FormatInt(entry.BytesOut, 10))
+	} else {
+		buff.WriteByte('-')
+	}
+	buff.WriteString(` "`)
+	buff.WriteString(strings.ReplaceAll(orDash(entry.Referer), `"`, `\"`))
+	buff.WriteString(`" "`)
+	buff.WriteString(strings.ReplaceAll(orDash(entry.UserAgent), `"`, `\"`))
+	buff.WriteString("\"\n")
+}
+
+// AccessLogFormatLogfmt access log in logfmt format (key=value)
+func AccessLogFormatLogfmt(buff *bytes.Buffer, entry *AccessLogEntry) {
+	first := true
+	writeLogfmt := func(key, value string) {
+		if !first {
+			buff.WriteByte(' ')
+		}
+		first = false
+		buff.WriteString(key)
+		buff.WriteByte('=')
+		if value == "" || strings.ContainsAny(value, " =\"\t\n") {
+			buff.WriteString(strconv.Quote(value))
+			return
+		}
+		buff.WriteString(value)
+	}
+
+	writeLogfmt("time", entry.Time.Format(time.RFC3339Nano))
+	if entry.RequestID != "" {
+		writeLogfmt("request_id", entry.RequestID)
+	}
+	if entry.RemoteIP != "" {
+		writeLogfmt("remote_ip", entry.RemoteIP)
+	}
+	writeLogfmt("method", entry.Method)
+	writeLogfmt("host", entry.Host)
+	writeLogfmt("path", entry.Path)
+	if entry.Query != "" {
+		writeLogfmt("query", entry.Query)
+	}
+	writeLogfmt("status", strconv.Itoa(entry.Status))
+	writeLogfmt("latency", entry.Latency.String())
+	if entry.TraceID != "" {
+		writeLogfmt("trace_id", entry.TraceID)
+	}
+	writeLogfmt("bytes_in", strconv.FormatInt(entry.BytesIn, 10))
+	writeLogfmt("bytes_out", strconv.FormatInt(entry.BytesOut, 10))
+	writeLogfmt("user_agent", entry.UserAgent)
+	buff.WriteByte('\n')
+}
+
+func writeJSONString(buff *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	buff.Write(b)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// log access log entry to global zap logger (initialized with logger.InitZap), sampler is created at first log
+func (z *zapAccessLogger) log(entry *AccessLogEntry) {
+	z.once.Do(func() {
+		z.logger = zap.L()
+		if z.thereafter > 0 {
+			z.logger = z.logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+				return zapcore.NewSamplerWithOptions(core, time.Second, z.first, z.thereafter)
+			}))
+		}
+	})
+
+	level := zapcore.InfoLevel
+	switch {
+	case entry.Status >= http.StatusInternalServerError:
+		level = zapcore.ErrorLevel
+	case entry.Status >= http.StatusBadRequest:
+		level = zapcore.WarnLevel
+	}
+	if ce := z.logger.Check(level, "access"); ce != nil {
+		ce.Write(
+			zap.String("request_id", entry.RequestID),
+			zap.String("trace_id", entry.TraceID),
+			zap.String("remote_ip", entry.RemoteIP),
+			zap.String("method", entry.Method),
+			zap.String("host", entry.Host),
+			zap.String("path", entry.Path),
+			zap.String("query", entry.Query),
+			zap.String("user_agent", entry.UserAgent),
+			zap.Int("status", entry.Status),
+			zap.Duration("latency", entry.Latency),
+			zap.Int64("bytes_in", entry.BytesIn),
+			zap.Int64("bytes_out", entry.BytesOut),
+		)
+	}
+}
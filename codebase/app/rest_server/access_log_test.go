@@ -0,0 +1,175 @@
+package restserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/middleware"
+	mockinterfaces "github.com/golangid/candi/mocks/codebase/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newTestAccessLogEntry() *AccessLogEntry {
+	return &AccessLogEntry{
+		Time:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		RequestID: "req-1",
+		TraceID:   "trace-1",
+		RemoteIP:  "10.0.0.1",
+		User:      "user-1",
+		Method:    http.MethodGet,
+		Host:      "localhost:8000",
+		Path:      "/v1/users",
+		Query:     "page=1&q=a b",
+		Proto:     "HTTP/1.1",
+		UserAgent: `curl/8.0 "test"`,
+		Referer:   "",
+		Status:    http.StatusOK,
+		Latency:   1500 * time.Microsecond,
+		BytesIn:   10,
+		BytesOut:  20,
+	}
+}
+
+func TestAccessLogFormatJSON(t *testing.T) {
+	var buff bytes.Buffer
+	AccessLogFormatJSON(&buff, newTestAccessLogEntry())
+	assert.True(t, strings.HasSuffix(buff.String(), "}\n"))
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(buff.Bytes(), &got))
+	assert.Equal(t, map[string]any{
+		"time": "2024-01-02T03:04:05Z", "id": "req-1", "remote_ip": "10.0.0.1", "method": "GET", "host": "localhost:8000",
+		"path": "/v1/users", "query": "page=1&q=a b", "user_agent": `curl/8.0 "test"`, "status": float64(200), "latency": "1.5ms",
+		"trace_id": "trace-1", "bytes_in": float64(10), "bytes_out": float64(20),
+	}, got)
+}
+
+func TestAccessLogFormatCombined(t *testing.T) {
+	var buff bytes.Buffer
+	AccessLogFormatCombined(&buff, newTestAccessLogEntry())
+	assert.Equal(t, `10.0.0.1 - user-1 [02/Jan/2024:03:04:05 +0000] "GET /v1/users?page=1&q=a b HTTP/1.1" 200 20 "-" "curl/8.0 \"test\""`+"\n", buff.String())
+
+	buff.Reset()
+	AccessLogFormatCombined(&buff, &AccessLogEntry{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Method: http.MethodHead, Path: "/", Proto: "HTTP/2.0", Status: http.StatusNoContent})
+	assert.Equal(t, `- - - [02/Jan/2024:03:04:05 +0000] "HEAD / HTTP/2.0" 204 - "-" "-"`+"\n", buff.String())
+}
+
+func TestAccessLogFormatLogfmt(t *testing.T) {
+	var buff bytes.Buffer
+	AccessLogFormatLogfmt(&buff, newTestAccessLogEntry())
+	assert.Equal(t, `time=2024-01-02T03:04:05Z request_id=req-1 remote_ip=10.0.0.1 method=GET host=localhost:8000 path=/v1/users `+
+		`query="page=1&q=a b" status=200 latency=1.5ms trace_id=trace-1 bytes_in=10 bytes_out=20 user_agent="curl/8.0 \"test\""`+"\n", buff.String())
+}
+
+func TestZapAccessLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	z := &zapAccessLogger{first: 2, thereafter: 3}
+	entry := newTestAccessLogEntry()
+	for i := 0; i < 10; i++ {
+		z.log(entry)
+	}
+	// sampled per second: 1st, 2nd, then every 3rd entry (5th and 8th)
+	assert.Equal(t, 4, logs.Len())
+
+	entries := logs.TakeAll()
+	assert.Equal(t, "access", entries[0].Message)
+	assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "req-1", fields["request_id"])
+	assert.Equal(t, int64(200), fields["status"])
+	assert.Equal(t, 1500*time.Microsecond, fields["latency"])
+
+	entry.Status = http.StatusNotFound
+	z.log(entry)
+	entry.Status = http.StatusBadGateway
+	z.log(entry)
+	entries = logs.TakeAll()
+	require.Len(t, entries, 2)
+	assert.Equal(t, zapcore.WarnLevel, entries[0].Level)
+	assert.Equal(t, zapcore.ErrorLevel, entries[1].Level)
+}
+
+func TestTracerMiddlewareRequestID(t *testing.T) {
+	var logBuff bytes.Buffer
+	mw := &restMiddleware{
+		disableTrace:       true,
+		logResponseWriters: []io.Writer{&logBuff},
+		accessLogFormatter: AccessLogFormatJSON,
+		requestIDHeader:    "X-Request-ID",
+		requestIDGenerator: func() string { return "generated" },
+	}
+	handler := mw.Tracer()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name, requestID, want string
+	}{
+		{name: "valid", requestID: "abc-123_DEF.4", want: "abc-123_DEF.4"},
+		{name: "empty", requestID: "", want: "generated"},
+		{name: "too long", requestID: strings.Repeat("a", 129), want: "generated"},
+		{name: "invalid character", requestID: "abc\n{\"injected\":true}", want: "generated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logBuff.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			req.Header.Set("X-Request-ID", tt.requestID)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Header().Get("X-Request-ID"))
+			assert.Contains(t, logBuff.String(), `"id":"`+tt.want+`"`)
+		})
+	}
+}
+
+func TestTracerMiddlewareAccessLogUser(t *testing.T) {
+	var logBuff bytes.Buffer
+	mw := &restMiddleware{
+		disableTrace:       true,
+		logResponseWriters: []io.Writer{&logBuff},
+		accessLogFormatter: AccessLogFormatCombined,
+		requestIDHeader:    "X-Request-ID",
+		requestIDGenerator: func() string { return "generated" },
+	}
+	tokenValidator := &mockinterfaces.TokenValidator{}
+	tokenValidator.On("ValidateToken", mock.Anything, "valid").Return(&candishared.TokenClaim{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}}, nil)
+	tokenValidator.On("ValidateToken", mock.Anything, "invalid").Return(nil, errors.New("invalid token"))
+	handler := WithChainingMiddlewares(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}, mw.Tracer(), middleware.NewMiddlewareWithOption(middleware.SetTokenValidator(tokenValidator)).HTTPBearerAuth)
+
+	tests := []struct {
+		name, token, wantUser string
+	}{
+		{name: "authenticated", token: "valid", wantUser: "user-1"},
+		{name: "unauthenticated", token: "invalid", wantUser: "-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logBuff.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.wantUser, strings.Fields(logBuff.String())[2])
+		})
+	}
+}
@@ -0,0 +1,453 @@
+package restserver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golangid/candi/codebase/factory"
+	"github.com/golangid/candi/logger"
+	"github.com/golangid/candi/wrapper"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+var pathVariableRegex = regexp.MustCompile(`\{([^}=]+)(=([^}]*))?\}`)
+
+type (
+	// grpcTranscoder expose registered grpc service (unary method) to HTTP/JSON,
+	// transcoded call is served by grpc server engine in-process (with same interceptors and middleware group of grpc server)
+	grpcTranscoder struct {
+		engine http.Handler
+		routes []transcodeRoute
+	}
+
+	transcodeRoute struct {
+		httpMethod, pattern string
+		fullMethod          string
+		input, output       protoreflect.MessageType
+		body, responseBody  string
+		pathParams          map[string]string // key: chi url param, value: field path in request message
+	}
+
+	// grpcServerEngine implemented by grpc server app
+	grpcServerEngine interface {
+		ServerEngine() *grpc.Server
+	}
+
+	// grpcResponseRecorder in-process response writer for grpc server engine (see grpc.Server.ServeHTTP)
+	grpcResponseRecorder struct {
+		header http.Header
+		body   bytes.Buffer
+		code   int
+	}
+)
+
+// newGRPCTranscoder create transcoder from grpc server app in service applications
+func newGRPCTranscoder(service factory.ServiceFactory) (*grpcTranscoder, error) {
+	var engine *grpc.Server
+	for _, app := range service.GetApplications() {
+		if grpcApp, ok := app.(grpcServerEngine); ok {
+			engine = grpcApp.ServerEngine()
+			break
+		}
+	}
+	if engine == nil {
+		return nil, errors.New("gRPC server is not found in service applications")
+	}
+
+	t := &grpcTranscoder{engine: engine}
+	for serviceName := range engine.GetServiceInfo() {
+		desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(serviceName))
+		if err != nil {
+			logger.LogYellow(fmt.Sprintf("[REST-GRPC-TRANSCODING] skip service %s: %v", serviceName, err))
+			continue
+		}
+		serviceDesc, ok := desc.(protoreflect.ServiceDescriptor)
+		if !ok {
+			continue
+		}
+
+		methods := serviceDesc.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			routes, err := newTranscodeRoutes(serviceName, methods.Get(i))
+			if err != nil {
+				logger.LogYellow(fmt.Sprintf("[REST-GRPC-TRANSCODING] skip method %s: %v", methods.Get(i).FullName(), err))
+				continue
+			}
+			t.routes = append(t.routes, routes...)
+		}
+	}
+	return t, nil
+}
+
+func newTranscodeRoutes(serviceName string, method protoreflect.MethodDescriptor) ([]transcodeRoute, error) {
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return nil, fmt.Errorf("streaming method is not supported")
+	}
+
+	input, err := protoregistry.GlobalTypes.FindMessageByName(method.Input().FullName())
+	if err != nil {
+		return nil, err
+	}
+	output, err := protoregistry.GlobalTypes.FindMessageByName(method.Output().FullName())
+	if err != nil {
+		return nil, err
+	}
+
+	fullMethod := "/" + serviceName + "/" + string(method.Name())
+	httpRule, _ := proto.GetExtension(method.Options(), annotations.E_Http).(*annotations.HttpRule)
+	if httpRule == nil {
+		// fallback route if method has no google.api.http annotation
+		return []transcodeRoute{{
+			httpMethod: http.MethodPost, pattern: fullMethod, fullMethod: fullMethod,
+			input: input, output: output, body: "*",
+		}}, nil
+	}
+
+	var routes []transcodeRoute
+	for _, rule := range append([]*annotations.HttpRule{httpRule}, httpRule.GetAdditionalBindings()...) {
+		route := transcodeRoute{
+			fullMethod: fullMethod, input: input, output: output,
+			body: rule.GetBody(), responseBody: rule.GetResponseBody(),
+		}
+		var template string
+		switch pattern := rule.GetPattern().(type) {
+		case *annotations.HttpRule_Get:
+			route.httpMethod, template = http.MethodGet, pattern.Get
+		case *annotations.HttpRule_Post:
+			route.httpMethod, template = http.MethodPost, pattern.Post
+		case *annotations.HttpRule_Put:
+			route.httpMethod, template = http.MethodPut, pattern.Put
+		case *annotations.HttpRule_Patch:
+			route.httpMethod, template = http.MethodPatch, pattern.Patch
+		case *annotations.HttpRule_Delete:
+			route.httpMethod, template = http.MethodDelete, pattern.Delete
+		case *annotations.HttpRule_Custom:
+			route.httpMethod, template = strings.ToUpper(pattern.Custom.GetKind()), pattern.Custom.GetPath()
+		default:
+			return nil, fmt.Errorf("missing http pattern")
+		}
+
+		if route.pattern, route.pathParams, err = parsePathTemplate(template); err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// parsePathTemplate convert google.api.http path template to chi route pattern,
+// support simple variable ("{id}", "{user.id}", "{id=*}") and trailing multi segment variable ("{path=**}")
+func parsePathTemplate(template string) (pattern string, pathParams map[string]string, err error) {
+	pathParams = make(map[string]string)
+	idx := 0
+	pattern = pathVariableRegex.ReplaceAllStringFunc(template, func(s string) string {
+		match := pathVariableRegex.FindStringSubmatch(s)
+		fieldPath, segment := match[1], match[3]
+		switch segment {
+		case "", "*":
+			key := "p" + strconv.Itoa(idx)
+			idx++
+			pathParams[key] = fieldPath
+			return "{" + key + "}"
+		case "**":
+			pathParams["*"] = fieldPath
+			return "*"
+		}
+		err = fmt.Errorf("unsupported path template %s", s)
+		return s
+	})
+	return
+}
+
+func (t *grpcTranscoder) mount(router chi.Router) {
+	for _, route := range t.routes {
+		router.MethodFunc(route.httpMethod, route.pattern, t.handler(route))
+		logger.LogGreen(fmt.Sprintf("[REST-GRPC-TRANSCODING] %-6s %-30s --> %s", route.httpMethod, route.pattern, route.fullMethod))
+	}
+}
+
+func (t *grpcTranscoder) handler(route transcodeRoute) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		input := route.input.New()
+		if err := decodeTranscodeRequest(req, route, input); err != nil {
+			wrapper.NewHTTPResponse(http.StatusBadRequest, "Failed decode request", err).JSON(w)
+			return
+		}
+
+		output := route.output.New()
+		header, err := t.invoke(req, route.fullMethod, input.Interface(), output.Interface())
+		if err != nil {
+			st := status.Convert(err)
+			wrapper.NewHTTPResponse(httpStatusFromGRPCCode(st.Code()), st.Message()).JSON(w)
+			return
+		}
+		for key, values := range header {
+			for _, value := range values {
+				w.Header().Add("Grpc-Metadata-"+key, value)
+			}
+		}
+
+		var response proto.Message = output.Interface()
+		if route.responseBody != "" {
+			if fd := findField(output.Descriptor(), route.responseBody); fd != nil && fd.Message() != nil {
+				response = output.Get(fd).Message().Interface()
+			}
+		}
+		body, err := protojson.Marshal(response)
+		if err != nil {
+			wrapper.NewHTTPResponse(http.StatusInternalServerError, "Failed encode response", err).JSON(w)
+			return
+		}
+		wrapper.NewHTTPResponse(http.StatusOK, "Success", json.RawMessage(body)).JSON(w)
+	}
+}
+
+// invoke unary method in grpc server engine with HTTP/2 request in-process, return response header metadata
+func (t *grpcTranscoder) invoke(req *http.Request, fullMethod string, input, output proto.Message) (metadata.MD, error) {
+	payload, err := proto.Marshal(input)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	frame := make([]byte, 5, 5+len(payload))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	frame = append(frame, payload...)
+
+	grpcReq, err := http.NewRequestWithContext(req.Context(), http.MethodPost, fullMethod, bytes.NewReader(frame))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	grpcReq.Proto, grpcReq.ProtoMajor, grpcReq.ProtoMinor = "HTTP/2.0", 2, 0
+	grpcReq.Host, grpcReq.RemoteAddr, grpcReq.TLS = req.Host, req.RemoteAddr, req.TLS
+	grpcReq.Header = headerToMetadataHeader(req.Header)
+	grpcReq.Header.Set("Content-Type", "application/grpc+proto")
+
+	rec := &grpcResponseRecorder{header: make(http.Header), code: http.StatusOK}
+	t.engine.ServeHTTP(rec, grpcReq)
+	return rec.result(output)
+}
+
+func decodeTranscodeRequest(req *http.Request, route transcodeRoute, input protoreflect.Message) error {
+	unmarshaler := protojson.UnmarshalOptions{DiscardUnknown: true}
+	if route.body != "" {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		if len(body) > 0 {
+			target := input
+			if route.body != "*" {
+				fd := findField(input.Descriptor(), route.body)
+				if fd == nil || fd.Message() == nil {
+					return fmt.Errorf("body field %s must be message type", route.body)
+				}
+				target = input.Mutable(fd).Message()
+			}
+			if err := unmarshaler.Unmarshal(body, target.Interface()); err != nil {
+				return err
+			}
+		}
+	}
+
+	for key, fieldPath := range route.pathParams {
+		if err := setFieldFromString(input, fieldPath, chi.URLParam(req, key)); err != nil {
+			return err
+		}
+	}
+	if route.body != "*" {
+		for key, values := range req.URL.Query() {
+			for _, value := range values {
+				if err := setFieldFromString(input, key, value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func findField(desc protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	if fd := desc.Fields().ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+	return desc.Fields().ByJSONName(name)
+}
+
+// setFieldFromString set field value (with dot separated path) from string in url path or query param
+func setFieldFromString(msg protoreflect.Message, fieldPath, value string) error {
+	names := strings.Split(fieldPath, ".")
+	for i, name := range names {
+		fd := findField(msg.Descriptor(), name)
+		if fd == nil {
+			return nil // ignore unknown field
+		}
+		if i < len(names)-1 {
+			if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+				return fmt.Errorf("field %s is not message type", fieldPath)
+			}
+			msg = msg.Mutable(fd).Message()
+			continue
+		}
+
+		v, err := parseFieldValue(msg, fd, value)
+		if err != nil {
+			return fmt.Errorf("invalid value for field %s: %w", fieldPath, err)
+		}
+		if fd.IsList() {
+			msg.Mutable(fd).List().Append(v)
+		} else {
+			msg.Set(fd, v)
+		}
+	}
+	return nil
+}
+
+func parseFieldValue(msg protoreflect.Message, fd protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(value)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		i, err := strconv.ParseInt(value, 10, 32)
+		return protoreflect.ValueOfInt32(int32(i)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		i, err := strconv.ParseInt(value, 10, 64)
+		return protoreflect.ValueOfInt64(i), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		i, err := strconv.ParseUint(value, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(i)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		i, err := strconv.ParseUint(value, 10, 64)
+		return protoreflect.ValueOfUint64(i), err
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(value, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(value, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(value), nil
+	case protoreflect.BytesKind:
+		b, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			b, err = base64.URLEncoding.DecodeString(value)
+		}
+		return protoreflect.ValueOfBytes(b), err
+	case protoreflect.EnumKind:
+		if enumValue := fd.Enum().Values().ByName(protoreflect.Name(value)); enumValue != nil {
+			return protoreflect.ValueOfEnum(enumValue.Number()), nil
+		}
+		i, err := strconv.ParseInt(value, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(i)), err
+	case protoreflect.MessageKind:
+		// well known types (timestamp, duration, wrappers, field mask) in json string format
+		var sub protoreflect.Message
+		if fd.IsList() {
+			sub = msg.Mutable(fd).List().NewElement().Message()
+		} else {
+			sub = msg.NewField(fd).Message()
+		}
+		err := protojson.Unmarshal([]byte(strconv.Quote(value)), sub.Interface())
+		return protoreflect.ValueOfMessage(sub), err
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported field kind %s", fd.Kind())
+}
+
+// headerToMetadataHeader filter http header for grpc request metadata
+func headerToMetadataHeader(header http.Header) http.Header {
+	md := make(http.Header, len(header))
+	for key, values := range header {
+		switch strings.ToLower(key) {
+		case "connection", "content-length", "content-type", "te", "transfer-encoding", "upgrade", "keep-alive", "host", "trailer":
+			continue
+		}
+		md[key] = values
+	}
+	return md
+}
+
+func (r *grpcResponseRecorder) Header() http.Header         { return r.header }
+func (r *grpcResponseRecorder) Write(b []byte) (int, error) { return r.body.Write(b) }
+func (r *grpcResponseRecorder) WriteHeader(code int)        { r.code = code }
+func (r *grpcResponseRecorder) Flush()                      {}
+
+// result decode grpc status and response message from recorded response
+func (r *grpcResponseRecorder) result(output proto.Message) (metadata.MD, error) {
+	grpcStatus := r.header.Get("Grpc-Status")
+	if grpcStatus == "" {
+		// request is rejected by grpc server engine before handled
+		return nil, status.Error(codes.Internal, strings.TrimSpace(r.body.String()))
+	}
+	if code, _ := strconv.Atoi(grpcStatus); codes.Code(code) != codes.OK {
+		message, _ := url.PathUnescape(r.header.Get("Grpc-Message"))
+		return nil, status.Error(codes.Code(code), message)
+	}
+
+	body := r.body.Bytes()
+	if len(body) < 5 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+		return nil, status.Error(codes.Internal, "invalid grpc response frame")
+	}
+	if body[0] != 0 {
+		return nil, status.Error(codes.Internal, "compressed grpc response is not supported")
+	}
+	if err := proto.Unmarshal(body[5:], output); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	md := make(metadata.MD)
+	for key, values := range r.header {
+		lower := strings.ToLower(key)
+		if lower == "content-type" || lower == "trailer" || strings.HasPrefix(lower, "grpc-") || strings.HasPrefix(key, http.TrailerPrefix) {
+			continue
+		}
+		md.Append(lower, values...)
+	}
+	return md, nil
+}
+
+// httpStatusFromGRPCCode map grpc status code to http status code
+func httpStatusFromGRPCCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // client closed request
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
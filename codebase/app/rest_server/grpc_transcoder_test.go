@@ -0,0 +1,155 @@
+package restserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestParsePathTemplate(t *testing.T) {
+	tests := []struct {
+		template, pattern string
+		params            map[string]string
+		wantErr           bool
+	}{
+		{template: "/v1/users", pattern: "/v1/users", params: map[string]string{}},
+		{template: "/v1/users/{id}", pattern: "/v1/users/{p0}", params: map[string]string{"p0": "id"}},
+		{template: "/v1/users/{user.id=*}/books/{book_id}", pattern: "/v1/users/{p0}/books/{p1}",
+			params: map[string]string{"p0": "user.id", "p1": "book_id"}},
+		{template: "/v1/files/{path=**}", pattern: "/v1/files/*", params: map[string]string{"*": "path"}},
+		{template: "/v1/{name=shelves/*}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			pattern, params, err := parsePathTemplate(tt.template)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.pattern, pattern)
+			assert.Equal(t, tt.params, params)
+		})
+	}
+}
+
+func TestParseFieldValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		msg   protoreflect.Message
+		field string
+		value string
+		want  any
+	}{
+		{name: "bool", msg: (&wrapperspb.BoolValue{}).ProtoReflect(), field: "value", value: "true", want: true},
+		{name: "int32", msg: (&wrapperspb.Int32Value{}).ProtoReflect(), field: "value", value: "-12", want: int32(-12)},
+		{name: "uint64", msg: (&wrapperspb.UInt64Value{}).ProtoReflect(), field: "value", value: "12", want: uint64(12)},
+		{name: "double", msg: (&wrapperspb.DoubleValue{}).ProtoReflect(), field: "value", value: "1.5", want: 1.5},
+		{name: "string", msg: (&wrapperspb.StringValue{}).ProtoReflect(), field: "value", value: "candi", want: "candi"},
+		{name: "bytes", msg: (&wrapperspb.BytesValue{}).ProtoReflect(), field: "value", value: "Y2FuZGk=", want: []byte("candi")},
+		{name: "enum name", msg: (&descriptorpb.FieldDescriptorProto{}).ProtoReflect(), field: "type", value: "TYPE_STRING",
+			want: protoreflect.EnumNumber(descriptorpb.FieldDescriptorProto_TYPE_STRING)},
+		{name: "enum number", msg: (&descriptorpb.FieldDescriptorProto{}).ProtoReflect(), field: "type", value: "5",
+			want: protoreflect.EnumNumber(descriptorpb.FieldDescriptorProto_TYPE_INT32)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := parseFieldValue(tt.msg, findField(tt.msg.Descriptor(), tt.field), tt.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, v.Interface())
+		})
+	}
+
+	msg := (&wrapperspb.Int32Value{}).ProtoReflect()
+	_, err := parseFieldValue(msg, findField(msg.Descriptor(), "value"), "abc")
+	assert.Error(t, err)
+}
+
+func TestHTTPStatusFromGRPCCode(t *testing.T) {
+	assert.Equal(t, http.StatusOK, httpStatusFromGRPCCode(codes.OK))
+	assert.Equal(t, http.StatusBadRequest, httpStatusFromGRPCCode(codes.InvalidArgument))
+	assert.Equal(t, http.StatusNotFound, httpStatusFromGRPCCode(codes.NotFound))
+	assert.Equal(t, http.StatusConflict, httpStatusFromGRPCCode(codes.AlreadyExists))
+	assert.Equal(t, http.StatusUnauthorized, httpStatusFromGRPCCode(codes.Unauthenticated))
+	assert.Equal(t, http.StatusForbidden, httpStatusFromGRPCCode(codes.PermissionDenied))
+	assert.Equal(t, http.StatusTooManyRequests, httpStatusFromGRPCCode(codes.ResourceExhausted))
+	assert.Equal(t, http.StatusServiceUnavailable, httpStatusFromGRPCCode(codes.Unavailable))
+	assert.Equal(t, http.StatusInternalServerError, httpStatusFromGRPCCode(codes.DataLoss))
+}
+
+func TestGRPCTranscoderInvoke(t *testing.T) {
+	var intercepted []string
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		intercepted = append(intercepted, info.FullMethod)
+		md, _ := metadata.FromIncomingContext(ctx)
+		if len(md.Get("authorization")) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "missing authorization")
+		}
+		return handler(ctx, req)
+	}))
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Echo",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Echo",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := new(wrapperspb.StringValue)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req any) (any, error) {
+					grpc.SetHeader(ctx, metadata.Pairs("x-echo", "true"))
+					return wrapperspb.String("echo " + req.(*wrapperspb.StringValue).GetValue()), nil
+				}
+				return interceptor(ctx, in, &grpc.UnaryServerInfo{FullMethod: "/test.Echo/Echo"}, handler)
+			},
+		}},
+	}, struct{}{})
+
+	transcoder := &grpcTranscoder{engine: server}
+	handler := transcoder.handler(transcodeRoute{
+		httpMethod: http.MethodPost, pattern: "/test.Echo/Echo", fullMethod: "/test.Echo/Echo", body: "*",
+		input:  (&wrapperspb.StringValue{}).ProtoReflect().Type(),
+		output: (&wrapperspb.StringValue{}).ProtoReflect().Type(),
+	})
+
+	t.Run("success", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/test.Echo/Echo", strings.NewReader(`"candi"`))
+		req.Header.Set("Authorization", "Bearer token")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		var resp struct {
+			Code int    `json:"code"`
+			Data string `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "echo candi", resp.Data)
+		assert.Equal(t, "true", rec.Header().Get("Grpc-Metadata-x-echo"))
+	})
+
+	t.Run("error from interceptor", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/test.Echo/Echo", strings.NewReader(`"candi"`))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), "missing authorization")
+	})
+
+	assert.Equal(t, []string{"/test.Echo/Echo", "/test.Echo/Echo"}, intercepted)
+}
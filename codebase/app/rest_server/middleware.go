@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"strconv"
@@ -11,8 +12,8 @@ import (
 	"time"
 
 	"github.com/golangid/candi/candihelper"
+	"github.com/golangid/candi/candishared"
 	"github.com/golangid/candi/candiutils"
-	"github.com/golangid/candi/logger"
 	"github.com/golangid/candi/tracer"
 	"github.com/golangid/candi/wrapper"
 )
@@ -34,6 +35,10 @@ type restMiddleware struct {
 	disableTrace       bool
 	maxLogSize         int
 	logResponseWriters []io.Writer
+	accessLogFormatter AccessLogFormatter
+	accessLogZap       *zapAccessLogger
+	requestIDHeader    string
+	requestIDGenerator func() string
 
 	corsAllowMethods, corsAllowHeaders, corsAllowOrigins []string
 	corsExposeHeaders                                    []string
@@ -56,6 +61,19 @@ func (r *restMiddleware) Tracer() func(http.Handler) http.Handler {
 				return
 			}
 
+			// request id from client (if valid) or generated, propagated to context, response header and outgoing request (candiutils.HTTPRequest)
+			requestID := req.Header.Get(r.requestIDHeader)
+			if !isValidRequestID(requestID) {
+				requestID = r.requestIDGenerator()
+				req.Header.Set(r.requestIDHeader, requestID)
+			}
+			rw.Header().Set(r.requestIDHeader, requestID)
+			ctx = candishared.SetToContext(ctx, candishared.ContextKeyRequestID, requestID)
+			// token claim is set by auth middleware in downstream request context, read from holder for access log
+			tokenClaimHolder := new(candishared.TokenClaimHolder)
+			ctx = candishared.SetToContext(ctx, candishared.ContextKeyTokenClaimHolder, tokenClaimHolder)
+			req = req.WithContext(ctx)
+
 			var trace tracer.Tracer
 			isDisableTrace, _ := strconv.ParseBool(req.Header.Get(candihelper.HeaderDisableTrace))
 			if isDisableTrace || r.disableTrace {
@@ -74,6 +92,7 @@ func (r *restMiddleware) Tracer() func(http.Handler) http.Handler {
 				trace.SetTag("http.host", req.Host)
 				trace.SetTag("http.url_path", req.URL.Path)
 				trace.SetTag("http.method", req.Method)
+				trace.SetTag("http.request_id", requestID)
 
 				if contentLength, err := strconv.Atoi(req.Header.Get("Content-Length")); err == nil {
 					if contentLength < r.maxLogSize {
@@ -115,80 +134,67 @@ func (r *restMiddleware) Tracer() func(http.Handler) http.Handler {
 				trace.Log("response.body.size", candihelper.TransformSizeToByte(uint64(respWriter.GetContentLength())))
 			}
 
-			if len(r.logResponseWriters) == 0 {
+			if len(r.logResponseWriters) == 0 && r.accessLogZap == nil {
 				return
 			}
 
 			// log request
 			stop := time.Now()
-			logBuff := bPool.Get()
-			defer bPool.Put(logBuff)
-
-			logBuff.WriteString(`{"time":"`)
-			logBuff.WriteString(time.Now().Format(time.RFC3339Nano))
-
-			if id := req.Header.Get("X-Request-ID"); id != "" {
-				logBuff.WriteString(`","id":"`)
-				logBuff.WriteString(id)
+			entry := &AccessLogEntry{
+				Time:      stop,
+				RequestID: requestID,
+				RemoteIP:  req.Header.Get(candihelper.HeaderXRealIP),
+				Method:    req.Method,
+				Host:      req.Host,
+				Path:      req.URL.Path,
+				Query:     req.URL.RawQuery,
+				Proto:     req.Proto,
+				UserAgent: req.UserAgent(),
+				Referer:   req.Referer(),
+				Status:    respWriter.StatusCode(),
+				Latency:   stop.Sub(start),
+				BytesIn:   max(req.ContentLength, 0),
+				BytesOut:  int64(respWriter.GetContentLength()),
 			}
-
-			if realIP := req.Header.Get("X-Real-IP"); realIP != "" {
-				logBuff.WriteString(`","remote_ip":"`)
-				logBuff.WriteString(realIP)
-			}
-
-			logBuff.WriteString(`","method":"`)
-			logBuff.WriteString(req.Method)
-
-			logBuff.WriteString(`","host":"`)
-			logBuff.WriteString(req.Host)
-
-			logBuff.WriteString(`","path":"`)
-			logBuff.WriteString(req.URL.Path)
-			logBuff.WriteString(`","query":"`)
-			logBuff.WriteString(req.URL.RawQuery)
-
-			logBuff.WriteString(`","user_agent":"`)
-			logBuff.WriteString(req.UserAgent())
-
-			logBuff.WriteString(`","status":`)
-
-			s := logger.GreenColor(respWriter.StatusCode())
-			switch {
-			case respWriter.StatusCode() >= 500:
-				s = logger.RedColor(respWriter.StatusCode())
-			case respWriter.StatusCode() >= 400:
-				s = logger.YellowColor(respWriter.StatusCode())
-			case respWriter.StatusCode() >= 300:
-				s = logger.CyanColor(respWriter.StatusCode())
+			if entry.RemoteIP == "" {
+				entry.RemoteIP, _, _ = net.SplitHostPort(req.RemoteAddr)
 			}
-			logBuff.WriteString(s)
-
-			logBuff.WriteString(`,"latency":"`)
-			logBuff.WriteString(stop.Sub(start).String())
-
 			if _, ok := trace.(*tracer.NoopTracer); !ok {
-				logBuff.WriteString(`","trace_id":"`)
-				logBuff.WriteString(tracer.GetTraceID(ctx))
+				entry.TraceID = tracer.GetTraceID(ctx)
 			}
-
-			logBuff.WriteString(`","bytes_in":`)
-			cl := req.Header.Get("Content-Length")
-			if cl == "" {
-				cl = "0"
+			if tokenClaim := tokenClaimHolder.Get(); tokenClaim != nil {
+				entry.User = tokenClaim.Subject
 			}
-			logBuff.WriteString(cl)
 
-			logBuff.WriteString(`,"bytes_out":`)
-			logBuff.WriteString(strconv.FormatInt(int64(respWriter.GetContentLength()), 10))
-
-			logBuff.WriteString("}\n")
+			if r.accessLogZap != nil {
+				r.accessLogZap.log(entry)
+				return
+			}
 
+			logBuff := bPool.Get()
+			defer bPool.Put(logBuff)
+			r.accessLogFormatter(logBuff, entry)
 			io.Copy(io.MultiWriter(r.logResponseWriters...), logBuff)
 		})
 	}
 }
 
+// isValidRequestID request id from client must be 1-128 characters of [A-Za-z0-9._-],
+// prevent log injection and unbounded value propagated to log, trace and outgoing request
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > 128 {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		switch c := requestID[i]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // CORS middleware for cors
 func (r *restMiddleware) CORS() func(http.Handler) http.Handler {
 	if len(r.corsAllowOrigins) == 0 {
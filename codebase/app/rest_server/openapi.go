@@ -0,0 +1,557 @@
+package restserver
+
+/*
+	OpenAPI 3.1 document generated from registered RESTRouter routes, enriched with route document
+	(passed as route or group middleware with RouteDoc)
+*/
+
+import (
+	"bytes"
+	"compress/gzip"
+	"embed"
+	"io"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golangid/candi/wrapper"
+)
+
+const (
+	openAPIVersion = "3.1.0"
+
+	// SecuritySchemeBearerAuth OpenAPI security scheme for bearer auth (JWT)
+	SecuritySchemeBearerAuth = "bearerAuth"
+	// SecuritySchemeBasicAuth OpenAPI security scheme for basic auth
+	SecuritySchemeBasicAuth = "basicAuth"
+)
+
+var (
+	pathParamRegex   = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+	schemaNameRegex  = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+	timeType         = reflect.TypeOf(time.Time{})
+	httpResponseType = reflect.TypeOf(wrapper.HTTPResponse{})
+
+	//go:embed static/swagger-ui/*.gz
+	swaggerUIAssets embed.FS
+)
+
+type (
+	// RouteDocument route metadata for OpenAPI document
+	RouteDocument struct {
+		Summary     string
+		Description string
+		OperationID string
+		Tags        []string
+		Deprecated  bool
+		// RequestBody example value of request body type (struct)
+		RequestBody any
+		// QueryParams example value of query params type (struct with "query" or "json" tag)
+		QueryParams any
+		// Responses example value of response data type by http status code, data is wrapped in standard wrapper.HTTPResponse
+		Responses map[int]any
+		// Security list of alternative security requirement, all schemes in each requirement is required
+		Security [][]string
+		// PermissionACL route is protected with permission ACL middleware
+		PermissionACL bool
+	}
+
+	// RouteDocOption function type for setting route document
+	RouteDocOption func(*RouteDocument)
+
+	routeDocHandler struct {
+		next http.Handler
+		doc  *RouteDocument
+	}
+
+	registeredRoute struct {
+		method string
+		path   string
+		doc    *RouteDocument
+		// groupDocs route document from parent groups, applied if not set in route document
+		groupDocs []*RouteDocument
+	}
+
+	routeRegistry struct {
+		mu     sync.Mutex
+		routes []*registeredRoute
+	}
+)
+
+// RouteDoc set route metadata for OpenAPI document, pass as route middleware or group middleware
+// (tags and security is applied to all routes in group). Example:
+//
+//	route.GET("/:id", h.getDetail, h.mw.HTTPBearerAuth, restserver.RouteDoc(
+//		restserver.RouteDocSetSummary("Get detail"),
+//		restserver.RouteDocAddSecurity(restserver.SecuritySchemeBearerAuth),
+//		restserver.RouteDocAddResponse(http.StatusOK, domain.ResponseDetail{}),
+//	))
+func RouteDoc(opts ...RouteDocOption) func(http.Handler) http.Handler {
+	doc := &RouteDocument{Responses: make(map[int]any)}
+	for _, opt := range opts {
+		opt(doc)
+	}
+	return func(next http.Handler) http.Handler {
+		return &routeDocHandler{next: next, doc: doc}
+	}
+}
+
+func (h *routeDocHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.next.ServeHTTP(w, req)
+}
+
+// RouteDocSetSummary route doc option func
+func RouteDocSetSummary(summary string) RouteDocOption {
+	return func(d *RouteDocument) {
+		d.Summary = summary
+	}
+}
+
+// RouteDocSetDescription route doc option func
+func RouteDocSetDescription(description string) RouteDocOption {
+	return func(d *RouteDocument) {
+		d.Description = description
+	}
+}
+
+// RouteDocSetOperationID route doc option func
+func RouteDocSetOperationID(operationID string) RouteDocOption {
+	return func(d *RouteDocument) {
+		d.OperationID = operationID
+	}
+}
+
+// RouteDocSetTags route doc option func
+func RouteDocSetTags(tags ...string) RouteDocOption {
+	return func(d *RouteDocument) {
+		d.Tags = tags
+	}
+}
+
+// RouteDocSetDeprecated route doc option func
+func RouteDocSetDeprecated() RouteDocOption {
+	return func(d *RouteDocument) {
+		d.Deprecated = true
+	}
+}
+
+// RouteDocSetRequestBody route doc option func, body is value of request body type
+func RouteDocSetRequestBody(body any) RouteDocOption {
+	return func(d *RouteDocument) {
+		d.RequestBody = body
+	}
+}
+
+// RouteDocSetQueryParams route doc option func, params is value of struct type with "query" or "json" tag
+func RouteDocSetQueryParams(params any) RouteDocOption {
+	return func(d *RouteDocument) {
+		d.QueryParams = params
+	}
+}
+
+// RouteDocAddResponse route doc option func, data is value of response data type (nil if no data)
+func RouteDocAddResponse(statusCode int, data any) RouteDocOption {
+	return func(d *RouteDocument) {
+		d.Responses[statusCode] = data
+	}
+}
+
+// RouteDocAddSecurity route doc option func, add security requirement with all schemes is required,
+// call multiple times for alternative requirement (example: bearer or basic auth with HTTPMultipleAuth)
+func RouteDocAddSecurity(schemes ...string) RouteDocOption {
+	return func(d *RouteDocument) {
+		d.Security = append(d.Security, schemes)
+	}
+}
+
+// RouteDocSetPermissionACL route doc option func, route is protected with permission ACL middleware
+func RouteDocSetPermissionACL() RouteDocOption {
+	return func(d *RouteDocument) {
+		d.PermissionACL = true
+	}
+}
+
+func (r *routeRegistry) add(route *registeredRoute) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, route)
+}
+
+type (
+	openAPIDocument struct {
+		OpenAPI    string                                  `json:"openapi"`
+		Info       openAPIInfo                             `json:"info"`
+		Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+		Components openAPIComponents                       `json:"components"`
+	}
+
+	openAPIInfo struct {
+		Title       string `json:"title"`
+		Version     string `json:"version"`
+		Description string `json:"description,omitempty"`
+	}
+
+	openAPIComponents struct {
+		Schemas         map[string]any `json:"schemas,omitempty"`
+		SecuritySchemes map[string]any `json:"securitySchemes,omitempty"`
+	}
+
+	openAPIOperation struct {
+		Tags          []string                    `json:"tags,omitempty"`
+		Summary       string                      `json:"summary,omitempty"`
+		Description   string                      `json:"description,omitempty"`
+		OperationID   string                      `json:"operationId,omitempty"`
+		Deprecated    bool                        `json:"deprecated,omitempty"`
+		Parameters    []openAPIParameter          `json:"parameters,omitempty"`
+		RequestBody   *openAPIRequestBody         `json:"requestBody,omitempty"`
+		Responses     map[string]*openAPIResponse `json:"responses"`
+		Security      []map[string][]string       `json:"security,omitempty"`
+		PermissionACL bool                        `json:"x-permission-acl,omitempty"`
+	}
+
+	openAPIParameter struct {
+		Name     string         `json:"name"`
+		In       string         `json:"in"`
+		Required bool           `json:"required,omitempty"`
+		Schema   map[string]any `json:"schema"`
+	}
+
+	openAPIRequestBody struct {
+		Required bool                        `json:"required"`
+		Content  map[string]openAPIMediaType `json:"content"`
+	}
+
+	openAPIResponse struct {
+		Description string                      `json:"description"`
+		Content     map[string]openAPIMediaType `json:"content,omitempty"`
+	}
+
+	openAPIMediaType struct {
+		Schema map[string]any `json:"schema"`
+	}
+)
+
+// buildOpenAPIDocument generate OpenAPI document from registered routes
+func buildOpenAPIDocument(info openAPIInfo, routes []*registeredRoute) *openAPIDocument {
+	doc := &openAPIDocument{
+		OpenAPI:    openAPIVersion,
+		Info:       info,
+		Paths:      make(map[string]map[string]*openAPIOperation),
+		Components: openAPIComponents{Schemas: make(map[string]any), SecuritySchemes: make(map[string]any)},
+	}
+	gen := &openAPISchemaGenerator{schemas: doc.Components.Schemas}
+
+	for _, route := range routes {
+		// route registered with HandleFunc (any method) is not documented
+		if route.method == "" || route.method == http.MethodConnect {
+			continue
+		}
+
+		path := pathParamRegex.ReplaceAllString(route.path, "{$1}")
+		op := &openAPIOperation{Responses: make(map[string]*openAPIResponse)}
+		for _, match := range pathParamRegex.FindAllStringSubmatch(route.path, -1) {
+			op.Parameters = append(op.Parameters, openAPIParameter{
+				Name: match[1], In: "path", Required: true, Schema: map[string]any{"type": "string"},
+			})
+		}
+
+		for _, d := range append(route.groupDocs[:len(route.groupDocs):len(route.groupDocs)], route.doc) {
+			if d == nil {
+				continue
+			}
+			if len(d.Tags) > 0 {
+				op.Tags = d.Tags
+			}
+			if len(d.Security) > 0 {
+				op.Security = nil
+				for _, schemes := range d.Security {
+					security := make(map[string][]string, len(schemes))
+					for _, scheme := range schemes {
+						security[scheme] = []string{}
+						doc.Components.SecuritySchemes[scheme] = securitySchemeDefinition(scheme)
+					}
+					op.Security = append(op.Security, security)
+				}
+			}
+			op.PermissionACL = op.PermissionACL || d.PermissionACL
+		}
+
+		if d := route.doc; d != nil {
+			op.Summary, op.Description = d.Summary, d.Description
+			op.OperationID, op.Deprecated = d.OperationID, d.Deprecated
+			if d.QueryParams != nil {
+				op.Parameters = append(op.Parameters, gen.queryParameters(reflect.TypeOf(d.QueryParams))...)
+			}
+			if d.RequestBody != nil {
+				op.RequestBody = &openAPIRequestBody{
+					Required: true,
+					Content: map[string]openAPIMediaType{
+						"application/json": {Schema: gen.schemaOf(reflect.TypeOf(d.RequestBody))},
+					},
+				}
+			}
+			for code, data := range d.Responses {
+				op.Responses[strconv.Itoa(code)] = gen.response(code, data)
+			}
+		}
+
+		if len(op.Responses) == 0 {
+			op.Responses[strconv.Itoa(http.StatusOK)] = gen.response(http.StatusOK, nil)
+		}
+		if len(op.Security) > 0 {
+			if _, ok := op.Responses[strconv.Itoa(http.StatusUnauthorized)]; !ok {
+				op.Responses[strconv.Itoa(http.StatusUnauthorized)] = gen.response(http.StatusUnauthorized, nil)
+			}
+		}
+		if op.PermissionACL {
+			if _, ok := op.Responses[strconv.Itoa(http.StatusForbidden)]; !ok {
+				op.Responses[strconv.Itoa(http.StatusForbidden)] = gen.response(http.StatusForbidden, nil)
+			}
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*openAPIOperation)
+		}
+		doc.Paths[path][strings.ToLower(route.method)] = op
+	}
+	return doc
+}
+
+func securitySchemeDefinition(scheme string) map[string]any {
+	switch scheme {
+	case SecuritySchemeBasicAuth:
+		return map[string]any{"type": "http", "scheme": "basic"}
+	default:
+		return map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"}
+	}
+}
+
+// openAPISchemaGenerator generate JSON schema from go type, named struct is registered to components schemas
+type openAPISchemaGenerator struct {
+	schemas map[string]any
+}
+
+func (g *openAPISchemaGenerator) response(statusCode int, data any) *openAPIResponse {
+	schema := g.schemaOf(httpResponseType)
+	if data != nil {
+		schema = map[string]any{
+			"allOf": []any{
+				schema,
+				map[string]any{"type": "object", "properties": map[string]any{"data": g.schemaOf(reflect.TypeOf(data))}},
+			},
+		}
+	}
+	return &openAPIResponse{
+		Description: http.StatusText(statusCode),
+		Content:     map[string]openAPIMediaType{"application/json": {Schema: schema}},
+	}
+}
+
+func (g *openAPISchemaGenerator) queryParameters(typ reflect.Type) (params []openAPIParameter) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous {
+			params = append(params, g.queryParameters(field.Type)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		for _, tag := range []string{"query", "json"} {
+			if tagName, _, _ := strings.Cut(field.Tag.Get(tag), ","); tagName != "" {
+				name = tagName
+				break
+			}
+		}
+		if name == "-" {
+			continue
+		}
+		params = append(params, openAPIParameter{Name: name, In: "query", Schema: g.schemaOf(field.Type)})
+	}
+	return params
+}
+
+func (g *openAPISchemaGenerator) schemaOf(typ reflect.Type) map[string]any {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch {
+	case typ == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8:
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schemaOf(typ.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schemaOf(typ.Elem())}
+	case reflect.Struct:
+		if typ.Name() == "" {
+			return g.structSchema(typ)
+		}
+		name := schemaNameRegex.ReplaceAllString(typ.Name(), "_")
+		ref := map[string]any{"$ref": "#/components/schemas/" + name}
+		if _, ok := g.schemas[name]; ok {
+			return ref
+		}
+		g.schemas[name] = map[string]any{} // placeholder for recursive type
+		g.schemas[name] = g.structSchema(typ)
+		return ref
+	default:
+		return map[string]any{}
+	}
+}
+
+func (g *openAPISchemaGenerator) structSchema(typ reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			name, opts, _ := strings.Cut(tag, ",")
+			if name == "-" {
+				continue
+			}
+
+			fieldType := field.Type
+			if field.Anonymous && name == "" {
+				for fieldType.Kind() == reflect.Ptr {
+					fieldType = fieldType.Elem()
+				}
+				if fieldType.Kind() == reflect.Struct {
+					collect(fieldType)
+					continue
+				}
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			properties[name] = g.schemaOf(field.Type)
+			if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Ptr && field.Type.Kind() != reflect.Interface {
+				required = append(required, name)
+			}
+		}
+	}
+	collect(typ)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+func serveOpenAPIDocument(spec []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	}
+}
+
+func serveSwaggerUI(specURL, assetsURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8"/>
+	<meta name="viewport" content="width=device-width, initial-scale=1"/>
+	<title>Swagger UI</title>
+	<link rel="stylesheet" href="` + assetsURL + `/swagger-ui.css"/>
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="` + assetsURL + `/swagger-ui-bundle.js"></script>
+<script>
+	window.onload = function () {
+		window.ui = SwaggerUIBundle({ url: '` + specURL + `', dom_id: '#swagger-ui', deepLinking: true });
+	};
+</script>
+</body>
+</html>`))
+	}
+}
+
+// serveSwaggerUIAsset serve embedded gzipped swagger ui asset, decompressed if client not accept gzip encoding
+func serveSwaggerUIAsset(w http.ResponseWriter, req *http.Request) {
+	name := path.Base(req.URL.Path)
+	content, err := swaggerUIAssets.ReadFile("static/swagger-ui/" + name + ".gz")
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	switch path.Ext(name) {
+	case ".css":
+		w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	case ".js":
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	}
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Add("Vary", "Accept-Encoding")
+	if strings.Contains(req.Header.Get("Accept-Encoding"), "gzip") {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(content)
+		return
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer gz.Close()
+	io.Copy(w, gz)
+}
+
+func serveRedoc(specURL, scriptURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8"/>
+	<meta name="viewport" content="width=device-width, initial-scale=1"/>
+	<title>Redoc</title>
+	<style>body { margin: 0; padding: 0; }</style>
+</head>
+<body>
+<redoc spec-url="` + specURL + `"></redoc>
+<script src="` + scriptURL + `"></script>
+</body>
+</html>`))
+	}
+}
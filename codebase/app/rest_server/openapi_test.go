@@ -0,0 +1,144 @@
+package restserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type openAPITestRequest struct {
+	Name     string   `json:"name"`
+	Email    string   `json:"email,omitempty"`
+	Tags     []string `json:"tags"`
+	Internal string   `json:"-"`
+}
+
+type openAPITestFilter struct {
+	Page   int    `query:"page"`
+	Search string `json:"search"`
+}
+
+type openAPITestResponse struct {
+	ID        int                  `json:"id"`
+	CreatedAt time.Time            `json:"createdAt"`
+	Parent    *openAPITestResponse `json:"parent"`
+}
+
+func TestBuildOpenAPIDocument(t *testing.T) {
+	noop := func(w http.ResponseWriter, req *http.Request) {}
+	registry := &routeRegistry{}
+	root := &routeWrapper{router: chi.NewRouter(), prefix: "/", registry: registry}
+
+	root.GET("/health", noop)
+	root.HandleFunc("/any", noop)
+	v1 := root.Group("/v1/users", RouteDoc(
+		RouteDocSetTags("User"),
+		RouteDocAddSecurity(SecuritySchemeBearerAuth),
+	))
+	v1.GET("/:id", noop, RouteDoc(
+		RouteDocSetSummary("Get user"),
+		RouteDocSetOperationID("getUser"),
+		RouteDocSetQueryParams(openAPITestFilter{}),
+		RouteDocAddResponse(http.StatusOK, openAPITestResponse{}),
+	))
+	v1.POST("/", noop, RouteDoc(
+		RouteDocSetRequestBody(openAPITestRequest{}),
+		RouteDocAddSecurity(SecuritySchemeBearerAuth),
+		RouteDocAddSecurity(SecuritySchemeBasicAuth),
+		RouteDocSetPermissionACL(),
+		RouteDocSetDeprecated(),
+	))
+
+	doc := buildOpenAPIDocument(openAPIInfo{Title: "test", Version: "1.0.0"}, registry.routes)
+	assert.Equal(t, openAPIVersion, doc.OpenAPI)
+	assert.Len(t, doc.Paths, 3)
+	assert.NotContains(t, doc.Paths, "/any")
+
+	health := doc.Paths["/health"]["get"]
+	require.NotNil(t, health)
+	assert.Empty(t, health.Security)
+	assert.Contains(t, health.Responses, "200")
+
+	getUser := doc.Paths["/v1/users/{id}"]["get"]
+	require.NotNil(t, getUser)
+	assert.Equal(t, []string{"User"}, getUser.Tags)
+	assert.Equal(t, "getUser", getUser.OperationID)
+	assert.Equal(t, []map[string][]string{{SecuritySchemeBearerAuth: {}}}, getUser.Security)
+	assert.Equal(t, []openAPIParameter{
+		{Name: "id", In: "path", Required: true, Schema: map[string]any{"type": "string"}},
+		{Name: "page", In: "query", Schema: map[string]any{"type": "integer", "format": "int32"}},
+		{Name: "search", In: "query", Schema: map[string]any{"type": "string"}},
+	}, getUser.Parameters)
+	assert.Contains(t, getUser.Responses, "200")
+	assert.Contains(t, getUser.Responses, "401")
+	assert.NotContains(t, getUser.Responses, "403")
+
+	createUser := doc.Paths["/v1/users"]["post"]
+	require.NotNil(t, createUser)
+	assert.Equal(t, []string{"User"}, createUser.Tags)
+	assert.True(t, createUser.Deprecated)
+	assert.True(t, createUser.PermissionACL)
+	assert.Equal(t, []map[string][]string{{SecuritySchemeBearerAuth: {}}, {SecuritySchemeBasicAuth: {}}}, createUser.Security)
+	assert.Contains(t, createUser.Responses, "403")
+	require.NotNil(t, createUser.RequestBody)
+	assert.Equal(t, map[string]any{"$ref": "#/components/schemas/openAPITestRequest"}, createUser.RequestBody.Content["application/json"].Schema)
+
+	assert.Equal(t, map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"name":  map[string]any{"type": "string"},
+			"email": map[string]any{"type": "string"},
+			"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+		"required": []string{"name", "tags"},
+	}, doc.Components.Schemas["openAPITestRequest"])
+	assert.Equal(t, map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id":        map[string]any{"type": "integer", "format": "int32"},
+			"createdAt": map[string]any{"type": "string", "format": "date-time"},
+			"parent":    map[string]any{"$ref": "#/components/schemas/openAPITestResponse"},
+		},
+		"required": []string{"createdAt", "id"},
+	}, doc.Components.Schemas["openAPITestResponse"])
+	assert.Contains(t, doc.Components.Schemas, "HTTPResponse")
+	assert.Equal(t, map[string]any{
+		SecuritySchemeBearerAuth: map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+		SecuritySchemeBasicAuth:  map[string]any{"type": "http", "scheme": "basic"},
+	}, doc.Components.SecuritySchemes)
+
+	_, err := json.Marshal(doc)
+	assert.NoError(t, err)
+}
+
+func TestServeSwaggerUIAsset(t *testing.T) {
+	mux := chi.NewRouter()
+	mux.Get("/docs/swagger-ui/{asset}", serveSwaggerUIAsset)
+
+	req := httptest.NewRequest(http.MethodGet, "/docs/swagger-ui/swagger-ui.css", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "text/css; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	req = httptest.NewRequest(http.MethodGet, "/docs/swagger-ui/swagger-ui-bundle.js", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.True(t, strings.Contains(rec.Body.String(), "SwaggerUIBundle"))
+
+	req = httptest.NewRequest(http.MethodGet, "/docs/swagger-ui/unknown.js", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
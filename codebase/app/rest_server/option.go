@@ -7,10 +7,12 @@ import (
 	"os"
 	"strings"
 
+	"github.com/golangid/candi/candihelper"
 	graphqlserver "github.com/golangid/candi/codebase/app/graphql_server"
 	"github.com/golangid/candi/codebase/interfaces"
 	"github.com/golangid/candi/config/env"
 	"github.com/golangid/candi/wrapper"
+	"github.com/google/uuid"
 	"github.com/soheilhy/cmux"
 )
 
@@ -28,6 +30,14 @@ type (
 		sharedListener  cmux.CMux
 		graphqlOption   graphqlserver.Option
 		tlsConfig       *tls.Config
+
+		enableGRPCTranscoding bool
+
+		enableOpenAPI bool
+		openAPIPath   string
+		openAPIInfo   openAPIInfo
+
+		openAPIRedocScriptURL string
 	}
 
 	// OptionFunc type
@@ -45,12 +55,16 @@ func getDefaultOption() option {
 		debugMode: true,
 		baseMiddleware: &restMiddleware{
 			logResponseWriters:  []io.Writer{os.Stdout},
+			accessLogFormatter:  AccessLogFormatJSON,
+			requestIDHeader:     candihelper.HeaderXRequestID,
+			requestIDGenerator:  uuid.NewString,
 			corsAllowMethods:    env.BaseEnv().CORSAllowMethods,
 			corsAllowHeaders:    env.BaseEnv().CORSAllowHeaders,
 			corsAllowOrigins:    env.BaseEnv().CORSAllowOrigins,
 			corsAllowCredential: env.BaseEnv().CORSAllowCredential,
 		},
 		rootHandler: http.HandlerFunc(wrapper.HTTPHandlerDefaultRoot),
+		openAPIPath: "/docs",
 	}
 	return opt
 }
@@ -159,3 +173,76 @@ func SetLogResponseWriter(writers ...io.Writer) OptionFunc {
 		o.baseMiddleware.logResponseWriters = writers
 	}
 }
+
+// SetAccessLogFormat option func, set access log format for log response writers,
+// available: AccessLogFormatJSON (default), AccessLogFormatCombined, AccessLogFormatLogfmt or custom formatter
+func SetAccessLogFormat(formatter AccessLogFormatter) OptionFunc {
+	return func(o *option) {
+		o.baseMiddleware.accessLogFormatter = formatter
+	}
+}
+
+// SetAccessLogToZap option func, route access log to zap logger (see logger.InitZap) instead of log response writers,
+// log first N entries per second then every thereafter-th entry, set thereafter to 0 for disable sampling
+func SetAccessLogToZap(sampleFirst, sampleThereafter int) OptionFunc {
+	return func(o *option) {
+		o.baseMiddleware.accessLogZap = &zapAccessLogger{first: sampleFirst, thereafter: sampleThereafter}
+	}
+}
+
+// SetRequestIDHeader option func, set header for read and write request ID, default is X-Request-ID
+func SetRequestIDHeader(header string) OptionFunc {
+	return func(o *option) {
+		o.baseMiddleware.requestIDHeader = header
+	}
+}
+
+// SetRequestIDGenerator option func, set generator for request ID if not found or invalid in request header
+// (must be 1-128 characters of [A-Za-z0-9._-]), default is UUID v4
+func SetRequestIDGenerator(generator func() string) OptionFunc {
+	return func(o *option) {
+		o.baseMiddleware.requestIDGenerator = generator
+	}
+}
+
+// SetEnableGRPCTranscoding option func, expose registered grpc handler (unary method) in modules over HTTP/JSON
+// using google.api.http annotation, fallback route is "POST /package.Service/Method".
+// gRPC server must be enabled in same service, transcoded call is handled by gRPC server (with same interceptors and middleware group)
+func SetEnableGRPCTranscoding(enable bool) OptionFunc {
+	return func(o *option) {
+		o.enableGRPCTranscoding = enable
+	}
+}
+
+// SetEnableOpenAPI option func, serve OpenAPI document generated from registered routes at {openAPIPath}/openapi.json
+// with embedded Swagger UI at {openAPIPath}
+func SetEnableOpenAPI(enable bool) OptionFunc {
+	return func(o *option) {
+		o.enableOpenAPI = enable
+	}
+}
+
+// SetOpenAPIPath option func, default is "/docs"
+func SetOpenAPIPath(path string) OptionFunc {
+	return func(o *option) {
+		if strings.Trim(path, "/") == "" {
+			return
+		}
+		o.openAPIPath = "/" + strings.Trim(path, "/")
+	}
+}
+
+// SetOpenAPIInfo option func, default title is service name and version is build number
+func SetOpenAPIInfo(title, version, description string) OptionFunc {
+	return func(o *option) {
+		o.openAPIInfo = openAPIInfo{Title: title, Version: version, Description: description}
+	}
+}
+
+// SetOpenAPIRedocScriptURL option func, serve Redoc at {openAPIPath}/redoc using redoc standalone bundle from script url
+// (self hosted or CDN), Redoc is disabled if script url is empty
+func SetOpenAPIRedocScriptURL(scriptURL string) OptionFunc {
+	return func(o *option) {
+		o.openAPIRedocScriptURL = scriptURL
+	}
+}
@@ -3,6 +3,7 @@ package restserver
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net"
@@ -14,6 +15,7 @@ import (
 	graphqlserver "github.com/golangid/candi/codebase/app/graphql_server"
 	"github.com/golangid/candi/codebase/factory"
 	"github.com/golangid/candi/codebase/factory/types"
+	"github.com/golangid/candi/config/env"
 	"github.com/golangid/candi/logger"
 	"github.com/golangid/candi/wrapper"
 	"github.com/soheilhy/cmux"
@@ -23,6 +25,8 @@ type restServer struct {
 	opt        option
 	httpEngine *http.Server
 	listener   net.Listener
+	service    factory.ServiceFactory
+	rootPath   chi.Router
 }
 
 // NewServer create new REST server
@@ -48,7 +52,8 @@ func NewServer(service factory.ServiceFactory, opts ...OptionFunc) factory.AppSe
 	})
 
 	rootPath := mux.Route(server.opt.rootPath, func(chi.Router) {})
-	route := &routeWrapper{router: rootPath}
+	routes := &routeRegistry{}
+	route := &routeWrapper{router: rootPath, prefix: server.opt.rootPath, registry: routes}
 	for _, routerFunc := range server.opt.routerFuncs {
 		routerFunc(route)
 	}
@@ -57,6 +62,14 @@ func NewServer(service factory.ServiceFactory, opts ...OptionFunc) factory.AppSe
 			h.Mount(route)
 		}
 	}
+	if server.opt.enableGRPCTranscoding {
+		// grpc server app may be constructed after REST server, transcoding routes is mounted when serve
+		server.service, server.rootPath = service, rootPath
+	}
+
+	if server.opt.enableOpenAPI {
+		server.mountOpenAPI(mux, routes)
+	}
 
 	countRoute, maxLogRoute := 0, 20
 	chi.Walk(mux, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
@@ -84,6 +97,10 @@ func NewServer(service factory.ServiceFactory, opts ...OptionFunc) factory.AppSe
 		rootPath.HandleFunc(gqlRootPath, graphqlHandler.ServeGraphQL())
 		rootPath.Get(gqlRootPath+"/playground", http.HandlerFunc(graphqlHandler.ServePlayground))
 		rootPath.Get(gqlRootPath+"/voyager", http.HandlerFunc(graphqlHandler.ServeVoyager))
+		if pqHandler, ok := graphqlHandler.(graphqlserver.PersistedQueryHandler); ok {
+			rootPath.With(service.GetDependency().GetMiddleware().HTTPBasicAuth).
+				Post(gqlRootPath+"/persisted-queries", http.HandlerFunc(pqHandler.ServePersistedQueries))
+		}
 	}
 
 	server.httpEngine.Addr = fmt.Sprintf(":%d", server.opt.httpPort)
@@ -102,7 +119,42 @@ func NewServer(service factory.ServiceFactory, opts ...OptionFunc) factory.AppSe
 	return server
 }
 
+func (s *restServer) mountOpenAPI(mux chi.Router, routes *routeRegistry) {
+	info := s.opt.openAPIInfo
+	if info.Title == "" {
+		info.Title = env.BaseEnv().ServiceName
+	}
+	if info.Version == "" {
+		info.Version = env.BaseEnv().BuildNumber
+	}
+	if info.Version == "" {
+		info.Version = "1.0.0"
+	}
+	spec, err := json.MarshalIndent(buildOpenAPIDocument(info, routes.routes), "", "  ")
+	if err != nil {
+		panic(fmt.Errorf("REST OpenAPI: %w", err))
+	}
+
+	docPath := joinURLPath(s.opt.rootPath, s.opt.openAPIPath)
+	mux.Get(docPath+"/openapi.json", serveOpenAPIDocument(spec))
+	mux.Get(docPath, serveSwaggerUI(docPath+"/openapi.json", docPath+"/swagger-ui"))
+	mux.Get(docPath+"/swagger-ui/{asset}", serveSwaggerUIAsset)
+	if s.opt.openAPIRedocScriptURL != "" {
+		mux.Get(docPath+"/redoc", serveRedoc(docPath+"/openapi.json", s.opt.openAPIRedocScriptURL))
+	}
+	MiddlewareExcludeURLPath[docPath] = struct{}{}
+	logger.LogYellow(fmt.Sprintf("[REST] OpenAPI document\t\t: http://127.0.0.1:%d%s", s.opt.httpPort, docPath))
+}
+
 func (s *restServer) Serve() {
+	if s.opt.enableGRPCTranscoding {
+		transcoder, err := newGRPCTranscoder(s.service)
+		if err != nil {
+			panic(fmt.Errorf("REST gRPC transcoding: %w", err))
+		}
+		transcoder.mount(s.rootPath)
+	}
+
 	var err error
 	if s.listener == nil {
 		s.listener, err = net.Listen("tcp", s.httpEngine.Addr)
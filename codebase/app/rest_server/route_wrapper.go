@@ -14,11 +14,15 @@ var URLParam = func(r *http.Request, key string) string {
 }
 
 type routeWrapper struct {
-	router chi.Router
+	router    chi.Router
+	prefix    string
+	groupDocs []*RouteDocument
+	registry  *routeRegistry
 }
 
 func (r *routeWrapper) Use(middlewares ...func(http.Handler) http.Handler) {
 	r.router.Use(middlewares...)
+	r.groupDocs = appendRouteDocs(r.groupDocs, middlewares)
 }
 
 func (r *routeWrapper) Group(pattern string, middlewares ...func(http.Handler) http.Handler) interfaces.RESTRouter {
@@ -26,47 +30,93 @@ func (r *routeWrapper) Group(pattern string, middlewares ...func(http.Handler) h
 	if len(middlewares) > 0 {
 		route.Use(middlewares...)
 	}
-	return &routeWrapper{router: route}
+	return &routeWrapper{
+		router:    route,
+		prefix:    joinURLPath(r.prefix, transformURLParam(pattern)),
+		groupDocs: appendRouteDocs(r.groupDocs[:len(r.groupDocs):len(r.groupDocs)], middlewares),
+		registry:  r.registry,
+	}
 }
 
 func (r *routeWrapper) HandleFunc(pattern string, h http.HandlerFunc, middlewares ...func(http.Handler) http.Handler) {
-	r.router.HandleFunc(transformURLParam(pattern), WithChainingMiddlewares(h, middlewares...))
+	r.router.HandleFunc(transformURLParam(pattern), r.handler("", pattern, h, middlewares))
 }
 
 func (r *routeWrapper) CONNECT(pattern string, h http.HandlerFunc, middlewares ...func(http.Handler) http.Handler) {
-	r.router.Connect(transformURLParam(pattern), WithChainingMiddlewares(h, middlewares...))
+	r.router.Connect(transformURLParam(pattern), r.handler(http.MethodConnect, pattern, h, middlewares))
 }
 
 func (r *routeWrapper) DELETE(pattern string, h http.HandlerFunc, middlewares ...func(http.Handler) http.Handler) {
-	r.router.Delete(transformURLParam(pattern), WithChainingMiddlewares(h, middlewares...))
+	r.router.Delete(transformURLParam(pattern), r.handler(http.MethodDelete, pattern, h, middlewares))
 }
 
 func (r *routeWrapper) GET(pattern string, h http.HandlerFunc, middlewares ...func(http.Handler) http.Handler) {
-	r.router.Get(transformURLParam(pattern), WithChainingMiddlewares(h, middlewares...))
+	r.router.Get(transformURLParam(pattern), r.handler(http.MethodGet, pattern, h, middlewares))
 }
 
 func (r *routeWrapper) HEAD(pattern string, h http.HandlerFunc, middlewares ...func(http.Handler) http.Handler) {
-	r.router.Head(transformURLParam(pattern), WithChainingMiddlewares(h, middlewares...))
+	r.router.Head(transformURLParam(pattern), r.handler(http.MethodHead, pattern, h, middlewares))
 }
 
 func (r *routeWrapper) OPTIONS(pattern string, h http.HandlerFunc, middlewares ...func(http.Handler) http.Handler) {
-	r.router.Options(transformURLParam(pattern), WithChainingMiddlewares(h, middlewares...))
+	r.router.Options(transformURLParam(pattern), r.handler(http.MethodOptions, pattern, h, middlewares))
 }
 
 func (r *routeWrapper) PATCH(pattern string, h http.HandlerFunc, middlewares ...func(http.Handler) http.Handler) {
-	r.router.Patch(transformURLParam(pattern), WithChainingMiddlewares(h, middlewares...))
+	r.router.Patch(transformURLParam(pattern), r.handler(http.MethodPatch, pattern, h, middlewares))
 }
 
 func (r *routeWrapper) POST(pattern string, h http.HandlerFunc, middlewares ...func(http.Handler) http.Handler) {
-	r.router.Post(transformURLParam(pattern), WithChainingMiddlewares(h, middlewares...))
+	r.router.Post(transformURLParam(pattern), r.handler(http.MethodPost, pattern, h, middlewares))
 }
 
 func (r *routeWrapper) PUT(pattern string, h http.HandlerFunc, middlewares ...func(http.Handler) http.Handler) {
-	r.router.Put(transformURLParam(pattern), WithChainingMiddlewares(h, middlewares...))
+	r.router.Put(transformURLParam(pattern), r.handler(http.MethodPut, pattern, h, middlewares))
 }
 
 func (r *routeWrapper) TRACE(pattern string, h http.HandlerFunc, middlewares ...func(http.Handler) http.Handler) {
-	r.router.Trace(transformURLParam(pattern), WithChainingMiddlewares(h, middlewares...))
+	r.router.Trace(transformURLParam(pattern), r.handler(http.MethodTrace, pattern, h, middlewares))
+}
+
+// handler chaining middlewares and register route (with route document if any) for generate OpenAPI document
+func (r *routeWrapper) handler(method, pattern string, h http.HandlerFunc, middlewares []func(http.Handler) http.Handler) http.HandlerFunc {
+	route := &registeredRoute{
+		method:    method,
+		path:      joinURLPath(r.prefix, transformURLParam(pattern)),
+		groupDocs: r.groupDocs,
+	}
+
+	wrapped := h
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		next := middlewares[i](wrapped)
+		if docHandler, ok := next.(*routeDocHandler); ok {
+			route.doc = docHandler.doc
+			next = docHandler.next
+		}
+		wrapped = next.ServeHTTP
+	}
+	if r.registry != nil {
+		r.registry.add(route)
+	}
+	return wrapped
+}
+
+// appendRouteDocs append route document from RouteDoc middleware
+func appendRouteDocs(docs []*RouteDocument, middlewares []func(http.Handler) http.Handler) []*RouteDocument {
+	for _, mw := range middlewares {
+		if docHandler, ok := mw(http.NotFoundHandler()).(*routeDocHandler); ok {
+			docs = append(docs, docHandler.doc)
+		}
+	}
+	return docs
+}
+
+func joinURLPath(prefix, pattern string) string {
+	joined := strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(pattern, "/")
+	if joined != "/" {
+		joined = strings.TrimSuffix(joined, "/")
+	}
+	return joined
 }
 
 func transformURLParam(pattern string) string {
@@ -0,0 +1,361 @@
+package restserver
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golangid/candi/candihelper"
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/codebase/factory/dependency"
+	"github.com/golangid/candi/codebase/interfaces"
+	"github.com/golangid/candi/wrapper"
+)
+
+type (
+	// HandlerOption function type for setting typed handler options
+	HandlerOption func(*handlerOption)
+
+	handlerOption struct {
+		validator       interfaces.Validator
+		jsonSchema      string
+		successCode     int
+		successMessage  string
+		errorStatusCode func(error) int
+	}
+
+	// HTTPStatusCoder error with http status code, used by typed handler for response error code
+	HTTPStatusCoder interface {
+		HTTPStatusCode() int
+	}
+)
+
+// HandlerSetValidator set validator for validate request, default is validator from dependency
+func HandlerSetValidator(validator interfaces.Validator) HandlerOption {
+	return func(o *handlerOption) {
+		o.validator = validator
+	}
+}
+
+// HandlerSetJSONSchema validate request using json schema with reference ID, default is validate struct tag
+func HandlerSetJSONSchema(reference string) HandlerOption {
+	return func(o *handlerOption) {
+		o.jsonSchema = reference
+	}
+}
+
+// HandlerSetSuccessCode set http status code for success response, default is 200
+func HandlerSetSuccessCode(code int) HandlerOption {
+	return func(o *handlerOption) {
+		o.successCode = code
+	}
+}
+
+// HandlerSetSuccessMessage set message for success response, default is "Success"
+func HandlerSetSuccessMessage(message string) HandlerOption {
+	return func(o *handlerOption) {
+		o.successMessage = message
+	}
+}
+
+// HandlerSetErrorStatusCode set mapper from handler error to http status code,
+// default is code from HTTPStatusCoder error (example: *wrapper.HTTPError), otherwise 400
+func HandlerSetErrorStatusCode(mapper func(error) int) HandlerOption {
+	return func(o *handlerOption) {
+		o.errorStatusCode = mapper
+	}
+}
+
+// Handle typed handler adapter, bind path params (tag "path"), query (tag "query"), headers (tag "header") and body (JSON/XML/form)
+// into Req, validate Req and encode Resp or error in standard wrapper.HTTPResponse format (JSON or XML from Accept header).
+// If Resp is *wrapper.HTTPResponse, it's written as is, return *wrapper.HTTPError for set error status code. Example:
+//
+//	route.GET("/:id", restserver.Handle(h.getDetail))
+//
+//	func (h *RestHandler) getDetail(ctx context.Context, req struct{ ID string `path:"id"` }) (domain.Response, error)
+func Handle[Req, Resp any](fn func(ctx context.Context, req Req) (Resp, error), opts ...HandlerOption) http.HandlerFunc {
+	opt := handlerOption{
+		successCode:    http.StatusOK,
+		successMessage: "Success",
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		var request Req
+		if err := BindRequest(req, &request); err != nil {
+			writeResponse(w, req, wrapper.NewHTTPResponse(errorStatusCode(err), "Failed bind request", err))
+			return
+		}
+
+		validator := opt.validator
+		if validator == nil {
+			validator = dependency.GetValidator()
+		}
+		if validator != nil {
+			var err error
+			if opt.jsonSchema != "" {
+				err = validator.ValidateDocument(opt.jsonSchema, request)
+			} else if typ := reflect.TypeOf(request); typ != nil && candihelper.ReflectTypeUnwrapPtr(typ).Kind() == reflect.Struct {
+				err = validator.ValidateStruct(request)
+			}
+			if err != nil {
+				writeResponse(w, req, wrapper.NewHTTPResponse(errorStatusCode(err), "Failed validate request", err))
+				return
+			}
+		}
+
+		resp, err := fn(req.Context(), request)
+		if err != nil {
+			writeResponse(w, req, wrapper.NewHTTPResponse(opt.statusCode(err), err.Error()))
+			return
+		}
+
+		if httpResp, ok := any(resp).(*wrapper.HTTPResponse); ok && httpResp != nil {
+			writeResponse(w, req, httpResp)
+			return
+		}
+		writeResponse(w, req, wrapper.NewHTTPResponse(opt.successCode, opt.successMessage, resp))
+	}
+}
+
+func (o *handlerOption) statusCode(err error) int {
+	if o.errorStatusCode != nil {
+		return o.errorStatusCode(err)
+	}
+	return errorStatusCode(err)
+}
+
+// errorStatusCode get http status code from typed error, default is 400
+func errorStatusCode(err error) int {
+	var coder HTTPStatusCoder
+	if errors.As(err, &coder) {
+		return coder.HTTPStatusCode()
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// writeResponse encode response with content negotiation from Accept header, default is JSON
+func writeResponse(w http.ResponseWriter, req *http.Request, resp *wrapper.HTTPResponse) {
+	for _, accept := range strings.Split(req.Header.Get(candihelper.HeaderAccept), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(accept))
+		switch mediaType {
+		case candihelper.HeaderMIMEApplicationXML, "text/xml":
+			resp.XML(w)
+			return
+		case candihelper.HeaderMIMEApplicationJSON, "*/*":
+			resp.JSON(w)
+			return
+		}
+	}
+	resp.JSON(w)
+}
+
+// BindRequest bind http request to target (must be pointer): body decoded by content type (JSON, XML or form),
+// URL query for request without body (with "query" or "json" tag, see candihelper.ParseFromQueryParam)
+// or field with "query" tag for request with body, then field with "path" and "header" tag
+func BindRequest(req *http.Request, target any) error {
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Ptr || targetValue.IsNil() {
+		return fmt.Errorf("bind target must be non-nil pointer, got %T", target)
+	}
+	isStruct := targetValue.Elem().Kind() == reflect.Struct
+
+	hasBody := req.Body != nil && req.Body != http.NoBody && req.ContentLength != 0 &&
+		req.Method != http.MethodGet && req.Method != http.MethodHead
+	if hasBody {
+		if err := bindBody(req, target); err != nil {
+			return err
+		}
+	} else if isStruct {
+		if err := candihelper.ParseFromQueryParam(req.URL.Query(), target); err != nil {
+			return err
+		}
+	}
+	if !isStruct {
+		return nil
+	}
+
+	multiError := candishared.NewMultiError()
+	bindTaggedFields(targetValue.Elem(), func(field reflect.StructField) (string, []string, bool) {
+		if name := field.Tag.Get("path"); name != "" {
+			value := URLParam(req, name)
+			return name, []string{value}, value != ""
+		}
+		if name := field.Tag.Get("header"); name != "" {
+			values := req.Header.Values(name)
+			return name, values, len(values) > 0
+		}
+		if name, _, _ := strings.Cut(field.Tag.Get("query"), ","); hasBody && name != "" && name != "-" {
+			values, ok := req.URL.Query()[name]
+			return name, values, ok
+		}
+		return "", nil, false
+	}, multiError)
+	if multiError.HasError() {
+		return multiError
+	}
+	return nil
+}
+
+func bindBody(req *http.Request, target any) error {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get(candihelper.HeaderContentType))
+	switch mediaType {
+	case candihelper.HeaderMIMEApplicationXML, "text/xml":
+		if err := xml.NewDecoder(req.Body).Decode(target); err != nil && err != io.EOF {
+			return err
+		}
+
+	case candihelper.HeaderMIMEApplicationForm, candihelper.HeaderMIMEMultipartForm:
+		if mediaType == candihelper.HeaderMIMEMultipartForm {
+			if err := req.ParseMultipartForm(32 << 20); err != nil {
+				return err
+			}
+		} else if err := req.ParseForm(); err != nil {
+			return err
+		}
+		targetValue := reflect.ValueOf(target).Elem()
+		if targetValue.Kind() != reflect.Struct {
+			return fmt.Errorf("form body target must be struct, got %T", target)
+		}
+		multiError := candishared.NewMultiError()
+		bindTaggedFields(targetValue, func(field reflect.StructField) (string, []string, bool) {
+			name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+			if name == "" {
+				name, _, _ = strings.Cut(field.Tag.Get("json"), ",")
+			}
+			if name == "" || name == "-" {
+				return "", nil, false
+			}
+			values, ok := req.PostForm[name]
+			return name, values, ok
+		}, multiError)
+		if multiError.HasError() {
+			return multiError
+		}
+
+	default:
+		if err := json.NewDecoder(req.Body).Decode(target); err != nil && err != io.EOF {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindTaggedFields set struct field (include embedded struct) from values returned by lookup
+func bindTaggedFields(structValue reflect.Value, lookup func(reflect.StructField) (string, []string, bool), multiError candishared.MultiError) {
+	structType := structValue.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field, fieldValue := structType.Field(i), structValue.Field(i)
+		if field.Anonymous && candihelper.ReflectTypeUnwrapPtr(field.Type).Kind() == reflect.Struct {
+			if fieldValue.Kind() == reflect.Ptr {
+				if fieldValue.IsNil() {
+					if !fieldValue.CanSet() {
+						continue
+					}
+					fieldValue.Set(reflect.New(field.Type.Elem()))
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			bindTaggedFields(fieldValue, lookup, multiError)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name, values, ok := lookup(field)
+		if !ok {
+			continue
+		}
+		if err := setFieldValue(fieldValue, values); err != nil {
+			multiError.Append(name, err)
+		}
+	}
+}
+
+func setFieldValue(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Ptr {
+		value := reflect.New(field.Type().Elem())
+		if err := setFieldValue(value.Elem(), values); err != nil {
+			return err
+		}
+		field.Set(value)
+		return nil
+	}
+	if field.CanAddr() {
+		if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return unmarshaler.UnmarshalText([]byte(values[0]))
+		}
+	}
+
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setFieldValue(slice.Index(i), []string{value}); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	value := values[0]
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("cannot parse '%s' to type boolean", value)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if field.Type() == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("cannot parse '%s' to type duration", value)
+			}
+			field.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot parse '%s' to type number", value)
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot parse '%s' to type number", value)
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot parse '%s' to type float", value)
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
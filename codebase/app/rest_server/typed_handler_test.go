@@ -0,0 +1,180 @@
+package restserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golangid/candi/wrapper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type typedHandlerTestRequest struct {
+	ID      int           `path:"id"`
+	Token   string        `header:"X-Token"`
+	Page    int           `query:"page"`
+	Tags    []string      `query:"tags"`
+	Timeout time.Duration `query:"timeout"`
+	Name    string        `json:"name" form:"name"`
+	Age     *int          `json:"age" form:"age"`
+}
+
+type statusCoderError struct{ code int }
+
+func (e statusCoderError) Error() string       { return fmt.Sprintf("error %d", e.code) }
+func (e statusCoderError) HTTPStatusCode() int { return e.code }
+
+type fakeValidator struct{ err error }
+
+func (v fakeValidator) ValidateDocument(reference string, document any) error { return v.err }
+func (v fakeValidator) ValidateStruct(data any) error                         { return v.err }
+
+func bindTestRequest(t *testing.T, req *http.Request) (typedHandlerTestRequest, error) {
+	t.Helper()
+	var target typedHandlerTestRequest
+	var bindErr error
+	mux := chi.NewRouter()
+	mux.HandleFunc("/users/{id}", func(w http.ResponseWriter, req *http.Request) {
+		bindErr = BindRequest(req, &target)
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+	return target, bindErr
+}
+
+func TestBindRequest(t *testing.T) {
+	age := 20
+
+	t.Run("path, header and query without body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/10?page=2&tags=a,b", nil)
+		req.Header.Set("X-Token", "secret")
+		got, err := bindTestRequest(t, req)
+		require.NoError(t, err)
+		assert.Equal(t, typedHandlerTestRequest{ID: 10, Token: "secret", Page: 2, Tags: []string{"a", "b"}}, got)
+	})
+
+	t.Run("json body with query", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/users/10?page=3&tags=a&tags=b&timeout=1s", strings.NewReader(`{"name": "candi", "age": 20}`))
+		req.Header.Set("Content-Type", "application/json")
+		got, err := bindTestRequest(t, req)
+		require.NoError(t, err)
+		assert.Equal(t, typedHandlerTestRequest{ID: 10, Page: 3, Tags: []string{"a", "b"}, Timeout: time.Second, Name: "candi", Age: &age}, got)
+	})
+
+	t.Run("xml body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/users/10", strings.NewReader(`<req><Name>candi</Name></req>`))
+		req.Header.Set("Content-Type", "application/xml")
+		got, err := bindTestRequest(t, req)
+		require.NoError(t, err)
+		assert.Equal(t, "candi", got.Name)
+	})
+
+	t.Run("form body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/users/10", strings.NewReader(url.Values{"name": {"candi"}, "age": {"20"}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		got, err := bindTestRequest(t, req)
+		require.NoError(t, err)
+		assert.Equal(t, typedHandlerTestRequest{ID: 10, Name: "candi", Age: &age}, got)
+	})
+
+	t.Run("invalid path, header and query value", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/users/abc?page=x", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		_, err := bindTestRequest(t, req)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "id")
+		assert.Contains(t, err.Error(), "page")
+	})
+
+	t.Run("invalid json body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/users/10", strings.NewReader(`{"name": 1}`))
+		req.Header.Set("Content-Type", "application/json")
+		_, err := bindTestRequest(t, req)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+		var target typedHandlerTestRequest
+		assert.Error(t, BindRequest(httptest.NewRequest(http.MethodGet, "/", nil), target))
+	})
+}
+
+func TestHandle(t *testing.T) {
+	type response struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	serve := func(handler http.HandlerFunc, body string) (*httptest.ResponseRecorder, wrapper.HTTPResponse) {
+		mux := chi.NewRouter()
+		mux.Post("/users/{id}", handler)
+		req := httptest.NewRequest(http.MethodPost, "/users/10", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		var resp wrapper.HTTPResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec, resp
+	}
+	echo := func(ctx context.Context, req typedHandlerTestRequest) (response, error) {
+		return response{ID: req.ID, Name: req.Name}, nil
+	}
+	failWith := func(err error) func(context.Context, typedHandlerTestRequest) (response, error) {
+		return func(context.Context, typedHandlerTestRequest) (response, error) { return response{}, err }
+	}
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		body     string
+		wantCode int
+		wantMsg  string
+	}{
+		{name: "success", handler: Handle(echo, HandlerSetValidator(fakeValidator{}), HandlerSetSuccessCode(http.StatusCreated)),
+			body: `{"name": "candi"}`, wantCode: http.StatusCreated, wantMsg: "Success"},
+		{name: "invalid body", handler: Handle(echo, HandlerSetValidator(fakeValidator{})),
+			body: `{`, wantCode: http.StatusBadRequest, wantMsg: "Failed bind request"},
+		{name: "validation error", handler: Handle(echo, HandlerSetValidator(fakeValidator{err: errors.New("name is required")})),
+			body: `{}`, wantCode: http.StatusBadRequest, wantMsg: "Failed validate request"},
+		{name: "validation error with status code", handler: Handle(echo, HandlerSetValidator(fakeValidator{err: statusCoderError{http.StatusUnprocessableEntity}})),
+			body: `{}`, wantCode: http.StatusUnprocessableEntity, wantMsg: "Failed validate request"},
+		{name: "default error", handler: Handle(failWith(errors.New("failed")), HandlerSetValidator(fakeValidator{})),
+			body: `{}`, wantCode: http.StatusBadRequest, wantMsg: "failed"},
+		{name: "status coder error", handler: Handle(failWith(fmt.Errorf("wrap: %w", statusCoderError{http.StatusNotFound})), HandlerSetValidator(fakeValidator{})),
+			body: `{}`, wantCode: http.StatusNotFound, wantMsg: "wrap: error 404"},
+		{name: "http error", handler: Handle(failWith(wrapper.NewHTTPError(http.StatusConflict, "already exist")), HandlerSetValidator(fakeValidator{})),
+			body: `{}`, wantCode: http.StatusConflict, wantMsg: "already exist"},
+		{name: "error status code mapper", handler: Handle(failWith(errors.New("failed")), HandlerSetValidator(fakeValidator{}),
+			HandlerSetErrorStatusCode(func(error) int { return http.StatusInternalServerError })),
+			body: `{}`, wantCode: http.StatusInternalServerError, wantMsg: "failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, resp := serve(tt.handler, tt.body)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantCode, resp.Code)
+			assert.Equal(t, tt.wantMsg, resp.Message)
+			assert.Equal(t, tt.wantCode < http.StatusBadRequest, resp.Success)
+		})
+	}
+
+	rec, resp := serve(Handle(echo, HandlerSetValidator(fakeValidator{})), `{"name": "candi"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, map[string]any{"id": float64(10), "name": "candi"}, resp.Data)
+}
+
+func TestWriteResponseXML(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/html, application/xml;q=0.9")
+	rec := httptest.NewRecorder()
+	writeResponse(rec, req, wrapper.NewHTTPResponse(http.StatusOK, "Success"))
+	assert.Equal(t, "application/xml", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "<Message>Success</Message>")
+}
@@ -28,7 +28,9 @@ func initEngine(service factory.ServiceFactory, opts ...OptionFunc) *taskQueueWo
 	opt.autoRemoveClientInterval = 30 * time.Minute
 	opt.dashboardPort = 8080
 	opt.debugMode = true
-	if redisPool := service.GetDependency().GetRedisPool(); redisPool != nil {
+	if locker := service.GetDependency().GetLocker(); locker != nil {
+		opt.locker = locker
+	} else if redisPool := service.GetDependency().GetRedisPool(); redisPool != nil {
 		opt.locker = candiutils.NewRedisLocker(redisPool.WritePool())
 	} else {
 		opt.locker = &candiutils.NoopLocker{}
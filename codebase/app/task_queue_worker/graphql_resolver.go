@@ -160,6 +160,9 @@ func (r *rootResolver) AddJob(ctx context.Context, input struct{ Param AddJobInp
 		job.RetryInterval = interval
 	} else if input.Param.CronExpression != nil {
 		job.CronExpression = *input.Param.CronExpression
+		if input.Param.CronTimezone != nil {
+			job.CronTimezone = *input.Param.CronTimezone
+		}
 	}
 	return AddJob(ctx, &job)
 }
@@ -684,17 +687,28 @@ func (r *rootResolver) HoldJobTask(ctx context.Context, input struct {
 	return "Success", nil
 }
 
-func (r *rootResolver) ParseCronExpression(ctx context.Context, input struct{ Expr string }) (date []string, err error) {
-	schedule, err := cronexpr.Parse(input.Expr)
+func (r *rootResolver) ParseCronExpression(ctx context.Context, input struct {
+	Expr     string
+	Timezone *string
+}) (date []string, err error) {
+	expr := input.Expr
+	if input.Timezone != nil {
+		expr = cronexpr.WithTimezone(expr, *input.Timezone)
+	}
+	schedule, err := cronexpr.Parse(expr)
 	if err != nil {
 		return date, err
 	}
 
+	// show next schedules in timezone of schedule
+	loc := schedule.Location()
+	if loc == nil {
+		loc = time.Local
+	}
 	date = make([]string, 6)
-	now := time.Now()
+	now := time.Now().In(loc)
 	for i := range date {
-		nextInterval := schedule.NextInterval(now)
-		now = now.Add(nextInterval)
+		now = schedule.Next(now)
 		date[i] = now.Format(candihelper.DateFormatYYYYMMDDHHmmss)
 	}
 	return
@@ -14,7 +14,7 @@ type Query {
 	get_count_job(filter: GetAllJobInputResolver): Int!
 	get_all_configuration(): [ConfigurationResolver!]!
 	get_detail_configuration(key: String!): ConfigurationResolver!
-	parse_cron_expression(expr: String!): [String!]!
+	parse_cron_expression(expr: String!, timezone: String): [String!]!
 }
 
 type Mutation {
@@ -193,6 +193,7 @@ input AddJobInputResolver {
 	args: String!
 	retry_interval: String
 	cron_expression: String
+	cron_timezone: String
 }
 
 input GetAllJobInputResolver {
@@ -249,4 +250,5 @@ type AddJobInputResolver struct {
 	Args           string
 	RetryInterval  *string
 	CronExpression *string
+	CronTimezone   *string
 }
@@ -29,6 +29,7 @@ type (
 		RetryInterval  time.Duration `json:"retry_interval"`
 		StartAt        time.Time     `json:"start_at"`
 		CronExpression string        `json:"cron_expression"`
+		CronTimezone   string        `json:"cron_timezone"`
 
 		direct   bool              `json:"-"`
 		schedule cronexpr.Schedule `json:"-"`
@@ -38,6 +39,8 @@ type (
 // Validate method
 func (a *AddJobRequest) Validate() error {
 	if a.CronExpression != "" {
+		// timezone is saved in cron expression (CRON_TZ= prefix) of job interval
+		a.CronExpression = cronexpr.WithTimezone(a.CronExpression, a.CronTimezone)
 		schedule, err := cronexpr.Parse(a.CronExpression)
 		if err != nil {
 			return err
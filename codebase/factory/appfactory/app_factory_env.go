@@ -16,6 +16,12 @@ USE_REST=[bool]
 
 USE_GRPC=[bool]
 
+GRPC_ENABLE_HEALTH_SERVICE=[bool] # grpc.health.v1 with status from dependency health
+
+GRPC_ENABLE_REFLECTION=[bool]
+
+GRPC_ENABLE_CHANNELZ=[bool]
+
 USE_GRAPHQL=[bool]
 
 ## Worker
@@ -13,6 +13,9 @@ func SetupGRPCServer(service factory.ServiceFactory, opts ...grpcserver.OptionFu
 		grpcserver.SetSharedListener(service.GetConfig().SharedListener),
 		grpcserver.SetDebugMode(env.BaseEnv().DebugMode),
 		grpcserver.SetMaxLogSize(int(service.GetConfig().GetOption().MaxLogSize)),
+		grpcserver.SetEnableHealthService(env.BaseEnv().GRPCEnableHealthService),
+		grpcserver.SetEnableReflection(env.BaseEnv().GRPCEnableReflection),
+		grpcserver.SetEnableChannelz(env.BaseEnv().GRPCEnableChannelz),
 	}
 	grpcOption = append(grpcOption, opts...)
 	return grpcserver.NewServer(service, grpcOption...)
@@ -0,0 +1,57 @@
+package appfactory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golangid/candi/codebase/factory"
+	"github.com/golangid/candi/config"
+	"github.com/golangid/candi/config/env"
+	mockfactory "github.com/golangid/candi/mocks/codebase/factory"
+	mockdeps "github.com/golangid/candi/mocks/codebase/factory/dependency"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestSetupGRPCServer(t *testing.T) {
+	services := map[string]string{
+		"GRPC_ENABLE_HEALTH_SERVICE": "grpc.health.v1.Health",
+		"GRPC_ENABLE_REFLECTION":     "grpc.reflection.v1.ServerReflection",
+		"GRPC_ENABLE_CHANNELZ":       "grpc.channelz.v1.Channelz",
+	}
+	prevEnv := env.BaseEnv()
+	t.Cleanup(func() { env.SetEnv(prevEnv) })
+
+	for enabledEnv, enabledService := range services {
+		t.Run(enabledEnv, func(t *testing.T) {
+			// app flags is parsed from environment
+			for _, name := range []string{"USE_REST", "USE_GRAPHQL", "USE_KAFKA_CONSUMER", "USE_CRON_SCHEDULER", "USE_REDIS_SUBSCRIBER",
+				"USE_TASK_QUEUE_WORKER", "USE_POSTGRES_LISTENER_WORKER", "USE_RABBITMQ_CONSUMER"} {
+				t.Setenv(name, "false")
+			}
+			t.Setenv("USE_GRPC", "true")
+			t.Setenv("GRPC_PORT", "0")
+			for name := range services {
+				t.Setenv(name, "false")
+			}
+			t.Setenv(enabledEnv, "true")
+			env.Load("test")
+
+			service := &mockfactory.ServiceFactory{}
+			service.On("GetConfig").Return(&config.Config{})
+			service.On("GetDependency").Return(&mockdeps.Dependency{})
+			service.On("GetModules").Return([]factory.ModuleFactory{})
+			server := SetupGRPCServer(service)
+			defer server.Shutdown(context.Background())
+
+			registered := server.(interface{ ServerEngine() *grpc.Server }).ServerEngine().GetServiceInfo()
+			for _, name := range services {
+				if name == enabledService {
+					assert.Contains(t, registered, name)
+				} else {
+					assert.NotContains(t, registered, name)
+				}
+			}
+		})
+	}
+}
@@ -135,7 +135,7 @@ func SetValidator(validator interfaces.Validator) Option {
 	}
 }
 
-// SetLocker option func
+// SetLocker option func, used as default locker of cron, task queue and postgres worker (ex: candiutils.NewSQLLocker, candiutils.NewMongoLocker)
 func SetLocker(lock interfaces.Locker) Option {
 	return func(d *deps) {
 		d.locker = lock
@@ -0,0 +1,48 @@
+package dependency
+
+import (
+	"github.com/golangid/candi/codebase/factory/types"
+	"github.com/golangid/candi/codebase/interfaces"
+)
+
+type healthChecker interface {
+	Health() map[string]error
+}
+
+// GetHealth get health status from all registered dependency (sql, mongo, redis and brokers),
+// non primary database instance is prefixed with the registered key
+func GetHealth(d Dependency) map[string]error {
+	health := make(map[string]error)
+	appendHealth := func(prefix string, h healthChecker) {
+		if h == nil {
+			return
+		}
+		for name, err := range h.Health() {
+			if prefix != "" && prefix != primary {
+				name = prefix + "." + name
+			}
+			health[name] = err
+		}
+	}
+
+	if std, ok := d.(*deps); ok {
+		for key, db := range std.sqlDB {
+			appendHealth(key, db)
+		}
+		for key, db := range std.mongoDB {
+			appendHealth(key, db)
+		}
+		for key, db := range std.redisPool {
+			appendHealth(key, db)
+		}
+	} else {
+		appendHealth("", d.GetSQLDatabase())
+		appendHealth("", d.GetMongoDatabase())
+		appendHealth("", d.GetRedisPool())
+	}
+
+	d.FetchBroker(func(_ types.Worker, bk interfaces.Broker) {
+		appendHealth("", bk)
+	})
+	return health
+}
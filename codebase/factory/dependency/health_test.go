@@ -0,0 +1,55 @@
+package dependency
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golangid/candi/codebase/factory/types"
+	"github.com/golangid/candi/codebase/interfaces"
+	mockdeps "github.com/golangid/candi/mocks/codebase/factory/dependency"
+	mockinterfaces "github.com/golangid/candi/mocks/codebase/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetHealth(t *testing.T) {
+	errDown := errors.New("connection refused")
+	newSQL := func(health map[string]error) *mockinterfaces.SQLDatabase {
+		db := &mockinterfaces.SQLDatabase{}
+		db.On("Health").Return(health)
+		return db
+	}
+	redisPool := &mockinterfaces.RedisPool{}
+	redisPool.On("Health").Return(map[string]error{"redis_read": nil, "redis_write": errDown})
+	broker := &mockinterfaces.Broker{}
+	broker.On("Health").Return(map[string]error{"kafka": nil})
+
+	t.Run("standard dependency", func(t *testing.T) {
+		d := &deps{}
+		for _, opt := range []Option{
+			SetSQLDatabase(newSQL(map[string]error{"sql_read": nil, "sql_write": nil})),
+			AddSQLDatabase("replica", newSQL(map[string]error{"sql_read": errDown})),
+			SetRedisPool(redisPool),
+			SetBrokers(map[types.Worker]interfaces.Broker{types.Kafka: broker}),
+		} {
+			opt(d)
+		}
+
+		assert.Equal(t, map[string]error{
+			"sql_read": nil, "sql_write": nil, "replica.sql_read": errDown,
+			"redis_read": nil, "redis_write": errDown, "kafka": nil,
+		}, GetHealth(d))
+	})
+
+	t.Run("custom dependency", func(t *testing.T) {
+		d := &mockdeps.Dependency{}
+		d.On("GetSQLDatabase").Return(newSQL(map[string]error{"sql_read": errDown}))
+		d.On("GetMongoDatabase").Return(nil)
+		d.On("GetRedisPool").Return(nil)
+		d.On("FetchBroker", mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(0).(func(types.Worker, interfaces.Broker))(types.Kafka, broker)
+		})
+
+		assert.Equal(t, map[string]error{"sql_read": errDown, "kafka": nil}, GetHealth(d))
+	})
+}
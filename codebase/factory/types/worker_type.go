@@ -1,20 +1,37 @@
 package types
 
 import (
+	"context"
+	"time"
+
 	"github.com/golangid/candi/candishared"
 )
 
+const (
+	// DefaultBatchMaxSize default max messages in one batch
+	DefaultBatchMaxSize = 100
+	// DefaultBatchMaxWait default max time for waiting batch to be full
+	DefaultBatchMaxWait = time.Second
+)
+
 type (
 	// WorkerHandlerFunc types
 	WorkerHandlerFunc func(ctx *candishared.EventContext) error
 
+	// WorkerBatchHandlerFunc types, report failure of single message with EventContext.SetError,
+	// returning error will mark all messages in batch as failed
+	WorkerBatchHandlerFunc func(ctx context.Context, events []*candishared.EventContext) error
+
 	// WorkerHandler types
 	WorkerHandler struct {
-		Pattern      string
-		HandlerFuncs []WorkerHandlerFunc
-		DisableTrace bool
-		AutoACK      bool
-		Configs      map[string]any
+		Pattern          string
+		HandlerFuncs     []WorkerHandlerFunc
+		BatchHandlerFunc WorkerBatchHandlerFunc
+		BatchMaxSize     int
+		BatchMaxWait     time.Duration
+		DisableTrace     bool
+		AutoACK          bool
+		Configs          map[string]any
 	}
 
 	// WorkerHandlerOptionFunc types
@@ -48,6 +65,25 @@ func (m *WorkerHandlerGroup) Add(patternRoute string, mainHandlerFunc WorkerHand
 	m.Handlers = append(m.Handlers, h)
 }
 
+// AddBatch method from WorkerHandlerGroup, register batch handler for consume multiple messages at once.
+// Batch will be dispatched when reach max size or max wait time (whichever comes first)
+func (m *WorkerHandlerGroup) AddBatch(patternRoute string, batchHandlerFunc WorkerBatchHandlerFunc, opts ...WorkerHandlerOptionFunc) {
+	h := WorkerHandler{
+		Pattern: patternRoute, BatchHandlerFunc: batchHandlerFunc, AutoACK: true,
+		BatchMaxSize: DefaultBatchMaxSize, BatchMaxWait: DefaultBatchMaxWait,
+	}
+
+	for _, opt := range opts {
+		opt(&h)
+	}
+	m.Handlers = append(m.Handlers, h)
+}
+
+// IsBatch check handler is batch handler
+func (w *WorkerHandler) IsBatch() bool {
+	return w.BatchHandlerFunc != nil
+}
+
 // WorkerHandlerOptionDisableTrace set disable trace
 func WorkerHandlerOptionDisableTrace() WorkerHandlerOptionFunc {
 	return func(wh *WorkerHandler) {
@@ -78,3 +114,21 @@ func WorkerHandlerOptionAddHandlers(handlerFuncs ...WorkerHandlerFunc) WorkerHan
 		wh.HandlerFuncs = append(wh.HandlerFuncs, handlerFuncs...)
 	}
 }
+
+// WorkerHandlerOptionBatchMaxSize set max messages in one batch, only for batch handler
+func WorkerHandlerOptionBatchMaxSize(size int) WorkerHandlerOptionFunc {
+	return func(wh *WorkerHandler) {
+		if size > 0 {
+			wh.BatchMaxSize = size
+		}
+	}
+}
+
+// WorkerHandlerOptionBatchMaxWait set max wait time before dispatch batch, only for batch handler
+func WorkerHandlerOptionBatchMaxWait(wait time.Duration) WorkerHandlerOptionFunc {
+	return func(wh *WorkerHandler) {
+		if wait > 0 {
+			wh.BatchMaxWait = wait
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package interfaces
 
 import (
+	"context"
+
 	"github.com/golangid/candi/codebase/factory/types"
 	"google.golang.org/grpc"
 )
@@ -23,6 +25,21 @@ type GraphQLHandler interface {
 	Schema() string
 }
 
+// GraphQLEntityHandler optional interface for GraphQLHandler module to register apollo federation entity resolvers
+type GraphQLEntityHandler interface {
+	EntityResolvers() []GraphQLEntityResolver
+}
+
+// GraphQLEntityResolver apollo federation entity resolver, construct with graphqlserver.NewEntityResolver
+type GraphQLEntityResolver interface {
+	// EntityTypeName graphql type name with @key directive
+	EntityTypeName() string
+	// EntityQueryResolver root query resolver for resolve entity fields
+	EntityQueryResolver() any
+	// ResolveEntities resolve all representations of entity type in one batch
+	ResolveEntities(ctx context.Context, representations []map[string]any) (any, error)
+}
+
 // WorkerHandler delivery factory for all worker handler
 type WorkerHandler interface {
 	MountHandlers(group *types.WorkerHandlerGroup)
@@ -1,6 +1,11 @@
 package interfaces
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"github.com/golangid/candi/candishared"
+)
 
 type (
 	// Locker abstraction, lock concurrent process
@@ -11,8 +16,32 @@ type (
 		Unlock(key string)
 		Reset(key string)
 		Lock(key string, timeout time.Duration) (unlockFunc func(), err error)
+		// AcquireLock acquire lock with owner token, return candishared.ErrLockNotAcquired if lock is held by another owner
+		AcquireLock(ctx context.Context, key string, opts ...candishared.LockOption) (LockLease, error)
 		GetPrefixLocker() string
 		GetTTLLocker() time.Duration
 		Closer
 	}
+
+	// LockOwnerGetter optional interface of Locker, get owner token of held lock
+	LockOwnerGetter interface {
+		// GetLockOwner return owner token of lock (see candishared.LockSetToken), empty if lock is not held by lock lease
+		GetLockOwner(ctx context.Context, key string) (string, error)
+	}
+
+	// LockLease acquired lock, only the owner can refresh and release the lock
+	LockLease interface {
+		Key() string
+		// Token owner token of the lock
+		Token() string
+		// FencingToken monotonically increasing number for each acquired lock with same key,
+		// pass it to storage for reject write from stale owner. Zero if not supported by the locker (ex: Redlock mode)
+		FencingToken() int64
+		// Refresh extend lease of lock with TTL, return candishared.ErrLockNotOwned if lock already expired
+		Refresh(ctx context.Context) error
+		// Release release lock if still owned, return candishared.ErrLockNotOwned if lock already expired
+		Release(ctx context.Context) error
+		// Lost closed when lock is released or auto renew failed (lock is lost)
+		Lost() <-chan struct{}
+	}
 )
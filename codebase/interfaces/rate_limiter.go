@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/golangid/candi/candishared"
+)
+
+// RateLimiter abstraction, limit request rate by key
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) (candishared.RateLimitResult, error)
+}
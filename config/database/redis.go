@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"io"
 	"log"
 	"time"
 
@@ -71,6 +72,9 @@ func (m *RedisInstance) Cache() interfaces.Cache {
 func (m *RedisInstance) Disconnect(ctx context.Context) (err error) {
 	defer logger.LogWithDefer("\x1b[33;5mredis\x1b[0m: disconnect...")()
 
+	if closer, ok := m.ICache.(io.Closer); ok {
+		closer.Close()
+	}
 	if m.DBRead != nil {
 		if err := m.DBRead.Close(); err != nil {
 			return err
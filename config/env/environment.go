@@ -51,6 +51,13 @@ type Env struct {
 	HTTPRootPath                string
 	GraphQLDisableIntrospection bool
 
+	// GRPCEnableHealthService env for register grpc.health.v1 service
+	GRPCEnableHealthService bool
+	// GRPCEnableReflection env for register grpc server reflection
+	GRPCEnableReflection bool
+	// GRPCEnableChannelz env for register grpc channelz service
+	GRPCEnableChannelz bool
+
 	// HTTPPort config
 	HTTPPort uint16
 	// GRPCPort Config
@@ -74,6 +81,8 @@ type Env struct {
 		ClientVersion string
 		ClientID      string
 		ConsumerGroup string
+		// SchemaRegistryURL Confluent-compatible schema registry url
+		SchemaRegistryURL string
 	}
 	RabbitMQ struct {
 		Broker        string
@@ -166,6 +175,9 @@ func Load(serviceName string) {
 	}
 
 	env.GraphQLDisableIntrospection = parseBool("GRAPHQL_DISABLE_INTROSPECTION")
+	env.GRPCEnableHealthService = parseBool("GRPC_ENABLE_HEALTH_SERVICE")
+	env.GRPCEnableReflection = parseBool("GRPC_ENABLE_REFLECTION")
+	env.GRPCEnableChannelz = parseBool("GRPC_ENABLE_CHANNELZ")
 	env.HTTPRootPath = os.Getenv("HTTP_ROOT_PATH")
 	env.BasicAuthUsername = os.Getenv("BASIC_AUTH_USERNAME")
 	env.BasicAuthPassword = os.Getenv("BASIC_AUTH_PASS")
@@ -276,6 +288,7 @@ func parseBrokerEnv(mErrs candishared.MultiError) {
 	env.Kafka.Brokers = strings.Split(kafkaBrokerEnv, ",") // optional
 	env.Kafka.ClientID = os.Getenv("KAFKA_CLIENT_ID")      // optional
 	env.Kafka.ClientVersion = os.Getenv("KAFKA_CLIENT_VERSION")
+	env.Kafka.SchemaRegistryURL = os.Getenv("KAFKA_SCHEMA_REGISTRY_URL") // optional
 	if env.UseKafkaConsumer {
 		if kafkaBrokerEnv == "" {
 			mErrs.Append("KAFKA_BROKERS", errors.New("kafka consumer is active, missing KAFKA_BROKERS environment"))
@@ -54,7 +54,7 @@ func (m *Middleware) HTTPPermissionACL(permissionCode string) func(http.Handler)
 					trace.SetError(err)
 					return err
 				}
-				ctx = candishared.SetToContext(ctx, candishared.ContextKeyTokenClaim, tokenClaim)
+				ctx = candishared.SetTokenClaimToContext(ctx, tokenClaim)
 				return nil
 			}(permissionCode); err != nil {
 				wrapper.NewHTTPResponse(http.StatusForbidden, err.Error()).JSON(w)
@@ -94,7 +94,7 @@ func (m *Middleware) GraphQLPermissionACL(ctx context.Context, directive *gqltyp
 				"success": false,
 			})
 	}
-	return candishared.SetToContext(ctx, candishared.ContextKeyTokenClaim, tokenClaim), nil
+	return candishared.SetTokenClaimToContext(ctx, tokenClaim), nil
 }
 
 // GRPCPermissionACL grpc interceptor for check acl permission
@@ -108,6 +108,6 @@ func (m *Middleware) GRPCPermissionACL(permissionCode string) types.MiddlewareFu
 		if err != nil {
 			return ctx, status.Errorf(codes.PermissionDenied, "Permission denied: %v", err.Error())
 		}
-		return candishared.SetToContext(ctx, candishared.ContextKeyTokenClaim, tokenClaim), nil
+		return candishared.SetTokenClaimToContext(ctx, tokenClaim), nil
 	}
 }
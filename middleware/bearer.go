@@ -54,7 +54,7 @@ func (m *Middleware) HTTPBearerAuth(next http.Handler) http.Handler {
 			wrapper.NewHTTPResponse(http.StatusUnauthorized, err.Error()).JSON(w)
 			return
 		}
-		next.ServeHTTP(w, req.WithContext(candishared.SetToContext(req.Context(), candishared.ContextKeyTokenClaim, tokenClaim)))
+		next.ServeHTTP(w, req.WithContext(candishared.SetTokenClaimToContext(req.Context(), tokenClaim)))
 	})
 }
 
@@ -77,5 +77,5 @@ func (m *Middleware) GRPCBearerAuth(ctx context.Context) (context.Context, error
 	}
 
 	trace.Log("token_claim", tokenClaim)
-	return candishared.SetToContext(ctx, candishared.ContextKeyTokenClaim, tokenClaim), nil
+	return candishared.SetTokenClaimToContext(ctx, tokenClaim), nil
 }
@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/golangid/candi/candihelper"
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/candiutils"
+	"github.com/golangid/candi/codebase/interfaces"
+	"github.com/golangid/candi/tracer"
+	"github.com/golangid/candi/wrapper"
+)
+
+const (
+	// HeaderIdempotencyKey header const
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed header const, set to "true" if response is replayed from stored response
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	// DefaultIdempotencyTTL const
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// DefaultIdempotencyLockTTL const
+	DefaultIdempotencyLockTTL = 1 * time.Minute
+
+	maxIdempotencyKeyLength = 255
+)
+
+type (
+	// IdempotencyOption function type for setting idempotency middleware options
+	IdempotencyOption func(*idempotencyOption)
+
+	idempotencyOption struct {
+		header   string
+		ttl      time.Duration
+		lockTTL  time.Duration
+		required bool
+		methods  map[string]struct{}
+		userFunc func(req *http.Request) string
+	}
+
+	idempotencyRecord struct {
+		RequestHash string      `json:"request_hash"`
+		StatusCode  int         `json:"status_code"`
+		Header      http.Header `json:"header,omitempty"`
+		Body        []byte      `json:"body,omitempty"`
+	}
+)
+
+// IdempotencySetHeader set header of idempotency key, default is Idempotency-Key
+func IdempotencySetHeader(header string) IdempotencyOption {
+	return func(o *idempotencyOption) {
+		o.header = header
+	}
+}
+
+// IdempotencySetTTL set how long first response is stored, default is 24 hours
+func IdempotencySetTTL(ttl time.Duration) IdempotencyOption {
+	return func(o *idempotencyOption) {
+		o.ttl = ttl
+	}
+}
+
+// IdempotencySetLockTTL set max duration of in-flight request lock, default is 1 minute
+func IdempotencySetLockTTL(ttl time.Duration) IdempotencyOption {
+	return func(o *idempotencyOption) {
+		o.lockTTL = ttl
+	}
+}
+
+// IdempotencySetRequired response 400 if idempotency key is empty, default is false (request is processed without idempotency)
+func IdempotencySetRequired(required bool) IdempotencyOption {
+	return func(o *idempotencyOption) {
+		o.required = required
+	}
+}
+
+// IdempotencySetMethods set http methods checked by idempotency middleware, default is POST and PATCH
+func IdempotencySetMethods(methods ...string) IdempotencyOption {
+	return func(o *idempotencyOption) {
+		o.methods = make(map[string]struct{}, len(methods))
+		for _, method := range methods {
+			o.methods[method] = struct{}{}
+		}
+	}
+}
+
+// IdempotencySetUserFunc set scope of idempotency key, default is subject in token claim (HTTPBearerAuth middleware must executed before)
+func IdempotencySetUserFunc(userFunc func(req *http.Request) string) IdempotencyOption {
+	return func(o *idempotencyOption) {
+		o.userFunc = userFunc
+	}
+}
+
+// HTTPIdempotency middleware, store first response (status, headers and body) per idempotency key and user in cache,
+// replay stored response for retried request with same key, response 409 if request with same key is still in progress
+// and 422 if same key is reused with different request (method, path or body). Response with 5xx status is not stored
+func HTTPIdempotency(cache interfaces.Cache, locker interfaces.Locker, opts ...IdempotencyOption) func(http.Handler) http.Handler {
+	opt := idempotencyOption{
+		header:  HeaderIdempotencyKey,
+		ttl:     DefaultIdempotencyTTL,
+		lockTTL: DefaultIdempotencyLockTTL,
+		methods: map[string]struct{}{http.MethodPost: {}, http.MethodPatch: {}},
+		userFunc: func(req *http.Request) string {
+			if tokenClaim, ok := candishared.GetValueFromContext(req.Context(), candishared.ContextKeyTokenClaim).(*candishared.TokenClaim); ok {
+				return tokenClaim.Subject
+			}
+			return ""
+		},
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+	if locker == nil {
+		locker = &candiutils.NoopLocker{}
+	}
+
+	bPool := candiutils.NewSyncPool(func() *bytes.Buffer {
+		buff := bytes.NewBuffer(make([]byte, 256))
+		buff.Reset()
+		return buff
+	}, func(b *bytes.Buffer) {
+		b.Reset()
+	})
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if _, ok := opt.methods[req.Method]; !ok {
+				next.ServeHTTP(res, req)
+				return
+			}
+
+			idempotencyKey := req.Header.Get(opt.header)
+			if idempotencyKey == "" {
+				if opt.required {
+					wrapper.NewHTTPResponse(http.StatusBadRequest, "Missing "+opt.header+" header").JSON(res)
+					return
+				}
+				next.ServeHTTP(res, req)
+				return
+			}
+			if len(idempotencyKey) > maxIdempotencyKeyLength {
+				wrapper.NewHTTPResponse(http.StatusBadRequest, "Invalid "+opt.header+" header, max length is 255").JSON(res)
+				return
+			}
+
+			trace, ctx := tracer.StartTraceWithContext(req.Context(), "Middleware:HTTPIdempotency")
+			defer trace.Finish()
+
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				trace.SetError(err)
+				wrapper.NewHTTPResponse(http.StatusBadRequest, "Failed read request body").JSON(res)
+				return
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			hash := sha256.New()
+			hash.Write([]byte(req.Method + " " + req.URL.Path + "\n"))
+			hash.Write(body)
+			requestHash := hex.EncodeToString(hash.Sum(nil))
+
+			cacheKey := "idempotency:" + opt.userFunc(req) + ":" + idempotencyKey
+			trace.SetTag("key", cacheKey)
+
+			if replayIdempotencyRecord(ctx, cache, cacheKey, requestHash, res) {
+				trace.SetTag("replayed", true)
+				return
+			}
+
+			lease, err := locker.AcquireLock(ctx, cacheKey, candishared.LockSetTTL(opt.lockTTL))
+			switch {
+			case errors.Is(err, candishared.ErrLockNotAcquired):
+				trace.SetTag("in_progress", true)
+				wrapper.NewHTTPResponse(http.StatusConflict, "Request with same idempotency key is still in progress").JSON(res)
+				return
+			case err != nil:
+				trace.SetError(err)
+				wrapper.NewHTTPResponse(http.StatusServiceUnavailable, "Failed acquire idempotency lock").JSON(res)
+				return
+			}
+			defer lease.Release(context.WithoutCancel(ctx))
+
+			// recheck stored response, first request may be finished before lock acquired
+			if replayIdempotencyRecord(ctx, cache, cacheKey, requestHash, res) {
+				trace.SetTag("replayed", true)
+				return
+			}
+
+			resBody := bPool.Get()
+			defer bPool.Put(resBody)
+			respWriter := wrapper.NewWrapHTTPResponseWriter(resBody, res)
+
+			next.ServeHTTP(respWriter, req)
+
+			if respWriter.StatusCode() >= http.StatusInternalServerError {
+				return
+			}
+			if err := cache.Set(ctx, cacheKey, candihelper.ToBytes(idempotencyRecord{
+				RequestHash: requestHash,
+				StatusCode:  respWriter.StatusCode(),
+				Header:      res.Header(),
+				Body:        resBody.Bytes(),
+			}), opt.ttl); err != nil {
+				trace.SetError(err)
+			}
+		})
+	}
+}
+
+// replayIdempotencyRecord write stored response if exist, header already set in current response (ex: request id) is not overwritten
+func replayIdempotencyRecord(ctx context.Context, cache interfaces.Cache, cacheKey, requestHash string, res http.ResponseWriter) (replayed bool) {
+	cacheVal, err := cache.Get(ctx, cacheKey)
+	if err != nil || len(cacheVal) == 0 {
+		return false
+	}
+	var record idempotencyRecord
+	if err := json.Unmarshal(cacheVal, &record); err != nil {
+		cache.Delete(ctx, cacheKey)
+		return false
+	}
+
+	if record.RequestHash != requestHash {
+		wrapper.NewHTTPResponse(http.StatusUnprocessableEntity, "Idempotency key is already used for different request").JSON(res)
+		return true
+	}
+
+	header := res.Header()
+	for key, values := range record.Header {
+		if _, ok := header[key]; !ok {
+			header[key] = values
+		}
+	}
+	header.Set(HeaderIdempotentReplayed, "true")
+	res.WriteHeader(record.StatusCode)
+	res.Write(record.Body)
+	return true
+}
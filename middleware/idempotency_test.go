@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/candiutils"
+	mocks "github.com/golangid/candi/mocks/codebase/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type memoryCache struct {
+	sync.Map
+}
+
+func (m *memoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if val, ok := m.Load(key); ok {
+		return val.([]byte), nil
+	}
+	return nil, errors.New("not found")
+}
+func (m *memoryCache) GetKeys(ctx context.Context, pattern string) ([]string, error) { return nil, nil }
+func (m *memoryCache) GetTTL(ctx context.Context, key string) (time.Duration, error) { return 0, nil }
+func (m *memoryCache) Set(ctx context.Context, key string, value any, expire time.Duration) error {
+	m.Store(key, value)
+	return nil
+}
+func (m *memoryCache) Exists(ctx context.Context, key string) (bool, error) {
+	_, ok := m.Load(key)
+	return ok, nil
+}
+func (m *memoryCache) Delete(ctx context.Context, key string) error {
+	m.Map.Delete(key)
+	return nil
+}
+func (m *memoryCache) DoCommand(ctx context.Context, isWrite bool, command string, args ...any) (any, error) {
+	return nil, nil
+}
+
+func TestHTTPIdempotency(t *testing.T) {
+	var counter int
+	handler := HTTPIdempotency(&memoryCache{}, &candiutils.NoopLocker{})(
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			counter++
+			w.Header().Set("X-Order-ID", "order-1")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"order-1"}`))
+		}),
+	)
+
+	request := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		req.Header.Set(HeaderIdempotencyKey, key)
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := request("key-1", `{"item":"a"}`)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Empty(t, resp.Header().Get(HeaderIdempotentReplayed))
+
+	resp = request("key-1", `{"item":"a"}`)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, "true", resp.Header().Get(HeaderIdempotentReplayed))
+	assert.Equal(t, "order-1", resp.Header().Get("X-Order-ID"))
+	assert.Equal(t, `{"id":"order-1"}`, resp.Body.String())
+	assert.Equal(t, 1, counter)
+
+	resp = request("key-1", `{"item":"b"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Equal(t, 1, counter)
+
+	locker := &mocks.Locker{}
+	locker.On("AcquireLock", mock.Anything, mock.Anything, mock.Anything).Return(nil, candishared.ErrLockNotAcquired)
+	handler = HTTPIdempotency(&memoryCache{}, locker)(handler)
+	resp = request("key-2", `{"item":"a"}`)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	assert.Equal(t, 1, counter)
+}
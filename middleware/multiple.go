@@ -40,7 +40,7 @@ func (m *Middleware) HTTPMultipleAuth(next http.Handler) http.Handler {
 			return
 		}
 		if claimData != nil {
-			ctx = candishared.SetToContext(ctx, candishared.ContextKeyTokenClaim, claimData)
+			ctx = candishared.SetTokenClaimToContext(ctx, claimData)
 		}
 
 		next.ServeHTTP(w, req.WithContext(ctx))
@@ -69,7 +69,7 @@ func (m *Middleware) GRPCMultipleAuth(ctx context.Context) (context.Context, err
 	}
 
 	if claimData != nil {
-		ctx = candishared.SetToContext(ctx, candishared.ContextKeyTokenClaim, claimData)
+		ctx = candishared.SetTokenClaimToContext(ctx, claimData)
 		trace.Log("token_claim", claimData)
 	}
 	return ctx, nil
@@ -123,7 +123,7 @@ func (m *Middleware) GraphQLAuth(ctx context.Context, directive *gqltypes.Direct
 	}
 
 	if claimData != nil {
-		ctx = candishared.SetToContext(ctx, candishared.ContextKeyTokenClaim, claimData)
+		ctx = candishared.SetTokenClaimToContext(ctx, claimData)
 		trace.Log("token_claim", claimData)
 	}
 
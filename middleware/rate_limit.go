@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golangid/candi/candihelper"
+	"github.com/golangid/candi/candishared"
+	"github.com/golangid/candi/codebase/interfaces"
+	"github.com/golangid/candi/logger"
+	"github.com/golangid/candi/tracer"
+	"github.com/golangid/candi/wrapper"
+)
+
+const (
+	// HeaderRateLimitLimit header const
+	HeaderRateLimitLimit = "RateLimit-Limit"
+	// HeaderRateLimitRemaining header const
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	// HeaderRateLimitReset header const, in seconds
+	HeaderRateLimitReset = "RateLimit-Reset"
+	// HeaderRetryAfter header const, in seconds
+	HeaderRetryAfter = "Retry-After"
+)
+
+type (
+	// RateLimitKeyFunc extract rate limit key from request
+	RateLimitKeyFunc func(req *http.Request) string
+
+	// RateLimitOption function type for setting rate limit middleware options
+	RateLimitOption func(*rateLimitOption)
+
+	rateLimitOption struct {
+		keyFuncs []RateLimitKeyFunc
+		skipper  func(req *http.Request) bool
+		failOpen bool
+	}
+)
+
+// RateLimitSetKeyFunc set key of rate limit, multiple key func is combined (ex: by user and route), default is by IP
+func RateLimitSetKeyFunc(keyFuncs ...RateLimitKeyFunc) RateLimitOption {
+	return func(o *rateLimitOption) {
+		o.keyFuncs = keyFuncs
+	}
+}
+
+// RateLimitSetSkipper skip rate limit for request if skipper return true
+func RateLimitSetSkipper(skipper func(req *http.Request) bool) RateLimitOption {
+	return func(o *rateLimitOption) {
+		o.skipper = skipper
+	}
+}
+
+// RateLimitSetFailOpen allow request if rate limiter backend (ex: redis) is error, default is true
+func RateLimitSetFailOpen(failOpen bool) RateLimitOption {
+	return func(o *rateLimitOption) {
+		o.failOpen = failOpen
+	}
+}
+
+// RateLimitKeyByIP rate limit key by client IP from remote address, forwarded header is not trusted
+// (use RateLimitKeyByForwardedIP if service is behind reverse proxy)
+func RateLimitKeyByIP(req *http.Request) string {
+	return "ip:" + remoteIP(req)
+}
+
+// RateLimitKeyByForwardedIP rate limit key by client IP from X-Forwarded-For or X-Real-IP header, header is only read if
+// request come from trusted proxies (IP or CIDR, ex: "10.0.0.0/8"). Client IP is the rightmost address in X-Forwarded-For
+// which is not trusted proxy, fallback to remote address. Panic if trusted proxy is invalid
+func RateLimitKeyByForwardedIP(trustedProxies ...string) RateLimitKeyFunc {
+	prefixes := make([]netip.Prefix, 0, len(trustedProxies))
+	for _, proxy := range trustedProxies {
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				panic(fmt.Sprintf("rate limit: invalid trusted proxy %q", proxy))
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	isTrusted := func(ip string) bool {
+		addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+		if err != nil {
+			return false
+		}
+		addr = addr.Unmap()
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(req *http.Request) string {
+		ip := remoteIP(req)
+		if !isTrusted(ip) {
+			return "ip:" + ip
+		}
+		if forwarded := req.Header.Values(candihelper.HeaderXForwardedFor); len(forwarded) > 0 {
+			ips := strings.Split(strings.Join(forwarded, ","), ",")
+			for i := len(ips) - 1; i >= 0; i-- {
+				if forwardedIP := strings.TrimSpace(ips[i]); forwardedIP != "" && !isTrusted(forwardedIP) {
+					return "ip:" + forwardedIP
+				}
+			}
+		}
+		if realIP := strings.TrimSpace(req.Header.Get(candihelper.HeaderXRealIP)); realIP != "" {
+			return "ip:" + realIP
+		}
+		return "ip:" + ip
+	}
+}
+
+func remoteIP(req *http.Request) string {
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		ip = req.RemoteAddr
+	}
+	return ip
+}
+
+// RateLimitKeyByUserID rate limit key by user ID (subject) in token claim, HTTPBearerAuth middleware must executed before.
+// Fallback to IP if token claim not found
+func RateLimitKeyByUserID(req *http.Request) string {
+	if tokenClaim, ok := candishared.GetValueFromContext(req.Context(), candishared.ContextKeyTokenClaim).(*candishared.TokenClaim); ok && tokenClaim.Subject != "" {
+		return "user:" + tokenClaim.Subject
+	}
+	return RateLimitKeyByIP(req)
+}
+
+// RateLimitKeyByAPIKey rate limit key by API key in header (hashed with sha256, raw API key is not stored
+// in rate limiter backend and trace), fallback to IP if header is empty
+func RateLimitKeyByAPIKey(header string) RateLimitKeyFunc {
+	return func(req *http.Request) string {
+		if apiKey := req.Header.Get(header); apiKey != "" {
+			hash := sha256.Sum256([]byte(apiKey))
+			return "apikey:" + hex.EncodeToString(hash[:16])
+		}
+		return RateLimitKeyByIP(req)
+	}
+}
+
+// RateLimitKeyByRoute rate limit key by route pattern (ex: "GET /v1/orders/{id}"), fallback to URL path
+func RateLimitKeyByRoute(req *http.Request) string {
+	route := req.URL.Path
+	if rctx := chi.RouteContext(req.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		route = rctx.RoutePattern()
+	}
+	return "route:" + req.Method + " " + route
+}
+
+// HTTPRateLimit middleware, limit request rate with limiter (see candiutils.NewLocalRateLimiter or candiutils.NewCacheRateLimiter),
+// set RateLimit-* header and response 429 if limit exceeded. Can be used as root middleware or per route middleware
+func HTTPRateLimit(limiter interfaces.RateLimiter, opts ...RateLimitOption) func(http.Handler) http.Handler {
+	opt := rateLimitOption{
+		keyFuncs: []RateLimitKeyFunc{RateLimitKeyByIP},
+		failOpen: true,
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if opt.skipper != nil && opt.skipper(req) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			keys := make([]string, len(opt.keyFuncs))
+			for i, keyFunc := range opt.keyFuncs {
+				keys[i] = keyFunc(req)
+			}
+			key := strings.Join(keys, ":")
+
+			res, err := limiter.Allow(req.Context(), key)
+			if err != nil {
+				logger.LogE(fmt.Sprintf("rate limiter: %v", err))
+				if opt.failOpen {
+					next.ServeHTTP(w, req)
+					return
+				}
+				wrapper.NewHTTPResponse(http.StatusServiceUnavailable, "Rate limiter unavailable").JSON(w)
+				return
+			}
+
+			header := w.Header()
+			header.Set(HeaderRateLimitLimit, strconv.Itoa(res.Limit))
+			header.Set(HeaderRateLimitRemaining, strconv.Itoa(res.Remaining))
+			header.Set(HeaderRateLimitReset, strconv.Itoa(durationSeconds(res.ResetAfter)))
+			if !res.Allowed {
+				tracer.Log(req.Context(), "rate_limit_exceeded", key)
+				header.Set(HeaderRetryAfter, strconv.Itoa(durationSeconds(res.RetryAfter)))
+				wrapper.NewHTTPResponse(http.StatusTooManyRequests, "Too many requests").JSON(w)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// durationSeconds round up duration to seconds
+func durationSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golangid/candi/candiutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPRateLimit(t *testing.T) {
+	limiter := candiutils.NewLocalRateLimiter(2, time.Minute, candiutils.WithAlgorithmRateLimiter(candiutils.RateLimitFixedWindow))
+	handler := HTTPRateLimit(limiter, RateLimitSetKeyFunc(RateLimitKeyByAPIKey("X-Api-Key")))(
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { w.WriteHeader(http.StatusOK) }),
+	)
+
+	request := func(apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Api-Key", apiKey)
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		return resp
+	}
+
+	for i := 0; i < 2; i++ {
+		resp := request("key-a")
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "2", resp.Header().Get(HeaderRateLimitLimit))
+	}
+
+	resp := request("key-a")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "0", resp.Header().Get(HeaderRateLimitRemaining))
+	assert.NotEmpty(t, resp.Header().Get(HeaderRetryAfter))
+
+	assert.Equal(t, http.StatusOK, request("key-b").Code)
+}
+
+func TestRateLimitKeyFunc(t *testing.T) {
+	newRequest := func(remoteAddr string, headers map[string]string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		return req
+	}
+
+	t.Run("ip from remote address", func(t *testing.T) {
+		req := newRequest("203.0.113.1:1234", map[string]string{"X-Forwarded-For": "1.1.1.1", "X-Real-Ip": "2.2.2.2"})
+		assert.Equal(t, "ip:203.0.113.1", RateLimitKeyByIP(req))
+	})
+
+	t.Run("forwarded ip from trusted proxy", func(t *testing.T) {
+		keyFunc := RateLimitKeyByForwardedIP("10.0.0.0/8", "192.168.1.1")
+		tests := []struct {
+			name, remoteAddr string
+			headers          map[string]string
+			want             string
+		}{
+			{name: "untrusted remote address", remoteAddr: "203.0.113.1:1234",
+				headers: map[string]string{"X-Forwarded-For": "1.1.1.1"}, want: "ip:203.0.113.1"},
+			{name: "rightmost untrusted address", remoteAddr: "10.0.0.2:1234",
+				headers: map[string]string{"X-Forwarded-For": "6.6.6.6, 1.1.1.1, 192.168.1.1"}, want: "ip:1.1.1.1"},
+			{name: "real ip header", remoteAddr: "192.168.1.1:1234",
+				headers: map[string]string{"X-Real-Ip": "2.2.2.2"}, want: "ip:2.2.2.2"},
+			{name: "all forwarded address is trusted", remoteAddr: "[::ffff:10.0.0.2]:1234",
+				headers: map[string]string{"X-Forwarded-For": "10.0.0.3"}, want: "ip:::ffff:10.0.0.2"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				assert.Equal(t, tt.want, keyFunc(newRequest(tt.remoteAddr, tt.headers)))
+			})
+		}
+
+		assert.Panics(t, func() { RateLimitKeyByForwardedIP("invalid") })
+	})
+
+	t.Run("api key is hashed", func(t *testing.T) {
+		key := RateLimitKeyByAPIKey("X-Api-Key")(newRequest("203.0.113.1:1234", map[string]string{"X-Api-Key": "secret-key"}))
+		assert.NotContains(t, key, "secret-key")
+		assert.Regexp(t, `^apikey:[0-9a-f]{32}$`, key)
+		assert.Equal(t, "ip:203.0.113.1", RateLimitKeyByAPIKey("X-Api-Key")(newRequest("203.0.113.1:1234", nil)))
+	})
+}
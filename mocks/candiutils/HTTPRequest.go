@@ -82,6 +82,36 @@ func (_m *HTTPRequest) DoRequest(ctx context.Context, method string, url string,
 	return r0, r1
 }
 
+// DoStream provides a mock function with given fields: ctx, method, url, requestBody, headers
+func (_m *HTTPRequest) DoStream(ctx context.Context, method string, url string, requestBody []byte, headers map[string]string) (*candiutils.HTTPStreamResult, error) {
+	ret := _m.Called(ctx, method, url, requestBody, headers)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DoStream")
+	}
+
+	var r0 *candiutils.HTTPStreamResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []byte, map[string]string) (*candiutils.HTTPStreamResult, error)); ok {
+		return rf(ctx, method, url, requestBody, headers)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []byte, map[string]string) *candiutils.HTTPStreamResult); ok {
+		r0 = rf(ctx, method, url, requestBody, headers)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*candiutils.HTTPStreamResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, []byte, map[string]string) error); ok {
+		r1 = rf(ctx, method, url, requestBody, headers)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewHTTPRequest creates a new instance of HTTPRequest. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewHTTPRequest(t interface {
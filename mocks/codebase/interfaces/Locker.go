@@ -5,6 +5,10 @@ package mocks
 import (
 	context "context"
 
+	candishared "github.com/golangid/candi/candishared"
+
+	interfaces "github.com/golangid/candi/codebase/interfaces"
+
 	mock "github.com/stretchr/testify/mock"
 
 	time "time"
@@ -15,6 +19,43 @@ type Locker struct {
 	mock.Mock
 }
 
+// AcquireLock provides a mock function with given fields: ctx, key, opts
+func (_m *Locker) AcquireLock(ctx context.Context, key string, opts ...candishared.LockOption) (interfaces.LockLease, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AcquireLock")
+	}
+
+	var r0 interfaces.LockLease
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...candishared.LockOption) (interfaces.LockLease, error)); ok {
+		return rf(ctx, key, opts...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...candishared.LockOption) interfaces.LockLease); ok {
+		r0 = rf(ctx, key, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(interfaces.LockLease)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...candishared.LockOption) error); ok {
+		r1 = rf(ctx, key, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Disconnect provides a mock function with given fields: ctx
 func (_m *Locker) Disconnect(ctx context.Context) error {
 	ret := _m.Called(ctx)
@@ -51,6 +51,27 @@ func NewHTTPResponseWithMeta[M any](code int, message string, meta M, params ...
 	return commonResponse
 }
 
+// HTTPError error with http status code, return from handler for set response status code (example: from typed rest handler)
+type HTTPError struct {
+	Code    int
+	Message string
+}
+
+// NewHTTPError create error with http status code
+func NewHTTPError(code int, message string) *HTTPError {
+	return &HTTPError{Code: code, Message: message}
+}
+
+// Error implement error
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+// HTTPStatusCode http status code of error
+func (e *HTTPError) HTTPStatusCode() int {
+	return e.Code
+}
+
 // JSON for set http JSON response (Content-Type: application/json) with parameter is http response writer
 func (resp *HTTPResponse) JSON(w http.ResponseWriter) error {
 	w.Header().Set(candihelper.HeaderContentType, candihelper.HeaderMIMEApplicationJSON)
@@ -126,3 +126,14 @@ func TestHTTPResponse_XML(t *testing.T) {
 	resp := NewHTTPResponse(200, "success")
 	assert.NoError(t, resp.XML(rec))
 }
+
+func TestHTTPError(t *testing.T) {
+	err := fmt.Errorf("wrap: %w", NewHTTPError(http.StatusNotFound, "not found"))
+
+	var coder interface{ HTTPStatusCode() int }
+	if assert.True(t, errors.As(err, &coder)) {
+		assert.Equal(t, http.StatusNotFound, coder.HTTPStatusCode())
+	}
+	assert.Equal(t, "wrap: not found", err.Error())
+	assert.Equal(t, map[string]string{"detail": "not found"}, NewHTTPResponse(http.StatusNotFound, "Failed", NewHTTPError(http.StatusNotFound, "not found")).Errors)
+}